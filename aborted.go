@@ -0,0 +1,92 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrTxFinished is returned by the statements of Current when its transaction is finished, committed
+// or rolled back, even directly, or aborted, see MarkAborted, rather than passing them to a dead transaction.
+//
+// It wraps sql.ErrTxDone.
+var ErrTxFinished = fmt.Errorf("txx: transaction finished or aborted: %w", sql.ErrTxDone)
+
+// errAborted is the rollback-only cause of an aborted transaction.
+var errAborted = fmt.Errorf("%w: aborted", ErrTxFinished)
+
+// MarkAborted marks the transaction of ctx aborted, like by PostgreSQL after a failed statement,
+// which then rejects every statement until rollback.
+//
+// The transaction stays current, so that QuerierFor doesn't run statements outside of it, but its statements
+// fail with ErrTxFinished, Ensure returns ErrTxFinished instead of reusing it, and it is rolled back,
+// like with SetRollbackOnly. It returns ErrNoTransaction if the transaction was not begun by txx, like with Set.
+func MarkAborted(ctx context.Context) error {
+	s := Get(ctx).s
+	if s == nil {
+		return ErrNoTransaction
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aborted = true
+
+	if s.rollbackOnly == nil {
+		s.rollbackOnly = errAborted
+	}
+
+	return nil
+}
+
+// checkFinished returns ErrTxFinished if the transaction is finished or aborted.
+func (s *scope) checkFinished() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.aborted:
+		return errAborted
+	case s.ending != nil || s.closed || s.txDone:
+		return ErrTxFinished
+	}
+
+	return nil
+}
+
+// checkAborted returns ErrTxFinished if the transaction is aborted, so that Ensure doesn't reuse it.
+func (s *scope) checkAborted() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.aborted {
+		return errAborted
+	}
+
+	return nil
+}
+
+// finished returns ErrTxFinished for a statement error telling its transaction is done, recording it,
+// given error otherwise.
+func (s *scope) finished(err error) error {
+	if !errors.Is(err, sql.ErrTxDone) || errors.Is(err, ErrTxFinished) {
+		return err
+	}
+
+	if s != nil {
+		s.mu.Lock()
+		s.txDone = true
+		s.mu.Unlock()
+	}
+
+	return ErrTxFinished
+}
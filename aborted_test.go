@@ -0,0 +1,97 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrTxFinished(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish func(ctx context.Context) error
+	}{
+		{
+			name: "Commit",
+			finish: func(ctx context.Context) error {
+				return Get(ctx).Commit(ctx)
+			},
+		},
+		{
+			name: "Tx.Commit",
+			finish: func(ctx context.Context) error {
+				return Get(ctx).Tx.Commit()
+			},
+		},
+		{
+			name: "Rollback",
+			finish: func(ctx context.Context) error {
+				return Get(ctx).Rollback(ctx)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+			_ = Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+				require.NoError(t, tt.finish(ctx))
+
+				_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+				require.ErrorIs(t, err, ErrTxFinished)
+				require.ErrorIs(t, err, sql.ErrTxDone)
+
+				_, err = Get(ctx).QueryContext(ctx, "SELECT name FROM person")
+				require.ErrorIs(t, err, ErrTxFinished)
+
+				_, err = Get(ctx).PrepareContext(ctx, "SELECT name FROM person")
+				require.ErrorIs(t, err, ErrTxFinished)
+
+				outer := Get(ctx).ID()
+
+				return Ensure(ctx, db, nil, func(ctx context.Context) error {
+					assert.NotEqual(t, outer, Get(ctx).ID(), "a fresh transaction should be begun")
+
+					return nil
+				})
+			})
+		})
+	}
+}
+
+func TestMarkAborted(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var info Info
+
+	m := New(db, WithFinally(func(i Info) { info = i }))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+
+		// a failed statement, aborting a PostgreSQL transaction
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (id, name) VALUES (1, 'Alice')")
+		require.Error(t, err)
+		require.NoError(t, MarkAborted(ctx))
+
+		assert.True(t, Get(ctx).IsValid(), "aborted transaction should stay current")
+		assert.True(t, Get(ctx).RollbackOnly())
+
+		require.ErrorIs(t, insertPerson(ctx, db, "Dave"), ErrTxFinished)
+		require.ErrorIs(t, Get(ctx).QueryRowContext(ctx, "SELECT 1").Err(), ErrTxFinished)
+		require.ErrorIs(t, Ensure(ctx, db, nil, noop), ErrTxFinished)
+		require.ErrorIs(t, m.Ensure(ctx, nil, noop), ErrTxFinished)
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, ErrTxFinished)
+	assert.Equal(t, 2, countPeople(t, db), "aborted transaction should be rolled back")
+	assert.Equal(t, CauseRollbackOnly, info.Cause)
+	require.ErrorIs(t, MarkAborted(context.Background()), ErrNoTransaction)
+}
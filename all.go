@@ -0,0 +1,150 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrPartialCommit is matched by every PartialCommitError, see WrapAll.
+var ErrPartialCommit = errors.New("txx: partial commit")
+
+// PartialCommitError is returned by WrapAll when a commit failed after other transactions were committed:
+// the databases are then inconsistent, and only the application can compensate.
+type PartialCommitError struct {
+	// Committed are the names of the databases committed, in commit order.
+	Committed []string
+	// Failed is the name of the database whose commit failed.
+	Failed string
+	// RolledBack are the names of the databases rolled back after the failed commit, in commit order.
+	RolledBack []string
+	// Err is the error of the failed commit, joined to rollback errors if any.
+	Err error
+}
+
+func (e *PartialCommitError) Error() string {
+	return fmt.Sprintf(
+		"txx: partial commit: committed [%s], failed %s, rolled back [%s]: %v",
+		strings.Join(e.Committed, " "), e.Failed, strings.Join(e.RolledBack, " "), e.Err,
+	)
+}
+
+// Is reports if target is ErrPartialCommit.
+func (e *PartialCommitError) Is(target error) bool {
+	return target == ErrPartialCommit //nolint:errorlint
+}
+
+// Unwrap returns the error of the failed commit.
+func (e *PartialCommitError) Unwrap() error {
+	return e.Err
+}
+
+// WrapAll runs f with a new transaction on every database of dbs, each in the slot of its name, see GetNamed.
+//
+// Each transaction is run like by Wrap, the one of the first name innermost: the transactions are begun
+// in the reverse order of their names, then committed in the order of their names once f returns nil.
+// They are all rolled back if a begin fails, if f returns an error or panics, or if ctx is done before the commits.
+//
+// This is not a two-phase commit, see WrapTwoPhase: if a commit fails, the transactions already committed stay so,
+// and WrapAll returns a *PartialCommitError naming the databases committed and not committed.
+// A failure of the first commit is not partial: every transaction is rolled back and the error wraps ErrCommitFailed.
+func WrapAll(ctx context.Context, dbs map[string]*sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	names := sortedNames(dbs)
+
+	return runAll(ctx, names, func(name string) beginner {
+		return sqlBeginner{db: dbs[name]}
+	}, opts, f, func(i int, err error) error {
+		if i == 0 {
+			return err
+		}
+
+		return &PartialCommitError{
+			Committed:  slices.Clone(names[:i]),
+			Failed:     names[i],
+			RolledBack: slices.Clone(names[i+1:]),
+			Err:        err,
+		}
+	})
+}
+
+// sortedNames returns the names of given databases, sorted.
+func sortedNames[DB any](dbs map[string]DB) []string {
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// runAll runs f with a transaction per name, begun by the runner of the beginner of the name,
+// in the slot of the name while the default slot of ctx is left as is.
+//
+// The runs are nested, the one of the first name innermost, so that the transactions are completed
+// in the order of the names once f returns nil, the outer ones being rolled back once one fails.
+// If the completion of the transaction of index i fails, or if it is rolled back once the inner ones are completed,
+// like when ctx is done, runAll returns the error returned by failed, given the error of the run.
+func runAll(
+	ctx context.Context,
+	names []string,
+	b func(name string) beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+	failed func(i int, err error) error,
+) error {
+	outer := Get(ctx)
+
+	// rollback is requested by f, see ErrRollback: each run rolls back and returns nil
+	var rollback bool
+
+	var run func(ctx context.Context, i int) error
+
+	run = func(tctx context.Context, i int) error {
+		if i < 0 {
+			err := f(tctx)
+			if err == nil {
+				// the contexts of the runs are only canceled asynchronously once ctx is: none must commit
+				err = ctx.Err()
+			}
+
+			rollback = errors.Is(err, ErrRollback)
+
+			return err
+		}
+
+		var called, completed bool
+
+		err := runner{b: b(names[i])}.run(tctx, opts, func(tctx context.Context, _ transaction) error {
+			tctx = context.WithValue(tctx, namedKey(names[i]), Get(tctx))
+
+			// the default slot is left as is
+			inner := run(context.WithValue(tctx, ctxKey, outer), i-1)
+			called, completed = true, inner == nil
+
+			if completed && rollback {
+				return ErrRollback
+			}
+
+			return inner
+		})
+
+		switch {
+		case err == nil:
+			return nil
+		case !called:
+			// begin failed
+			return fmt.Errorf("%s: %w", names[i], err)
+		case completed && !rollback:
+			return failed(i, fmt.Errorf("%s: %w", names[i], err))
+		default:
+			return err
+		}
+	}
+
+	return run(ctx, len(names)-1)
+}
@@ -0,0 +1,221 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// purchaseSchema creates tables whose commit fails if a purchase without customer was inserted.
+const purchaseSchema = `
+	CREATE TABLE customer (id INTEGER PRIMARY KEY);
+	CREATE TABLE purchase (
+		id INTEGER PRIMARY KEY,
+		customer_id INTEGER NOT NULL REFERENCES customer (id) DEFERRABLE INITIALLY DEFERRED
+	);
+`
+
+// deferred are the options of databases whose commit fails if a purchase without customer was inserted.
+//
+// A failed commit leaves the transaction of SQLite open: closing the connection rolls it back.
+//
+//nolint:gochecknoglobals
+var deferred = []testdb.Option{onDisk, testdb.WithMaxIdleConns(0), testdb.WithSchema(purchaseSchema)}
+
+func purchases(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var result int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM purchase").Scan(&result))
+
+	return result
+}
+
+func insertPurchases(customers map[string]int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for name, customer := range customers {
+			_, err := GetNamed(ctx, name).ExecContext(ctx, "INSERT INTO purchase (customer_id) VALUES (?)", customer)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestWrapAll(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	for _, db := range dbs {
+		_, err := db.Exec("INSERT INTO customer VALUES (1)")
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, WrapAll(context.Background(), dbs, nil, func(ctx context.Context) error {
+		assert.False(t, IsInTx(ctx), "default slot should not be set")
+		assert.NotEqual(t, GetNamed(ctx, "a").ID(), GetNamed(ctx, "b").ID())
+
+		return insertPurchases(map[string]int{"a": 1, "b": 1})(ctx)
+	}))
+
+	assert.Equal(t, 1, purchases(t, dbs["a"]))
+	assert.Equal(t, 1, purchases(t, dbs["b"]))
+}
+
+func TestWrapAll_rollback(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	err := WrapAll(context.Background(), dbs, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPurchases(map[string]int{"a": 1, "b": 1})(ctx))
+
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	assert.Zero(t, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+}
+
+func TestWrapAll_panic(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = WrapAll(context.Background(), dbs, nil, func(ctx context.Context) error {
+			require.NoError(t, insertPurchases(map[string]int{"a": 1, "b": 1})(ctx))
+
+			panic("boom")
+		})
+	})
+
+	assert.Zero(t, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+}
+
+func TestWrapAll_partialCommit(t *testing.T) {
+	dbs := map[string]*sql.DB{
+		"a": testdb.Open(t, deferred...),
+		"b": testdb.Open(t, deferred...),
+		"c": testdb.Open(t, deferred...),
+	}
+
+	for _, name := range []string{"a", "c"} {
+		_, err := dbs[name].Exec("INSERT INTO customer VALUES (1)")
+		require.NoError(t, err)
+	}
+
+	// the purchase of b has no customer: its commit fails
+	err := WrapAll(context.Background(), dbs, nil, insertPurchases(map[string]int{"a": 1, "b": 1, "c": 1}))
+
+	var partial *PartialCommitError
+
+	require.ErrorAs(t, err, &partial)
+	assert.ErrorIs(t, err, ErrPartialCommit)
+	assert.ErrorIs(t, err, ErrCommitFailed)
+	assert.Equal(t, []string{"a"}, partial.Committed)
+	assert.Equal(t, "b", partial.Failed)
+	assert.Equal(t, []string{"c"}, partial.RolledBack)
+	assert.Contains(t, err.Error(), "committed [a], failed b, rolled back [c]")
+
+	assert.Equal(t, 1, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+	assert.Zero(t, purchases(t, dbs["c"]))
+}
+
+func TestWrapAll_firstCommit(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	_, err := dbs["b"].Exec("INSERT INTO customer VALUES (1)")
+	require.NoError(t, err)
+
+	err = WrapAll(context.Background(), dbs, nil, insertPurchases(map[string]int{"a": 1, "b": 1}))
+
+	require.ErrorIs(t, err, ErrCommitFailed)
+	assert.NotErrorIs(t, err, ErrPartialCommit, "nothing should be committed")
+	assert.Zero(t, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+}
+
+func TestWrapAll_errRollback(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	for _, db := range dbs {
+		_, err := db.Exec("INSERT INTO customer VALUES (1)")
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, WrapAll(context.Background(), dbs, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPurchases(map[string]int{"a": 1, "b": 1})(ctx))
+
+		return ErrRollback
+	}))
+
+	assert.Zero(t, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+}
+
+func TestWrapAll_rolledBackDirectly(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	err := WrapAll(context.Background(), dbs, nil, func(ctx context.Context) error {
+		require.NoError(t, GetNamed(ctx, "a").Tx.Rollback())
+
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	assert.NotErrorIs(t, err, ErrRollbackFailed, "sql.ErrTxDone should be ignored")
+}
+
+func TestWrapAll_beginFailed(t *testing.T) {
+	errBegin := errors.New("connection refused") //nolint:goerr113
+	dbs := map[string]*sql.DB{
+		"a": testdb.Open(t, deferred...),
+		"b": fakedb.Open(t, &fakedb.Driver{Begin: func(_ *sql.TxOptions) error { return errBegin }}),
+	}
+
+	called := false
+
+	err := WrapAll(context.Background(), dbs, nil, func(_ context.Context) error {
+		called = true
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, ErrBeginFailed)
+	require.ErrorIs(t, err, errBegin)
+	assert.Contains(t, err.Error(), "b: ")
+	assert.False(t, called)
+}
+
+func TestWrapAll_canceled(t *testing.T) {
+	dbs := map[string]*sql.DB{"a": testdb.Open(t, deferred...), "b": testdb.Open(t, deferred...)}
+
+	for _, db := range dbs {
+		_, err := db.Exec("INSERT INTO customer VALUES (1)")
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := WrapAll(ctx, dbs, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPurchases(map[string]int{"a": 1, "b": 1})(ctx))
+		cancel()
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrPartialCommit)
+	assert.Zero(t, purchases(t, dbs["a"]))
+	assert.Zero(t, purchases(t, dbs["b"]))
+}
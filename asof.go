@@ -0,0 +1,78 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrAsOfSystemTime is returned when AS OF SYSTEM TIME can't be applied, see WithAsOfSystemTime.
+var ErrAsOfSystemTime = errors.New("txx: invalid AS OF SYSTEM TIME")
+
+// FollowerReadTimestamp is the CockroachDB expression of the most recent timestamp served by follower reads.
+const FollowerReadTimestamp = "follower_read_timestamp()"
+
+// WithAsOfSystemTime makes read-only transactions CockroachDB historical ones, reading data as of
+// given expression with SET TRANSACTION AS OF SYSTEM TIME right after begin.
+//
+// The expression is either FollowerReadTimestamp, a negative duration relative to now like "-10s",
+// or a timestamp formatted by AsOfTime. It is reported in Info.
+//
+// The transaction fails with ErrAsOfSystemTime if the expression is not one of these,
+// the dialect is not DialectCockroach or the transaction is not read-only.
+func WithAsOfSystemTime(expr string) Option {
+	return func(m *Manager) {
+		literal, err := asOfSystemTime(expr)
+
+		m.r.setups = append(m.r.setups, setup{
+			check: func(opts *sql.TxOptions) error {
+				switch {
+				case err != nil:
+					return err
+				case m.r.dialect != DialectCockroach:
+					return fmt.Errorf("%w: requires %v dialect, not %v", ErrAsOfSystemTime, DialectCockroach, m.r.dialect)
+				case opts == nil || !opts.ReadOnly:
+					return fmt.Errorf("%w: requires a read-only transaction", ErrAsOfSystemTime)
+				}
+
+				return nil
+			},
+			apply: func(ctx context.Context, tx transaction, s *scope) error {
+				if err := tx.Exec(ctx, "SET TRANSACTION AS OF SYSTEM TIME "+literal); err != nil {
+					return err
+				}
+
+				s.asOf = literal
+
+				return nil
+			},
+		})
+	}
+}
+
+// AsOfTime returns the expression of given time for WithAsOfSystemTime.
+func AsOfTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// asOfSystemTime returns the SQL literal of given allowed expression.
+func asOfSystemTime(expr string) (string, error) {
+	if expr == FollowerReadTimestamp {
+		return expr, nil
+	}
+
+	if strings.HasPrefix(expr, "-") {
+		if d, err := time.ParseDuration(expr); err == nil && d < 0 {
+			return "'" + expr + "'", nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, expr); err == nil {
+		return "'" + AsOfTime(t) + "'", nil
+	}
+
+	return "", fmt.Errorf("%w: %q is not an allowed expression", ErrAsOfSystemTime, expr)
+}
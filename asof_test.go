@@ -0,0 +1,78 @@
+package txx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAsOfSystemTime(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{name: "follower", expr: FollowerReadTimestamp, want: "follower_read_timestamp()"},
+		{name: "interval", expr: "-10s", want: "'-10s'"},
+		{name: "time", expr: AsOfTime(at), want: "'2024-05-01T10:30:00Z'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+
+			var infos []Info
+
+			m := newManager(backend, WithDialect(DialectCockroach), WithAsOfSystemTime(tt.expr),
+				WithFinally(func(info Info) { infos = append(infos, info) }))
+
+			require.NoError(t, m.Wrap(context.Background(), ReadOnly(), func(ctx context.Context) error {
+				return m.Ensure(ctx, ReadOnly(), noop)
+			}))
+
+			assert.Equal(t, []string{"begin", "SET TRANSACTION AS OF SYSTEM TIME " + tt.want, "commit"}, backend.calls)
+			require.Len(t, infos, 1)
+			assert.Equal(t, tt.want, infos[0].AsOfSystemTime)
+		})
+	}
+}
+
+func TestWithAsOfSystemTime_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		dialect Dialect
+		write   bool
+	}{
+		{name: "expression", expr: "now()", dialect: DialectCockroach},
+		{name: "injection", expr: "'-1s'; DROP TABLE users", dialect: DialectCockroach},
+		{name: "future", expr: "10s", dialect: DialectCockroach},
+		{name: "dialect", expr: FollowerReadTimestamp, dialect: DialectPostgres},
+		{name: "readWrite", expr: FollowerReadTimestamp, dialect: DialectCockroach, write: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+			m := newManager(backend, WithDialect(tt.dialect), WithAsOfSystemTime(tt.expr))
+
+			opts := ReadOnly()
+			if tt.write {
+				opts = nil
+			}
+
+			require.ErrorIs(t, m.Wrap(context.Background(), opts, noop), ErrAsOfSystemTime)
+			assert.Empty(t, backend.calls)
+		})
+	}
+}
+
+func TestDialect_String(t *testing.T) {
+	assert.Equal(t, "cockroach", DialectCockroach.String())
+	assert.Equal(t, "Dialect(42)", Dialect(42).String())
+}
@@ -0,0 +1,481 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+var transactions atomic.Uint64 //nolint:gochecknoglobals
+
+// beginner begins transactions on a backend.
+type beginner interface {
+	begin(ctx context.Context, opts *sql.TxOptions) (transaction, error)
+}
+
+// transaction is a backend transaction driven by a runner.
+type transaction interface {
+	// bind returns a context carrying the transaction and its scope for function f.
+	bind(ctx context.Context, opts *sql.TxOptions, s *scope) context.Context
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	Savepoint(ctx context.Context, name string) error
+	RollbackTo(ctx context.Context, name string) error
+	Release(ctx context.Context, name string) error
+	// Exec executes a statement without arguments in the transaction.
+	Exec(ctx context.Context, query string) error
+}
+
+// runner orchestrates transactions begun by a backend.
+type runner struct {
+	b beginner
+	// begun is called, if not nil, once a transaction is begun.
+	begun func(c *completion)
+	// finish is called, if not nil, once a transaction is completed, and may complete c.
+	finish func(c *completion)
+	// statements enables the statement context on errors.
+	statements bool
+	// redact statement arguments, redactType if nil.
+	redact func(i int, v any) any
+	// setups prepare every transaction, in order.
+	setups []setup
+	// cancel the context of function f once the transaction is completed.
+	cancel bool
+	// rewrite, if not nil, rewrites the SQL of statements.
+	rewrite func(ctx context.Context, query string) (string, error)
+	// explain, if not nil, captures the plans of slow statements.
+	explain *Explain
+	// memoRows is the maximum number of rows memoized per transaction, see WithMemoizedSelects.
+	memoRows int
+	// labels, if not nil, extracts the labels of transactions, at most maxLabels.
+	labels    func(ctx context.Context) map[string]string
+	maxLabels int
+	// clock measures durations, the time package if nil.
+	clock Clock
+	// beginTimeout, if positive, bounds begin.
+	beginTimeout time.Duration
+	// replay, if not nil, replays transactions whose connection failed.
+	replay *Replay
+	// dialect of the database, see WithDialect.
+	dialect Dialect
+	// bufferLimit, if positive, overrides the maximum number of parameters of statements flushed by ExecBuffered.
+	bufferLimit int
+	// maxStatements per transaction, unlimited if not positive, see WithMaxStatements.
+	maxStatements int
+	// strict rejects concurrent statements, see WithStrictConcurrency.
+	strict bool
+	// locking is the locking mode of read-write transactions, see WithLockingMode.
+	locking LockingMode
+	// isolation is the level LevelDefault resolves to, see WithDefaultIsolation.
+	isolation sql.IsolationLevel
+	// readOnlyWrites disables the rejection of writes in read-only transactions, see WithoutReadOnlyEnforcement.
+	readOnlyWrites bool
+	// dryRun rolls back transactions instead of committing them, see WithDryRun.
+	dryRun bool
+	// quietRollbackOnly reports transactions marked by SetRollbackOnly as successes, see WithQuietRollbackOnly.
+	quietRollbackOnly bool
+	// metrics record transactions, in addition to the global recorder, see WithMetrics.
+	metrics recorders
+	// slow, if not nil, detects slow transactions, see WithSlowTransactions.
+	slow *Slow
+	// recoverPanics returns panics of f as errors, see WithRecoverPanics.
+	recoverPanics bool
+	// onPanic, if not nil, is called with the panics of f, see WithOnPanic.
+	onPanic func(ctx context.Context, current Current, value any, stack []byte)
+}
+
+// rollbackCauseKey is the context key of the context.CancelCauseFunc of the context of a transaction function,
+// called with the cause of a forced rollback right before it, so that it is reported by context.Cause.
+type rollbackCauseKey struct{}
+
+// setup prepares transactions.
+type setup struct {
+	// check, if not nil, validates the options before begin.
+	check func(opts *sql.TxOptions) error
+	// apply, if not nil, is called right after begin.
+	apply func(ctx context.Context, tx transaction, s *scope) error
+}
+
+// run function f in a new transaction with given options.
+//
+// If function f returns an error or panic, the transaction is aborted,
+// otherwise the transaction is committed and the commit error, if any, is returned.
+// If ctx is done once f returns, the transaction is aborted too and the context error returned.
+func (r runner) run(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	f func(ctx context.Context, tx transaction) error,
+) (err error) {
+	for _, setup := range r.setups {
+		if setup.check != nil {
+			if err := setup.check(opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	labels := r.extractLabels(ctx)
+	start := r.now()
+
+	bctx, cancel := ctx, context.CancelCauseFunc(func(error) {})
+	if r.beginTimeout > 0 {
+		bctx, cancel = context.WithCancelCause(ctx)
+	}
+
+	defer cancel(nil)
+
+	locking := r.lockingMode(ctx, opts)
+
+	tx, err := r.begin(bctx, cancel, opts)
+	if err == nil {
+		err = lock(bctx, tx, locking)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBeginFailed, err)
+	}
+
+	metrics := r.metrics.withGlobal()
+	metrics.TxStarted(false, opts, labels)
+
+	s := &scope{
+		id:            transactions.Add(1),
+		startedAt:     start,
+		clock:         r.getClock(),
+		tx:            tx,
+		dialect:       r.dialect,
+		statements:    r.statements,
+		redact:        r.redact,
+		rewrite:       r.rewrite,
+		explain:       r.explain,
+		memoLimit:     r.memoRows,
+		bufferLimit:   r.bufferLimit,
+		maxStatements: r.maxStatements,
+		strict:        r.strict,
+		locking:       locking,
+		isolation:     r.isolation,
+		readOnly:      opts != nil && opts.ReadOnly && !r.readOnlyWrites,
+		dryRun:        r.dryRun || isDryRun(ctx),
+		quiet:         r.quietRollbackOnly,
+		labels:        labels,
+	}
+	s.own()
+
+	// the context of f is canceled with a descriptive cause before a forced rollback, see rollbackCauseKey,
+	// and only canceled on completion by WithCancelOnCompletion, f possibly handing it to goroutines
+	fctx, cancelF, stop := functionContext(ctx)
+	defer stop()
+
+	slow := r.watchSlow(ctx, s.id, opts, start)
+	fctx = context.WithValue(fctx, rollbackCauseKey{}, cancelF)
+
+	if r.cancel {
+		defer cancelF(ErrTransactionFinished)
+	}
+
+	defer func() {
+		// once fctx expired, ctx is done too: the work must not be committed
+		settle(ctx, fctx)
+
+		c := completion{
+			id:     s.id,
+			tx:     tx,
+			opts:   opts,
+			err:    err,
+			schema: s.schema,
+			asOf:   s.asOf,
+			labels: s.labels,
+		}
+
+		defer func() {
+			s.close(c.info())
+		}()
+
+		p := recover()
+
+		var stack []byte
+
+		if p != nil {
+			stack = debug.Stack()
+			r.panicked(ctx, Get(tx.bind(ctx, opts, s)), p, stack)
+		}
+
+		if _, ended := s.ended(); !ended && p == nil && err == nil && ctx.Err() != nil {
+			// the caller gave up, believing the work failed: it must not be committed
+			err = ctx.Err()
+			c.err = err
+		}
+
+		if _, ended := s.ended(); !ended && p == nil && errors.Is(err, ErrRollback) {
+			// requested by f: rolled back as rollback-only, reporting success
+			err = s.markRollback(err)
+			c.err = err
+		}
+
+		if rollbackOnly := s.getRollbackOnly(); rollbackOnly != nil {
+			c.rollbackOnly = true
+			c.statementLimitExceeded = errors.Is(rollbackOnly, ErrStatementLimitExceeded)
+		}
+
+		if e, ended := s.ended(); ended {
+			// finished by Current.Commit or Current.Rollback
+			c.committed, c.rolledBack, c.dryRun = e.committed, e.rolledBack, e.dryRun
+
+			if e.err != nil {
+				c.err = e.err
+
+				if err == nil {
+					err = e.err
+				}
+			}
+		} else if p != nil || err != nil {
+			if rollbackErr := s.end(ctx, false); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+				c.err = err
+			}
+
+			c.rolledBack = true
+		} else {
+			err = s.end(ctx, true)
+			e, _ = s.ended()
+
+			c.err, c.committed, c.rolledBack, c.dryRun = err, e.committed, e.rolledBack, e.dryRun
+		}
+
+		if p != nil {
+			c.panicked = true
+			c.err = &PanicError{Value: p, Stack: stack}
+		}
+
+		slow(r.now().Sub(start))
+
+		c.callbackErr = s.callbacks(ctx, &c)
+		c.duration = r.now().Sub(start)
+		r.done(&c)
+		metrics.TxFinished(c.outcome(), c.info().Cause, c.duration, c.err, c.labels)
+
+		if p != nil {
+			if !r.recoverPanics {
+				panic(p)
+			}
+
+			// joined with the rollback error, if any
+			if err == nil {
+				err = c.err
+			} else {
+				err = errors.Join(c.err, err)
+			}
+		}
+	}()
+
+	if r.begun != nil {
+		r.begun(&completion{id: s.id, tx: tx, opts: opts, labels: s.labels})
+	}
+
+	for _, setup := range r.setups {
+		if setup.apply != nil {
+			if err = setup.apply(ctx, tx, s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.wrap(f(tx.bind(fctx, opts, s), tx))
+}
+
+// begin a transaction, bounded by the begin timeout if any.
+//
+// The context of a transaction must not be done before its completion, so it is only canceled,
+// with cause ErrBeginTimeout, if begin takes too long.
+func (r runner) begin(ctx context.Context, cancel context.CancelCauseFunc, opts *sql.TxOptions) (transaction, error) {
+	if r.beginTimeout <= 0 {
+		return r.b.begin(ctx, opts)
+	}
+
+	timer := r.getClock().AfterFunc(r.beginTimeout, func() {
+		cancel(ErrBeginTimeout)
+	})
+
+	tx, err := r.b.begin(ctx, opts)
+
+	if !timer.Stop() && errors.Is(context.Cause(ctx), ErrBeginTimeout) {
+		if err == nil {
+			_ = tx.Rollback(ctx)
+			err = ctx.Err()
+		}
+
+		return nil, fmt.Errorf("%w after %v: %w", ErrBeginTimeout, r.beginTimeout, err)
+	}
+
+	return tx, err
+}
+
+func (r runner) getClock() Clock {
+	if r.clock == nil {
+		return clock.Real{}
+	}
+
+	return r.clock
+}
+
+func (r runner) now() time.Time {
+	return r.getClock().Now()
+}
+
+func (r runner) done(c *completion) {
+	if r.finish != nil {
+		r.finish(c)
+	}
+}
+
+// sqlBeginner is the database/sql backend.
+type sqlBeginner struct {
+	db Beginner
+	// strategy begins and completes transactions, TxStrategy if nil.
+	// It is only set by WithStrategy, for a Manager of a *sql.DB.
+	strategy Strategy
+}
+
+func (b sqlBeginner) begin(ctx context.Context, opts *sql.TxOptions) (transaction, error) {
+	var (
+		strategy = b.strategy
+		tx       *sql.Tx
+		err      error
+	)
+
+	if strategy == nil {
+		strategy = TxStrategy{}
+		tx, err = b.db.BeginTx(ctx, opts)
+	} else {
+		db, _ := b.db.(*sql.DB)
+		tx, err = strategy.Begin(ctx, db, opts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tx == nil {
+		return &noTransaction{}, nil
+	}
+
+	return sqlTransaction{tx: tx, strategy: strategy}, nil
+}
+
+type sqlTransaction struct {
+	tx       *sql.Tx
+	strategy Strategy
+}
+
+func (t sqlTransaction) bind(ctx context.Context, opts *sql.TxOptions, s *scope) context.Context {
+	return context.WithValue(ctx, ctxKey, Current{
+		Tx:    t.tx,
+		Opts:  opts,
+		Depth: 1,
+		s:     s,
+	})
+}
+
+func (t sqlTransaction) Commit(ctx context.Context) error {
+	return t.strategy.Commit(ctx, t.tx)
+}
+
+func (t sqlTransaction) Rollback(ctx context.Context) error {
+	return t.strategy.Rollback(ctx, t.tx)
+}
+
+func (t sqlTransaction) Savepoint(ctx context.Context, name string) error {
+	return t.Exec(ctx, "SAVEPOINT "+name)
+}
+
+func (t sqlTransaction) RollbackTo(ctx context.Context, name string) error {
+	return t.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name)
+}
+
+func (t sqlTransaction) Release(ctx context.Context, name string) error {
+	return t.Exec(ctx, "RELEASE SAVEPOINT "+name)
+}
+
+func (t sqlTransaction) Exec(ctx context.Context, query string) error {
+	_, err := t.tx.ExecContext(ctx, query)
+
+	return err
+}
+
+// Backend begins the transactions of a database not driven through database/sql, like pgx, see WrapBackend.
+type Backend interface {
+	// Begin a transaction with given options.
+	Begin(ctx context.Context, opts *sql.TxOptions) (BackendTx, error)
+}
+
+// BackendTx is a transaction of a Backend.
+//
+// Rollback returns an error wrapping sql.ErrTxDone if the transaction is already finished, which is ignored.
+type BackendTx interface {
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	// Exec executes a statement without arguments in the transaction.
+	Exec(ctx context.Context, query string) error
+}
+
+// WrapBackend runs function f in a new transaction of given Backend, like Wrap, for the packages
+// integrating other databases, like txxpgx.
+//
+// The transaction is given to f, but not stored in its context: Get doesn't see it.
+func WrapBackend(
+	ctx context.Context,
+	b Backend,
+	opts *sql.TxOptions,
+	f func(ctx context.Context, tx BackendTx) error,
+) error {
+	return runner{b: backendBeginner{b: b}}.run(ctx, opts, func(ctx context.Context, tx transaction) error {
+		return f(ctx, tx.(backendTransaction).BackendTx) //nolint:forcetypeassert
+	})
+}
+
+// NewTransactionRequired returns if a transaction of current options doesn't match requested ones,
+// like Current.NewTransactionRequired, following the ReadOnlyReuse policy of ctx, for the transactions of a Backend.
+func NewTransactionRequired(ctx context.Context, current, requested *sql.TxOptions) bool {
+	return requiresNew(current, requested, sql.LevelDefault, GetReadOnlyReuse(ctx))
+}
+
+// backendBeginner begins the transactions of a Backend.
+type backendBeginner struct {
+	b Backend
+}
+
+func (b backendBeginner) begin(ctx context.Context, opts *sql.TxOptions) (transaction, error) {
+	tx, err := b.b.Begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return backendTransaction{BackendTx: tx}, nil
+}
+
+// backendTransaction is a transaction of a Backend, whose savepoints are statements.
+type backendTransaction struct {
+	BackendTx
+}
+
+func (t backendTransaction) bind(ctx context.Context, _ *sql.TxOptions, _ *scope) context.Context {
+	return ctx
+}
+
+func (t backendTransaction) Savepoint(ctx context.Context, name string) error {
+	return t.Exec(ctx, "SAVEPOINT "+name)
+}
+
+func (t backendTransaction) RollbackTo(ctx context.Context, name string) error {
+	return t.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name)
+}
+
+func (t backendTransaction) Release(ctx context.Context, name string) error {
+	return t.Exec(ctx, "RELEASE SAVEPOINT "+name)
+}
@@ -0,0 +1,416 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend is an in-memory backend recording the calls made by the orchestration.
+type fakeBackend struct {
+	script []error
+	// commits and execs script the commit and exec errors, before falling back to commitErr and execErr.
+	commits     []error
+	execs       []error
+	beginErr    error
+	commitErr   error
+	rollbackErr error
+	execErr     error
+	calls       []string
+}
+
+func (b *fakeBackend) begin(_ context.Context, _ *sql.TxOptions) (transaction, error) {
+	b.calls = append(b.calls, "begin")
+
+	if len(b.script) > 0 {
+		err := b.script[0]
+		b.script = b.script[1:]
+
+		if err != nil {
+			return nil, err
+		}
+	} else if b.beginErr != nil {
+		return nil, b.beginErr
+	}
+
+	return &fakeTransaction{b: b}, nil
+}
+
+type fakeTransaction struct {
+	b *fakeBackend
+}
+
+// bind marks the context as in a transaction, with a placeholder *sql.Tx which must not be used.
+func (t *fakeTransaction) bind(ctx context.Context, opts *sql.TxOptions, s *scope) context.Context {
+	return context.WithValue(ctx, ctxKey, Current{Tx: &sql.Tx{}, Opts: opts, s: s})
+}
+
+func (t *fakeTransaction) Commit(_ context.Context) error {
+	t.b.calls = append(t.b.calls, "commit")
+
+	if len(t.b.commits) > 0 {
+		err := t.b.commits[0]
+		t.b.commits = t.b.commits[1:]
+
+		return err
+	}
+
+	return t.b.commitErr
+}
+
+func (t *fakeTransaction) Rollback(_ context.Context) error {
+	t.b.calls = append(t.b.calls, "rollback")
+
+	return t.b.rollbackErr
+}
+
+func (t *fakeTransaction) Savepoint(_ context.Context, name string) error {
+	t.b.calls = append(t.b.calls, "savepoint "+name)
+
+	return nil
+}
+
+func (t *fakeTransaction) RollbackTo(_ context.Context, name string) error {
+	t.b.calls = append(t.b.calls, "rollback to "+name)
+
+	return nil
+}
+
+func (t *fakeTransaction) Release(_ context.Context, name string) error {
+	t.b.calls = append(t.b.calls, "release "+name)
+
+	return nil
+}
+
+func (t *fakeTransaction) Exec(_ context.Context, query string) error {
+	t.b.calls = append(t.b.calls, query)
+
+	if len(t.b.execs) > 0 {
+		err := t.b.execs[0]
+		t.b.execs = t.b.execs[1:]
+
+		return err
+	}
+
+	return t.b.execErr
+}
+
+func succeed(_ context.Context, _ transaction) error {
+	return nil
+}
+
+func TestRunner_run(t *testing.T) {
+	errBegin := errors.New("begin")   //nolint:goerr113
+	errCommit := errors.New("commit") //nolint:goerr113
+
+	tests := []struct {
+		name      string
+		backend   *fakeBackend
+		f         func(ctx context.Context, tx transaction) error
+		wantErr   assert.ErrorAssertionFunc
+		wantCalls []string
+	}{
+		{
+			name:      "commit",
+			backend:   &fakeBackend{},
+			f:         succeed,
+			wantErr:   assert.NoError,
+			wantCalls: []string{"begin", "commit"},
+		},
+		{
+			name:    "rollback",
+			backend: &fakeBackend{},
+			f: func(ctx context.Context, _ transaction) error {
+				return fail(ctx)
+			},
+			wantErr:   assert.Error,
+			wantCalls: []string{"begin", "rollback"},
+		},
+		{
+			name:    "commit error",
+			backend: &fakeBackend{commitErr: errCommit},
+			f:       succeed,
+			wantErr: func(t assert.TestingT, err error, _ ...any) bool {
+				return assert.ErrorIs(t, err, errCommit)
+			},
+			wantCalls: []string{"begin", "commit"},
+		},
+		{
+			name:      "begin error",
+			backend:   &fakeBackend{beginErr: errBegin},
+			f:         succeed,
+			wantErr:   assert.Error,
+			wantCalls: []string{"begin"},
+		},
+		{
+			name:    "savepoint",
+			backend: &fakeBackend{},
+			f: func(ctx context.Context, tx transaction) error {
+				_ = tx.Savepoint(ctx, "sp_1")
+
+				return tx.Release(ctx, "sp_1")
+			},
+			wantErr:   assert.NoError,
+			wantCalls: []string{"begin", "savepoint sp_1", "release sp_1", "commit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runner{b: tt.backend}.run(context.Background(), nil, tt.f)
+
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantCalls, tt.backend.calls)
+		})
+	}
+}
+
+func TestRunner_run_panic(t *testing.T) {
+	backend := &fakeBackend{}
+
+	require.PanicsWithValue(t, "test", func() {
+		_ = runner{b: backend}.run(context.Background(), nil, func(_ context.Context, _ transaction) error {
+			panic("test")
+		})
+	})
+
+	assert.Equal(t, []string{"begin", "rollback"}, backend.calls)
+}
+
+func TestManager_Wrap_commitError(t *testing.T) {
+	errCommit := errors.New("could not serialize access") //nolint:goerr113
+	backend := &fakeBackend{commitErr: errCommit}
+
+	var info Info
+
+	m := newManager(backend, WithFinally(func(i Info) { info = i }))
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return nil
+	}), errCommit)
+	assert.False(t, info.Committed)
+	require.ErrorIs(t, info.Err, errCommit)
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		_ = Get(ctx).Commit(ctx)
+
+		return nil
+	}), errCommit, "error of an ignored manual commit should be returned")
+
+	err := m.Wrap(context.Background(), nil, fail)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errCommit, "function error should take precedence")
+}
+
+func TestManager_Wrap_rollbackError(t *testing.T) {
+	errFailed := errors.New("failed")             //nolint:goerr113
+	errRollback := errors.New("connection reset") //nolint:goerr113
+	backend := &fakeBackend{rollbackErr: errRollback}
+
+	var info Info
+
+	m := newManager(backend, WithFinally(func(i Info) { info = i }))
+
+	err := m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	require.ErrorIs(t, err, errRollback)
+	require.ErrorIs(t, info.Err, errRollback)
+	assert.Equal(t, CauseError, info.Cause)
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return Get(ctx).Rollback(ctx)
+	}), errRollback)
+
+	require.PanicsWithValue(t, "test", func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("test")
+		})
+	})
+}
+
+func TestManager_Wrap_rollbackTxDone(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	m := newManager(&fakeBackend{rollbackErr: sql.ErrTxDone})
+
+	err := m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	assert.NotErrorIs(t, err, sql.ErrTxDone)
+	assert.Equal(t, errFailed, err, "error should be returned as is")
+}
+
+func TestManager_Wrap_errors(t *testing.T) {
+	errDriver := errors.New("driver") //nolint:goerr113
+	errFailed := errors.New("failed") //nolint:goerr113
+
+	tests := []struct {
+		name    string
+		backend *fakeBackend
+		f       func(ctx context.Context) error
+		want    []error
+		notWant []error
+	}{
+		{
+			name:    "begin",
+			backend: &fakeBackend{beginErr: errDriver},
+			f:       func(_ context.Context) error { return nil },
+			want:    []error{ErrBeginFailed, errDriver},
+			notWant: []error{ErrCommitFailed, ErrRollbackFailed},
+		},
+		{
+			name:    "commit",
+			backend: &fakeBackend{commitErr: errDriver},
+			f:       func(_ context.Context) error { return nil },
+			want:    []error{ErrCommitFailed, errDriver},
+			notWant: []error{ErrBeginFailed, ErrRollbackFailed},
+		},
+		{
+			name:    "rollback",
+			backend: &fakeBackend{rollbackErr: errDriver},
+			f:       func(_ context.Context) error { return errFailed },
+			want:    []error{ErrRollbackFailed, errDriver, errFailed},
+			notWant: []error{ErrBeginFailed, ErrCommitFailed},
+		},
+		{
+			name:    "function",
+			backend: &fakeBackend{},
+			f:       func(_ context.Context) error { return errFailed },
+			want:    []error{errFailed},
+			notWant: []error{ErrBeginFailed, ErrCommitFailed, ErrRollbackFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newManager(tt.backend).Wrap(context.Background(), nil, tt.f)
+
+			for _, want := range tt.want {
+				require.ErrorIs(t, err, want)
+			}
+
+			for _, notWant := range tt.notWant {
+				assert.NotErrorIs(t, err, notWant)
+			}
+		})
+	}
+}
+
+func TestWrap_functionError(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+
+	err := Wrap(context.Background(), testdb.Open(t, inMemory), nil, func(_ context.Context) error {
+		return errFailed
+	})
+
+	assert.Equal(t, errFailed, err, "function error should be returned as is")
+}
+
+func TestManager_fakeBackend(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return nil
+	}))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	assert.Equal(t, []string{"begin", "commit"}, backend.calls)
+}
+
+func TestSQLTransaction_savepoint(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+
+	err := runner{b: sqlBeginner{db: db}}.run(context.Background(), nil, func(ctx context.Context, tx transaction) error {
+		require.NoError(t, tx.Savepoint(ctx, "sp_1"))
+		require.NoError(t, tx.RollbackTo(ctx, "sp_1"))
+
+		return tx.Release(ctx, "sp_1")
+	})
+
+	require.NoError(t, err)
+}
+
+// publicBackend is the Backend of the transactions of a fakeBackend.
+type publicBackend struct {
+	b *fakeBackend
+}
+
+func (b publicBackend) Begin(ctx context.Context, opts *sql.TxOptions) (BackendTx, error) {
+	tx, err := b.b.begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.(*fakeTransaction), nil //nolint:forcetypeassert
+}
+
+func TestWrapBackend(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	errBegin := errors.New("begin")   //nolint:goerr113
+
+	tests := []struct {
+		name      string
+		backend   *fakeBackend
+		err       error
+		wantErr   []error
+		wantCalls []string
+	}{
+		{name: "commit", backend: &fakeBackend{}, wantCalls: []string{"begin", "commit"}},
+		{
+			name:      "rollback",
+			backend:   &fakeBackend{rollbackErr: sql.ErrTxDone},
+			err:       errFailed,
+			wantErr:   []error{errFailed},
+			wantCalls: []string{"begin", "rollback"},
+		},
+		{
+			name:      "begin error",
+			backend:   &fakeBackend{beginErr: errBegin},
+			wantErr:   []error{ErrBeginFailed, errBegin},
+			wantCalls: []string{"begin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapBackend(context.Background(), publicBackend{b: tt.backend}, nil,
+				func(ctx context.Context, tx BackendTx) error {
+					assert.False(t, IsInTx(ctx), "the transaction should not be stored")
+					assert.IsType(t, &fakeTransaction{}, tx)
+
+					return tt.err
+				},
+			)
+
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			}
+
+			for _, want := range tt.wantErr {
+				require.ErrorIs(t, err, want)
+			}
+
+			assert.NotErrorIs(t, err, ErrRollbackFailed, "sql.ErrTxDone should be ignored")
+			assert.Equal(t, tt.wantCalls, tt.backend.calls)
+		})
+	}
+}
+
+func TestNewTransactionRequired(t *testing.T) {
+	ctx := context.Background()
+
+	assert.False(t, NewTransactionRequired(ctx, Serializable(), ReadCommitted()))
+	assert.True(t, NewTransactionRequired(ctx, nil, ReadCommitted()), "LevelDefault should be unknown")
+	assert.True(t, NewTransactionRequired(ctx, nil, ReadOnly()))
+	assert.False(t, NewTransactionRequired(SetReadOnlyReuse(ctx, ReuseReadWrite), nil, ReadOnly()))
+}
@@ -0,0 +1,255 @@
+package txx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of parameters of a statement by dialect.
+const (
+	maxParamsGeneric  = 999
+	maxParamsSQLite   = 32766
+	maxParamsPostgres = 65535
+	maxParamsMySQL    = 65535
+)
+
+// insertPattern matches an INSERT statement ending with a single VALUES tuple of placeholders.
+var insertPattern = regexp.MustCompile( //nolint:gochecknoglobals
+	`(?is)^\s*(INSERT\s.+\sVALUES)\s*\(\s*((?:\?|\$\d+)(?:\s*,\s*(?:\?|\$\d+))*)\s*\)\s*;?\s*$`)
+
+// BufferedExecError is returned when flushing rows buffered by ExecBuffered fails.
+type BufferedExecError struct {
+	// Query of the buffered rows.
+	Query string
+	// First and Last rows of the failed statement, by order of ExecBuffered calls in the transaction from 1.
+	First, Last int
+	Err         error
+}
+
+func (e *BufferedExecError) Error() string {
+	return fmt.Sprintf("txx: flushing buffered rows %d to %d of %q: %v", e.First, e.Last, e.Query, e.Err)
+}
+
+func (e *BufferedExecError) Unwrap() error {
+	return e.Err
+}
+
+// WithExecBufferLimit sets the maximum number of parameters of the statements flushed by ExecBuffered,
+// by default the limit of the dialect, 999 for DialectGeneric.
+func WithExecBufferLimit(maxParams int) Option {
+	return func(m *Manager) {
+		m.r.bufferLimit = maxParams
+	}
+}
+
+// ExecBuffered buffers the row of a single-row INSERT statement in the current transaction,
+// like "INSERT INTO item (id, name) VALUES (?, ?)", coalescing consecutive rows of the same statement
+// into multi-row INSERT statements to save round trips.
+//
+// Buffered rows are flushed once the statement would exceed the parameter limit of the dialect,
+// see WithExecBufferLimit, before any other statement of the transaction executed by Current,
+// and right before commit. A rollback discards them. Flush failures are BufferedExecError errors,
+// except for QueryRowContext which then fails with context.Canceled, like with WithRewriteSQL.
+//
+// Statements other than INSERT with a single VALUES tuple of ? or sequential $n placeholders
+// are executed right away. It returns ErrNoTransaction if there is no transaction begun by txx.
+func ExecBuffered(ctx context.Context, query string, args ...any) error {
+	current := Get(ctx)
+	if current.s == nil || !current.IsValid() {
+		return ErrNoTransaction
+	}
+
+	if err := current.s.checkReadOnly(query); err != nil {
+		return err
+	}
+
+	return current.s.buffer(ctx, current, query, args)
+}
+
+// execBuffer holds rows of the same INSERT statement to be flushed, see ExecBuffered.
+type execBuffer struct {
+	current Current
+	query   string
+	// prefix of the statement, up to VALUES.
+	prefix string
+	// tuple of the original statement, if using ? placeholders.
+	tuple string
+	// params is the number of parameters by row.
+	params int
+	args   []any
+	first  int
+	rows   int
+}
+
+// statement returns the multi-row INSERT statement of the buffered rows.
+func (b *execBuffer) statement() string {
+	var sb strings.Builder
+
+	sb.WriteString(b.prefix)
+
+	for row := 0; row < b.rows; row++ {
+		if row > 0 {
+			sb.WriteByte(',')
+		}
+
+		sb.WriteString(" (")
+
+		if b.tuple != "" {
+			sb.WriteString(b.tuple)
+		} else {
+			for i := 1; i <= b.params; i++ {
+				if i > 1 {
+					sb.WriteString(", ")
+				}
+
+				sb.WriteByte('$')
+				sb.WriteString(strconv.Itoa(row*b.params + i))
+			}
+		}
+
+		sb.WriteByte(')')
+	}
+
+	return sb.String()
+}
+
+// parseInsert returns a buffer for given statement if it can be coalesced.
+func parseInsert(query string, args int) (*execBuffer, bool) {
+	match := insertPattern.FindStringSubmatch(query)
+	if match == nil {
+		return nil, false
+	}
+
+	placeholders := strings.Split(match[2], ",")
+	if len(placeholders) != args {
+		return nil, false
+	}
+
+	b := &execBuffer{query: query, prefix: match[1], params: args, tuple: match[2]}
+
+	for i, p := range placeholders {
+		p = strings.TrimSpace(p)
+
+		if p == "?" {
+			continue
+		}
+
+		if p != "$"+strconv.Itoa(i+1) {
+			return nil, false
+		}
+
+		b.tuple = ""
+	}
+
+	if b.tuple == "" && strings.Contains(match[2], "?") {
+		return nil, false // mixed placeholders
+	}
+
+	return b, true
+}
+
+func (s *scope) maxParams() int {
+	if s.bufferLimit > 0 {
+		return s.bufferLimit
+	}
+
+	switch s.dialect {
+	case DialectSQLite:
+		return maxParamsSQLite
+	case DialectPostgres, DialectCockroach:
+		return maxParamsPostgres
+	case DialectMySQL:
+		return maxParamsMySQL
+	case DialectGeneric:
+	}
+
+	return maxParamsGeneric
+}
+
+// buffer the row of given statement, see ExecBuffered.
+func (s *scope) buffer(ctx context.Context, current Current, query string, args []any) error {
+	shape, ok := parseInsert(query, len(args))
+	if !ok || shape.params > s.maxParams() {
+		_, err := current.ExecContext(ctx, query, args...)
+
+		return err
+	}
+
+	s.invalidate()
+
+	s.mu.Lock()
+	if s.pending != nil && s.pending.query != query {
+		// rows of the previous statement are flushed first, before buffering the new one
+		s.mu.Unlock()
+
+		if err := s.flush(ctx); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+	}
+
+	s.bufferedRows++
+
+	if s.pending == nil {
+		shape.current, shape.first = current, s.bufferedRows
+		s.pending = shape
+	}
+
+	b := s.pending
+	b.args = append(b.args, args...)
+	b.rows++
+
+	full := len(b.args)+b.params > s.maxParams()
+	if full {
+		s.pending = nil
+	}
+	s.mu.Unlock()
+
+	if full {
+		return b.flush(ctx)
+	}
+
+	return nil
+}
+
+// flush the buffered rows, if any.
+func (s *scope) flush(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	b := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if b == nil {
+		return nil
+	}
+
+	return b.flush(ctx)
+}
+
+// discard the buffered rows, if any.
+func (s *scope) discard() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = nil
+}
+
+func (b *execBuffer) flush(ctx context.Context) error {
+	if _, err := b.current.ExecContext(ctx, b.statement(), b.args...); err != nil {
+		return &BufferedExecError{Query: b.query, First: b.first, Last: b.first + b.rows - 1, Err: err}
+	}
+
+	return nil
+}
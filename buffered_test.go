@@ -0,0 +1,207 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryLog records the statements executed in transactions, see WithRewriteSQL.
+type queryLog struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (l *queryLog) rewrite(_ context.Context, query string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queries = append(l.queries, query)
+
+	return query, nil
+}
+
+// pointSchema creates the point table.
+const pointSchema = "CREATE TABLE point (id INTEGER PRIMARY KEY, x INTEGER)"
+
+func countPoints(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var n int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM point").Scan(&n))
+
+	return n
+}
+
+func insertPoints(ctx context.Context, ids ...int) error {
+	for _, id := range ids {
+		if err := ExecBuffered(ctx, "INSERT INTO point (id, x) VALUES (?, ?)", id, id*10); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestExecBuffered(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(pointSchema))
+	log := &queryLog{}
+	m := New(db, WithRewriteSQL(log.rewrite))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return insertPoints(ctx, 1, 2, 3)
+	}))
+
+	assert.Equal(t, []string{"INSERT INTO point (id, x) VALUES (?, ?), (?, ?), (?, ?)"}, log.queries)
+	assert.Equal(t, 3, countPoints(t, db))
+}
+
+func TestExecBuffered_limit(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(pointSchema))
+	log := &queryLog{}
+	m := New(db, WithRewriteSQL(log.rewrite), WithExecBufferLimit(4))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return insertPoints(ctx, 1, 2, 3, 4, 5)
+	}))
+
+	assert.Equal(t, []string{
+		"INSERT INTO point (id, x) VALUES (?, ?), (?, ?)",
+		"INSERT INTO point (id, x) VALUES (?, ?), (?, ?)",
+		"INSERT INTO point (id, x) VALUES (?, ?)",
+	}, log.queries)
+	assert.Equal(t, 5, countPoints(t, db))
+}
+
+func TestExecBuffered_ordering(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(pointSchema))
+	log := &queryLog{}
+	m := New(db, WithRewriteSQL(log.rewrite))
+
+	var name string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		var err error
+
+		name, err = TempTable(ctx, "(id INTEGER)")
+		if err != nil {
+			return err
+		}
+
+		if err = insertPoints(ctx, 1, 2); err != nil {
+			return err
+		}
+
+		got, err := QueryOne[int](ctx, db, "SELECT COUNT(*) FROM point")
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, 2, got, "buffered rows should be flushed before other statements")
+
+		if err = ExecBuffered(ctx, "INSERT INTO "+name+" (id) VALUES (?)", 1); err != nil {
+			return err
+		}
+
+		return insertPoints(ctx, 3)
+	}))
+
+	assert.Equal(t, []string{
+		"CREATE TEMPORARY TABLE " + name + " (id INTEGER)",
+		"INSERT INTO point (id, x) VALUES (?, ?), (?, ?)",
+		"SELECT COUNT(*) FROM point",
+		"INSERT INTO " + name + " (id) VALUES (?)",
+		"INSERT INTO point (id, x) VALUES (?, ?)",
+	}, log.queries, "buffered rows should be flushed before dropping temporary tables")
+	assert.Equal(t, 3, countPoints(t, db), "buffered rows should be flushed before commit")
+}
+
+func TestExecBuffered_rollback(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(pointSchema))
+	log := &queryLog{}
+	m := New(db, WithRewriteSQL(log.rewrite))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, insertPoints(ctx, 1, 2))
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Empty(t, log.queries, "buffered rows should be discarded")
+	assert.Zero(t, countPoints(t, db))
+}
+
+func TestExecBuffered_error(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(pointSchema))
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPoints(ctx, 1, 2))
+		require.NoError(t, ExecBuffered(ctx, "INSERT INTO point (x) VALUES (?)", 0))
+		require.NoError(t, insertPoints(ctx, 3, 3, 4))
+
+		_, err := Get(ctx).ExecContext(ctx, "DELETE FROM point WHERE id = 0")
+
+		var be *BufferedExecError
+
+		require.ErrorAs(t, err, &be)
+		assert.Equal(t, 4, be.First)
+		assert.Equal(t, 6, be.Last)
+
+		return nil
+	}))
+}
+
+func TestExecBuffered_noTransaction(t *testing.T) {
+	require.ErrorIs(t, ExecBuffered(context.Background(), "INSERT INTO point (id) VALUES (?)", 1), ErrNoTransaction)
+}
+
+func TestParseInsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		args  int
+		rows  int
+		want  string
+	}{
+		{
+			name:  "question",
+			query: "insert into point (id, x) values (?,?);",
+			args:  2,
+			rows:  2,
+			want:  "insert into point (id, x) values (?,?), (?,?)",
+		},
+		{
+			name:  "dollar",
+			query: "INSERT INTO point (id, x) VALUES ($1, $2)",
+			args:  2,
+			rows:  3,
+			want:  "INSERT INTO point (id, x) VALUES ($1, $2), ($3, $4), ($5, $6)",
+		},
+		{name: "unordered", query: "INSERT INTO point (id, x) VALUES ($2, $1)", args: 2},
+		{name: "mixed", query: "INSERT INTO point (id, x) VALUES (?, $2)", args: 2},
+		{name: "literal", query: "INSERT INTO point (id, x) VALUES (?, 1)", args: 1},
+		{name: "count", query: "INSERT INTO point (id, x) VALUES (?, ?)", args: 1},
+		{name: "conflict", query: "INSERT INTO point (id) VALUES (?) ON CONFLICT DO NOTHING", args: 1},
+		{name: "update", query: "UPDATE point SET x = ?", args: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := parseInsert(tt.query, tt.args)
+
+			require.Equal(t, tt.want != "", ok)
+
+			if ok {
+				b.rows = tt.rows
+				assert.Equal(t, tt.want, b.statement())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCallbackPanic wraps the panics of callbacks registered by OnCommit and OnRollback, see Info.CallbackErr.
+var ErrCallbackPanic = errors.New("txx: callback panicked")
+
+// OnCommit registers function f to be called once the transaction of the context is committed,
+// after the function run by Wrap returns, in registration order.
+//
+// Function f is not called if the transaction is rolled back, and only once with Ensure reuses.
+// Without a transaction begun by txx in the context, f is called right away, like in a shared snapshot,
+// and so is it once the transaction is committed. OnCommit returns ErrAlreadyFinished, f being dropped,
+// if the transaction is being committed or rolled back, its outcome being unknown.
+//
+// Panics of f are recovered, so that remaining functions are still called, and reported by Info.CallbackErr.
+// Function f is given the context of Wrap.
+func OnCommit(ctx context.Context, f func(ctx context.Context)) error {
+	s := Get(ctx).s
+	if s == nil || s.snapshot {
+		f(ctx)
+
+		return nil
+	}
+
+	s.mu.Lock()
+
+	if !s.finishing && !s.closed {
+		s.onCommit = append(s.onCommit, f)
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	e, closed := s.ending, s.closed
+	s.mu.Unlock()
+
+	switch {
+	case e == nil && !closed:
+		return ErrAlreadyFinished
+	case e != nil && e.committed:
+		f(ctx)
+	}
+
+	return nil
+}
+
+// OnRollback registers function f to be called once the transaction of the context is rolled back,
+// after the function run by Wrap returns, in registration order.
+//
+// Function f is given the cause of the rollback: the error returned by the function, a *PanicError if it panicked,
+// or why the transaction was rollback-only, like ErrStatementLimitExceeded.
+// The cause is nil if the function called Current.Rollback and returned nil.
+//
+// Function f is not called if the transaction is committed, nor if its commit fails as its outcome is unknown,
+// and only once with Ensure reuses.
+// If registered in a savepoint rolled back by Nested, f is called right away with the error of its function.
+// Without a transaction begun by txx in the context, there is nothing to roll back and f is never called.
+//
+// Panics of f are recovered, so that remaining functions are still called, and reported by Info.CallbackErr.
+// Function f is given the context of Wrap.
+func OnRollback(ctx context.Context, f func(ctx context.Context, cause error)) {
+	s := Get(ctx).s
+	if s == nil || s.snapshot {
+		return
+	}
+
+	s.mu.Lock()
+
+	if !s.calledBack {
+		s.onRollback = append(s.onRollback, f)
+		s.mu.Unlock()
+
+		return
+	}
+
+	rolledBack, cause := s.ending != nil && s.ending.rolledBack, s.cause
+	s.mu.Unlock()
+
+	if rolledBack {
+		f(ctx, cause)
+	}
+}
+
+// callbacks calls the functions registered by OnCommit if committed, or OnRollback if rolled back,
+// joining their panics with the ones of rolled back savepoints.
+func (s *scope) callbacks(ctx context.Context, c *completion) error {
+	s.mu.Lock()
+	onCommit, onRollback, errs := s.onCommit, s.onRollback, s.callbackErrs
+	s.onCommit, s.onRollback, s.callbackErrs = nil, nil, nil
+	s.calledBack, s.cause = true, c.err
+	s.mu.Unlock()
+
+	if c.committed {
+		for _, f := range onCommit {
+			errs = append(errs, callback(func() { f(ctx) }))
+		}
+	} else if c.rolledBack {
+		errs = append(errs, rollbackCallbacks(ctx, onRollback, c.err)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// rollbackCallbacks calls given functions registered by OnRollback with given cause, returning their panics.
+func rollbackCallbacks(ctx context.Context, fs []func(ctx context.Context, cause error), cause error) []error {
+	errs := make([]error, 0, len(fs))
+
+	for _, f := range fs {
+		errs = append(errs, callback(func() { f(ctx, cause) }))
+	}
+
+	return errs
+}
+
+// callback calls f, returning its panic as an error, if any.
+func callback(f func()) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", ErrCallbackPanic, p)
+		}
+	}()
+
+	f()
+
+	return nil
+}
@@ -0,0 +1,314 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// record returns a callback appending given name to calls.
+func record(calls *[]string, name string) func(ctx context.Context) {
+	return func(_ context.Context) {
+		*calls = append(*calls, name)
+	}
+}
+
+func TestOnCommit(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, OnCommit(ctx, record(&calls, "first")))
+
+		if err := m.Ensure(ctx, nil, func(ctx context.Context) error {
+			require.NoError(t, OnCommit(ctx, record(&calls, "nested")))
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		require.NoError(t, OnCommit(ctx, record(&calls, "last")))
+		assert.Empty(t, calls, "callbacks should wait for commit")
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"first", "nested", "last"}, calls)
+}
+
+func TestOnCommit_rollback(t *testing.T) {
+	errCommit := errors.New("commit") //nolint:goerr113
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.Error(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, OnCommit(ctx, record(&calls, "error")))
+
+		return fail(ctx)
+	}))
+
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			require.NoError(t, OnCommit(ctx, record(&calls, "panic")))
+
+			panic("test")
+		})
+	})
+
+	require.Error(t, newManager(&fakeBackend{commitErr: errCommit}).Wrap(
+		context.Background(),
+		nil,
+		func(ctx context.Context) error {
+			require.NoError(t, OnCommit(ctx, record(&calls, "commit error")))
+
+			return nil
+		},
+	))
+
+	assert.Empty(t, calls)
+}
+
+func TestOnCommit_panic(t *testing.T) {
+	var (
+		calls []string
+		info  Info
+	)
+
+	m := newManager(&fakeBackend{}, WithFinally(func(i Info) { info = i }))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, OnCommit(ctx, func(_ context.Context) { panic("first") }))
+		require.NoError(t, OnCommit(ctx, record(&calls, "second")))
+		require.NoError(t, OnCommit(ctx, func(_ context.Context) { panic("third") }))
+
+		return nil
+	}), "committed transaction should not fail")
+
+	assert.Equal(t, []string{"second"}, calls)
+	assert.True(t, info.Committed)
+	require.ErrorIs(t, info.CallbackErr, ErrCallbackPanic)
+	assert.ErrorContains(t, info.CallbackErr, "first")
+	assert.ErrorContains(t, info.CallbackErr, "third")
+}
+
+func TestOnCommit_noTransaction(t *testing.T) {
+	var calls []string
+
+	require.NoError(t, OnCommit(context.Background(), record(&calls, "now")))
+
+	assert.Equal(t, []string{"now"}, calls)
+}
+
+func TestOnCommit_finished(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, OnCommit(ctx, record(&calls, "before")))
+		require.NoError(t, Get(ctx).Commit(ctx))
+		require.NoError(t, OnCommit(ctx, record(&calls, "after")))
+
+		return nil
+	}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, Get(ctx).Rollback(ctx))
+		require.NoError(t, OnCommit(ctx, record(&calls, "rolled back")))
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"after", "before"}, calls, "callbacks registered once committed should run right away")
+}
+
+func TestOnCommit_ending(t *testing.T) {
+	var (
+		captured context.Context
+		calls    []string
+		err      error
+	)
+
+	db := fakedb.Open(t, &fakedb.Driver{
+		Commit: func() error {
+			err = OnCommit(captured, record(&calls, "ending"))
+
+			return nil
+		},
+	})
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		captured = ctx
+
+		return nil
+	}))
+
+	require.ErrorIs(t, err, ErrAlreadyFinished, "callbacks registered while committing should be rejected")
+	assert.Empty(t, calls)
+}
+
+func TestOnCommit_nested(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.Error(t, m.Nested(ctx, nil, func(ctx context.Context) error {
+			require.NoError(t, OnCommit(ctx, record(&calls, "rolled back")))
+
+			return fail(ctx)
+		}))
+
+		return m.Nested(ctx, nil, func(ctx context.Context) error {
+			require.NoError(t, OnCommit(ctx, record(&calls, "released")))
+
+			return nil
+		})
+	}))
+
+	assert.Equal(t, []string{"released"}, calls, "callbacks rolled back to a savepoint should be dropped")
+}
+
+// recordCause returns a callback appending given name and the rollback cause, if any, to calls.
+func recordCause(calls *[]string, name string) func(ctx context.Context, cause error) {
+	return func(_ context.Context, cause error) {
+		if cause != nil {
+			name += ": " + cause.Error()
+		}
+
+		*calls = append(*calls, name)
+	}
+}
+
+func TestOnRollback(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.Error(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		OnRollback(ctx, recordCause(&calls, "first"))
+
+		if err := m.Ensure(ctx, nil, func(ctx context.Context) error {
+			OnRollback(ctx, recordCause(&calls, "nested"))
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		assert.Empty(t, calls, "callbacks should wait for the outer rollback")
+
+		return fail(ctx)
+	}))
+
+	assert.Equal(t, []string{"first: test", "nested: test"}, calls)
+}
+
+func TestOnRollback_commit(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		OnRollback(ctx, recordCause(&calls, "committed"))
+
+		return nil
+	}))
+
+	OnRollback(context.Background(), recordCause(&calls, "no transaction"))
+
+	assert.Empty(t, calls)
+}
+
+func TestOnRollback_panic(t *testing.T) {
+	var (
+		cause error
+		info  Info
+	)
+
+	errPanic := errors.New("panicked") //nolint:goerr113
+	m := newManager(&fakeBackend{}, WithFinally(func(i Info) { info = i }))
+
+	require.PanicsWithValue(t, errPanic, func() {
+		_ = m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			OnRollback(ctx, func(_ context.Context, err error) { cause = err })
+			OnRollback(ctx, func(_ context.Context, _ error) { panic("callback") })
+
+			panic(errPanic)
+		})
+	})
+
+	var panicErr *PanicError
+
+	require.ErrorAs(t, cause, &panicErr)
+	assert.Equal(t, errPanic, panicErr.Value)
+	require.ErrorIs(t, cause, errPanic)
+	assert.EqualError(t, cause, "panic: panicked")
+
+	assert.Equal(t, CausePanic, info.Cause)
+	require.ErrorIs(t, info.CallbackErr, ErrCallbackPanic)
+	assert.ErrorContains(t, info.CallbackErr, "callback")
+}
+
+func TestOnRollback_commitError(t *testing.T) {
+	errCommit := errors.New("commit") //nolint:goerr113
+
+	var calls []string
+
+	require.ErrorIs(t, newManager(&fakeBackend{commitErr: errCommit}).Wrap(
+		context.Background(),
+		nil,
+		func(ctx context.Context) error {
+			OnRollback(ctx, recordCause(&calls, "rolled back"))
+
+			return nil
+		},
+	), errCommit)
+
+	assert.Empty(t, calls, "a failed commit is not a rollback")
+}
+
+func TestOnRollback_finished(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, Get(ctx).Rollback(ctx))
+		OnRollback(ctx, recordCause(&calls, "manual"))
+		assert.Empty(t, calls, "callbacks should wait for Wrap to return")
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"manual"}, calls)
+}
+
+func TestOnRollback_nested(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		OnRollback(ctx, recordCause(&calls, "outer"))
+
+		require.Error(t, m.Nested(ctx, nil, func(ctx context.Context) error {
+			OnRollback(ctx, recordCause(&calls, "savepoint"))
+
+			return fail(ctx)
+		}))
+
+		assert.Equal(t, []string{"savepoint: test"}, calls, "callbacks rolled back to a savepoint should run right away")
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"savepoint: test"}, calls)
+}
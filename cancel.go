@@ -0,0 +1,80 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTransactionFinished is the cause of the cancellation of the context of a completed transaction,
+// see WithCancelOnCompletion.
+var ErrTransactionFinished = errors.New("txx: transaction finished")
+
+// WithCancelOnCompletion cancels the context passed to the transaction function right after
+// commit or rollback, with ErrTransactionFinished as cause.
+//
+// Goroutines which captured that context then fail fast instead of using a finished transaction.
+// Callers reusing the context for post-processing must not enable it.
+// A context given to Wrap with a deadline is canceled on completion anyway, with context.Canceled as cause.
+func WithCancelOnCompletion() Option {
+	return func(m *Manager) {
+		m.r.cancel = true
+	}
+}
+
+// deadlineCauseKey is the context key of the deadlineCause of a context, see functionContext.
+type deadlineCauseKey struct{}
+
+// deadlineCause is the cause reported by a context once its deadline is exceeded.
+type deadlineCause struct {
+	deadline time.Time
+	cause    error
+}
+
+// withDeadlineCause returns ctx, whose expiry is reported with given cause, recorded for functionContext.
+func withDeadlineCause(ctx context.Context, cause error) context.Context {
+	deadline, _ := ctx.Deadline()
+
+	return context.WithValue(ctx, deadlineCauseKey{}, deadlineCause{deadline: deadline, cause: cause})
+}
+
+// functionContext returns the context of a transaction function derived from ctx, and its cancel function.
+//
+// The context is detached from ctx, so that ctx doesn't retain it once the transaction is completed:
+// it keeps the deadline of ctx, and its cause if recorded by withDeadlineCause, while the cancellation of ctx
+// is only propagated until stop is called. The deadline is released by stop too, canceling the context.
+func functionContext(ctx context.Context) (fctx context.Context, cancel context.CancelCauseFunc, stop func()) {
+	fctx = context.WithoutCancel(ctx)
+	cancelDeadline := context.CancelFunc(func() {})
+
+	if deadline, ok := ctx.Deadline(); ok {
+		cause := context.DeadlineExceeded
+		if c, ok := ctx.Value(deadlineCauseKey{}).(deadlineCause); ok && c.deadline.Equal(deadline) {
+			cause = c.cause
+		}
+
+		fctx, cancelDeadline = context.WithDeadlineCause(fctx, deadline, cause)
+	}
+
+	fctx, cancel = context.WithCancelCause(fctx)
+	stopAfter := context.AfterFunc(ctx, func() {
+		// an expired deadline is reported by the deadline of fctx
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cancel(context.Cause(ctx))
+		}
+	})
+
+	return fctx, cancel, func() {
+		stopAfter()
+		cancelDeadline()
+	}
+}
+
+// settle waits for ctx to be done once fctx, its function context, expired:
+// they share the deadline, the timer of ctx possibly firing later.
+func settle(ctx, fctx context.Context) {
+	// the cancellation of ctx is propagated as context.Canceled
+	if errors.Is(fctx.Err(), context.DeadlineExceeded) {
+		<-ctx.Done()
+	}
+}
@@ -0,0 +1,155 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCancelOnCompletion(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithCancelOnCompletion())
+
+	for _, f := range []func(ctx context.Context) error{checkTxExists, fail} {
+		var captured context.Context
+
+		_ = m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			captured = ctx
+
+			require.NoError(t, ctx.Err())
+
+			return f(ctx)
+		})
+
+		require.ErrorIs(t, captured.Err(), context.Canceled)
+		assert.ErrorIs(t, context.Cause(captured), ErrTransactionFinished)
+	}
+}
+
+func TestWithCancelOnCompletion_panic(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithCancelOnCompletion())
+
+	var captured context.Context
+
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			captured = ctx
+
+			panic("test")
+		})
+	})
+
+	assert.ErrorIs(t, context.Cause(captured), ErrTransactionFinished)
+}
+
+func TestWithCancelOnCompletion_disabled(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	var captured context.Context
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		captured = ctx
+
+		return nil
+	}))
+
+	assert.NoError(t, captured.Err())
+}
+
+func TestFunctionContext(t *testing.T) {
+	errCause := errors.New("cause") //nolint:goerr113
+	m := New(testdb.Open(t, inMemory))
+	deadline := time.Now().Add(time.Hour)
+
+	parent, cancel := context.WithCancelCause(context.Background())
+	parent, cancelDeadline := context.WithDeadline(parent, deadline)
+
+	defer cancelDeadline()
+
+	var captured context.Context
+
+	require.NoError(t, m.Wrap(parent, nil, func(ctx context.Context) error {
+		captured = ctx
+
+		return nil
+	}))
+
+	actual, ok := captured.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, deadline, actual, "deadline should be kept")
+
+	require.ErrorIs(t, m.Wrap(parent, nil, func(ctx context.Context) error {
+		cancel(errCause)
+		<-ctx.Done()
+
+		return context.Cause(ctx)
+	}), errCause)
+
+	require.ErrorIs(t, captured.Err(), context.Canceled, "deadline should be released once completed")
+	assert.NotErrorIs(t, context.Cause(captured), errCause, "context should be released from its parent once completed")
+}
+
+func TestFunctionContext_deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, New(testdb.Open(t, inMemory)).Wrap(ctx, nil, func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}), context.DeadlineExceeded)
+}
+
+func TestWrap_canceledBeforeCommit(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db)
+
+	var infos []Info
+
+	m.finally = func(info Info) {
+		infos = append(infos, info)
+	}
+
+	tests := []struct {
+		name string
+		wrap func(ctx context.Context, f func(ctx context.Context) error) error
+	}{
+		{
+			name: "package",
+			wrap: func(ctx context.Context, f func(ctx context.Context) error) error {
+				return Wrap(ctx, db, nil, f)
+			},
+		},
+		{
+			name: "manager",
+			wrap: func(ctx context.Context, f func(ctx context.Context) error) error {
+				return m.Wrap(ctx, nil, f)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			require.ErrorIs(t, tt.wrap(ctx, func(ctx context.Context) error {
+				_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+				require.NoError(t, err)
+
+				cancel()
+
+				return nil
+			}), context.Canceled)
+
+			assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(db)))
+		})
+	}
+
+	require.Len(t, infos, 1)
+	assert.Equal(t, CauseCanceled, infos[0].Cause)
+}
@@ -0,0 +1,165 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapping the last begin error, while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("txx: circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker configures a circuit breaker around transaction begin.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive begin failures opening the circuit.
+	Threshold int
+	// CoolDown is the time the circuit stays open before allowing probes.
+	CoolDown time.Duration
+	// Probes is the number of concurrent begin attempts allowed while half-open, 1 if not positive.
+	Probes int
+}
+
+// WithCircuitBreaker enables a circuit breaker around transaction begin.
+//
+// After Threshold consecutive begin failures, Wrap fails immediately with ErrCircuitOpen
+// until CoolDown has elapsed and a half-open probe succeeds.
+// Begin failures caused by the caller context being done are not counted.
+func WithCircuitBreaker(cfg CircuitBreaker) Option {
+	return func(m *Manager) {
+		if cfg.Probes <= 0 {
+			cfg.Probes = 1
+		}
+
+		m.breaker = &breaker{cfg: cfg}
+	}
+}
+
+type breaker struct {
+	cfg   CircuitBreaker
+	clock Clock
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	probes   int
+	openedAt time.Time
+	last     error
+}
+
+// allow returns an error if a begin attempt is not allowed.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		if b.clock.Now().Sub(b.openedAt) < b.cfg.CoolDown {
+			return fmt.Errorf("%w: %w", ErrCircuitOpen, b.last)
+		}
+
+		b.state = CircuitHalfOpen
+		b.probes = 0
+	}
+
+	if b.state == CircuitHalfOpen {
+		if b.probes >= b.cfg.Probes {
+			return fmt.Errorf("%w: %w", ErrCircuitOpen, b.last)
+		}
+
+		b.probes++
+	}
+
+	return nil
+}
+
+// done records the outcome of an allowed begin attempt.
+//
+// Attempts canceled by the caller are not failures, unlike the ones exceeding the begin timeout.
+func (b *breaker) done(ctx context.Context, err error) {
+	timeout := errors.Is(context.Cause(ctx), ErrBeginTimeout)
+	if timeout && err != nil {
+		err = fmt.Errorf("%w: %w", ErrBeginTimeout, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case err == nil:
+		b.state = CircuitClosed
+		b.failures = 0
+		b.last = nil
+	case ctx.Err() != nil && !timeout:
+		// canceled by the caller, not a failure of the database
+		if b.state == CircuitHalfOpen {
+			b.probes--
+		}
+	case b.state == CircuitHalfOpen:
+		b.last = err
+		b.open()
+	default:
+		b.last = err
+		b.failures++
+
+		if b.failures >= b.cfg.Threshold {
+			b.open()
+		}
+	}
+}
+
+func (b *breaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = b.clock.Now()
+	b.failures = 0
+}
+
+func (b *breaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// breakerBeginner guards a beginner with a circuit breaker.
+type breakerBeginner struct {
+	beginner
+
+	breaker *breaker
+}
+
+func (b breakerBeginner) begin(ctx context.Context, opts *sql.TxOptions) (transaction, error) {
+	if err := b.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	tx, err := b.beginner.begin(ctx, opts)
+
+	b.breaker.done(ctx, err)
+
+	return tx, err
+}
@@ -0,0 +1,110 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitState_String(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+	assert.Equal(t, "CircuitState(42)", CircuitState(42).String())
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	errDown := errors.New("database is down") //nolint:goerr113
+	c := clock.NewFake(time.Unix(0, 0))
+	backend := &fakeBackend{script: []error{errDown, errDown, errDown, nil}}
+	m := newManager(backend, WithClock(c), WithCircuitBreaker(CircuitBreaker{Threshold: 2, CoolDown: time.Second}))
+	wrap := func() error {
+		return m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			return nil
+		})
+	}
+
+	// closed: failures below threshold
+	require.ErrorIs(t, wrap(), errDown)
+	assert.Equal(t, CircuitClosed, m.Stats().Circuit)
+
+	// threshold reached
+	require.ErrorIs(t, wrap(), errDown)
+	assert.Equal(t, CircuitOpen, m.Stats().Circuit)
+
+	// open: fail fast without reaching the database
+	err := wrap()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.ErrorIs(t, err, errDown)
+	assert.Len(t, backend.calls, 2)
+
+	// half-open probe fails
+	c.Advance(time.Second)
+
+	require.ErrorIs(t, wrap(), errDown)
+	assert.Equal(t, CircuitOpen, m.Stats().Circuit)
+	require.ErrorIs(t, wrap(), ErrCircuitOpen)
+
+	// half-open probe succeeds
+	c.Advance(time.Second)
+
+	require.NoError(t, wrap())
+	assert.Equal(t, CircuitClosed, m.Stats().Circuit)
+	assert.Equal(t, []string{"begin", "begin", "begin", "begin", "commit"}, backend.calls)
+}
+
+func TestWithCircuitBreaker_probes(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	b := &breaker{cfg: CircuitBreaker{Threshold: 1, CoolDown: time.Second, Probes: 1}, clock: c}
+	ctx := context.Background()
+
+	require.NoError(t, b.allow())
+	b.done(ctx, errors.New("test")) //nolint:goerr113
+
+	c.Advance(time.Second)
+
+	require.NoError(t, b.allow())
+	assert.Equal(t, CircuitHalfOpen, b.State())
+	require.ErrorIs(t, b.allow(), ErrCircuitOpen, "only one probe allowed")
+
+	b.done(ctx, nil)
+
+	assert.Equal(t, CircuitClosed, b.State())
+	require.NoError(t, b.allow())
+}
+
+func TestWithCircuitBreaker_canceled(t *testing.T) {
+	b := &breaker{cfg: CircuitBreaker{Threshold: 1, Probes: 1}, clock: clock.Real{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, b.allow())
+	b.done(ctx, context.Canceled)
+
+	assert.Equal(t, CircuitClosed, b.State())
+}
+
+func TestWithCircuitBreaker_beginTimeout(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	m := New(db, WithBeginTimeout(10*time.Millisecond), WithCircuitBreaker(CircuitBreaker{Threshold: 1, CoolDown: time.Hour}))
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, noop), ErrBeginTimeout)
+	assert.Equal(t, CircuitOpen, m.Stats().Circuit, "begin timeouts should count as failures")
+
+	err = m.Wrap(context.Background(), nil, noop)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.ErrorIs(t, err, ErrBeginTimeout)
+}
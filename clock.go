@@ -0,0 +1,18 @@
+package txx
+
+import "github.com/MartyHub/txx/internal/clock"
+
+// Clock tells the time and creates timers for every time-based behavior of a Manager.
+//
+// txxtest provides a controllable implementation.
+type Clock = clock.Clock
+
+// Timer is a single event timer created by a Clock.
+type Timer = clock.Timer
+
+// WithClock overrides the Clock of a Manager, the time package by default.
+func WithClock(c Clock) Option {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
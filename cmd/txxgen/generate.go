@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const txxPath = "github.com/MartyHub/txx"
+
+var errUnsupported = errors.New("unsupported")
+
+type mode int
+
+const (
+	modeEnsure mode = iota
+	modeReadOnly
+	modeNew
+	modeSkip
+)
+
+func (m mode) String() string {
+	switch m {
+	case modeReadOnly:
+		return "in a read-only transaction"
+	case modeNew:
+		return "in a new transaction"
+	case modeSkip:
+		return "without transaction handling"
+	case modeEnsure:
+	}
+
+	return "in a transaction"
+}
+
+// std returns if given import path is the one of a standard package.
+func std(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+
+	return !strings.Contains(first, ".")
+}
+
+type method struct {
+	name    string
+	mode    mode
+	params  []param
+	results []string // types, the trailing error excluded
+}
+
+type param struct {
+	name     string
+	typ      string
+	variadic bool
+}
+
+type generator struct {
+	fset    *token.FileSet
+	imports map[string]string // path by name
+	used    map[string]bool   // import paths
+}
+
+// generate the decorator of interface typeName declared in given source.
+func generate(src []byte, filename, typeName string) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{fset: fset, imports: make(map[string]string), used: map[string]bool{"context": true, txxPath: true}}
+
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		g.imports[name] = path
+	}
+
+	iface, err := findInterface(file, typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make([]method, 0, len(iface.Methods.List))
+
+	for _, field := range iface.Methods.List {
+		m, err := g.method(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fset.Position(field.Pos()), err)
+		}
+
+		methods = append(methods, m)
+	}
+
+	var buf bytes.Buffer
+
+	g.write(&buf, file.Name.Name, typeName, methods)
+
+	return format.Source(buf.Bytes())
+}
+
+func findInterface(file *ast.File, typeName string) (*ast.InterfaceType, error) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts, _ := spec.(*ast.TypeSpec)
+			if ts.Name.Name != typeName {
+				continue
+			}
+
+			if iface, ok := ts.Type.(*ast.InterfaceType); ok {
+				return iface, nil
+			}
+
+			return nil, fmt.Errorf("%s is not an interface: %w", typeName, errUnsupported)
+		}
+	}
+
+	return nil, fmt.Errorf("interface %s not found", typeName) //nolint:goerr113
+}
+
+func (g *generator) method(field *ast.Field) (method, error) {
+	ft, ok := field.Type.(*ast.FuncType)
+	if !ok || len(field.Names) != 1 {
+		return method{}, fmt.Errorf("embedded interface: %w", errUnsupported)
+	}
+
+	m := method{name: field.Names[0].Name}
+
+	if field.Doc != nil {
+		for _, comment := range field.Doc.List {
+			switch strings.TrimSpace(comment.Text) {
+			case "//txx:readonly":
+				m.mode = modeReadOnly
+			case "//txx:new":
+				m.mode = modeNew
+			case "//txx:skip":
+				m.mode = modeSkip
+			}
+		}
+	}
+
+	params := expand(ft.Params)
+	if len(params) == 0 || g.expr(params[0].Type) != "context.Context" {
+		return method{}, fmt.Errorf("%s must take a context.Context first: %w", m.name, errUnsupported)
+	}
+
+	for i, p := range params[1:] {
+		typ := p.Type
+		variadic := false
+
+		if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+			typ, variadic = ellipsis.Elt, true
+		}
+
+		name := fmt.Sprintf("p%d", i+1)
+		if len(p.Names) == 1 && !reserved(p.Names[0].Name) {
+			name = p.Names[0].Name
+		}
+
+		m.params = append(m.params, param{name: name, typ: g.expr(typ), variadic: variadic})
+	}
+
+	results := expand(ft.Results)
+	if len(results) == 0 || g.expr(results[len(results)-1].Type) != "error" {
+		return method{}, fmt.Errorf("%s must return an error last: %w", m.name, errUnsupported)
+	}
+
+	for _, r := range results[:len(results)-1] {
+		m.results = append(m.results, g.expr(r.Type))
+	}
+
+	return m, nil
+}
+
+// expand fields so that each has at most one name.
+func expand(list *ast.FieldList) []*ast.Field {
+	if list == nil {
+		return nil
+	}
+
+	var result []*ast.Field
+
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			result = append(result, field)
+
+			continue
+		}
+
+		for _, name := range field.Names {
+			result = append(result, &ast.Field{Names: []*ast.Ident{name}, Type: field.Type})
+		}
+	}
+
+	return result
+}
+
+func reserved(name string) bool {
+	switch name {
+	case "_", "ctx", "d", "err":
+		return true
+	}
+
+	return len(name) > 1 && name[0] == 'r' && strings.Trim(name[1:], "0123456789") == ""
+}
+
+// expr returns the source of given type expression, recording the imports it uses.
+func (g *generator) expr(e ast.Expr) string {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				if path, found := g.imports[ident.Name]; found {
+					g.used[path] = true
+				}
+			}
+		}
+
+		return true
+	})
+
+	var buf bytes.Buffer
+
+	_ = printer.Fprint(&buf, g.fset, e)
+
+	return buf.String()
+}
+
+func (g *generator) write(buf *bytes.Buffer, pkg, typeName string, methods []method) {
+	decorator := typeName + "Tx"
+
+	fmt.Fprintf(buf, "// Code generated by txxgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+
+	paths := make([]string, 0, len(g.used))
+	for path := range g.used {
+		paths = append(paths, path)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if std(paths[i]) != std(paths[j]) {
+			return std(paths[i])
+		}
+
+		return paths[i] < paths[j]
+	})
+
+	for i, path := range paths {
+		name := path[strings.LastIndex(path, "/")+1:]
+
+		if i > 0 && std(paths[i-1]) && !std(path) {
+			buf.WriteString("\n")
+		}
+
+		for alias, other := range g.imports {
+			if other == path && alias != name {
+				fmt.Fprintf(buf, "%s ", alias)
+			}
+		}
+
+		fmt.Fprintf(buf, "%q\n", path)
+	}
+
+	fmt.Fprintf(buf, ")\n\n")
+	fmt.Fprintf(buf, "// %s decorates a %s, running its methods in transactions.\n", decorator, typeName)
+	fmt.Fprintf(buf, "type %s struct {\n*txx.Manager\n\nNext %s\n}\n\n", decorator, typeName)
+	fmt.Fprintf(buf, "// New%s returns a %s running next methods in transactions of m.\n", decorator, decorator)
+	fmt.Fprintf(buf, "func New%s(m *txx.Manager, next %s) *%s {\n", decorator, typeName, decorator)
+	fmt.Fprintf(buf, "return &%s{Manager: m, Next: next}\n}\n\n", decorator)
+	fmt.Fprintf(buf, "var _ %s = (*%s)(nil)\n", typeName, decorator)
+
+	for _, m := range methods {
+		writeMethod(buf, decorator, m)
+	}
+}
+
+func writeMethod(buf *bytes.Buffer, decorator string, m method) {
+	params := []string{"ctx context.Context"}
+	args := []string{"ctx"}
+
+	for _, p := range m.params {
+		if p.variadic {
+			params = append(params, p.name+" ..."+p.typ)
+			args = append(args, p.name+"...")
+		} else {
+			params = append(params, p.name+" "+p.typ)
+			args = append(args, p.name)
+		}
+	}
+
+	results := make([]string, 0, len(m.results)+1)
+	names := make([]string, 0, len(m.results)+1)
+
+	for i, r := range m.results {
+		results = append(results, fmt.Sprintf("r%d %s", i, r))
+		names = append(names, fmt.Sprintf("r%d", i))
+	}
+
+	results = append(results, "err error")
+	names = append(names, "err")
+
+	call := fmt.Sprintf("d.Next.%s(%s)", m.name, strings.Join(args, ", "))
+
+	fmt.Fprintf(buf, "\n// %s runs Next.%s %s.\n", m.name, m.name, m.mode)
+	fmt.Fprintf(buf, "func (d *%s) %s(%s) (%s) {\n", decorator, m.name, strings.Join(params, ", "), strings.Join(results, ", "))
+
+	switch m.mode {
+	case modeSkip:
+		fmt.Fprintf(buf, "return %s\n}\n", call)
+
+		return
+	case modeNew:
+		fmt.Fprintf(buf, "err = d.Manager.Wrap(ctx, nil, ")
+	case modeReadOnly:
+		fmt.Fprintf(buf, "err = d.Manager.Ensure(ctx, txx.ReadOnly(), ")
+	case modeEnsure:
+		fmt.Fprintf(buf, "err = d.Manager.Ensure(ctx, nil, ")
+	}
+
+	fmt.Fprintf(buf, "func(ctx context.Context) error {\n")
+
+	if len(m.results) == 0 {
+		fmt.Fprintf(buf, "return %s\n", call)
+	} else {
+		fmt.Fprintf(buf, "var err error\n\n%s = %s\n\nreturn err\n", strings.Join(names, ", "), call)
+	}
+
+	fmt.Fprintf(buf, "})\n\nreturn %s\n}\n", strings.Join(names, ", "))
+}
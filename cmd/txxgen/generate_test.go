@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files") //nolint:gochecknoglobals
+
+func TestGenerate_golden(t *testing.T) {
+	tests := []struct {
+		source   string
+		typeName string
+		golden   string
+	}{
+		{
+			source:   "testdata/service.go",
+			typeName: "Service",
+			golden:   "testdata/service_txx.golden",
+		},
+		{
+			source:   "../../internal/txxgenexample/store.go",
+			typeName: "Store",
+			golden:   "../../internal/txxgenexample/store_txx.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			src, err := os.ReadFile(tt.source)
+			require.NoError(t, err)
+
+			got, err := generate(src, tt.source, tt.typeName)
+			require.NoError(t, err)
+
+			if *update {
+				require.NoError(t, os.WriteFile(tt.golden, got, 0o600))
+			}
+
+			want, err := os.ReadFile(tt.golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "not found",
+			src:  "package p\n",
+			want: "interface Service not found",
+		},
+		{
+			name: "not an interface",
+			src:  "package p\ntype Service struct{}\n",
+			want: "not an interface",
+		},
+		{
+			name: "embedded",
+			src:  "package p\nimport \"io\"\ntype Service interface{ io.Closer }\n",
+			want: "embedded interface",
+		},
+		{
+			name: "no context",
+			src:  "package p\ntype Service interface{ Do(id int) error }\n",
+			want: "must take a context.Context first",
+		},
+		{
+			name: "no error",
+			src:  "package p\nimport \"context\"\ntype Service interface{ Do(ctx context.Context) int }\n",
+			want: "must return an error last",
+		},
+		{
+			name: "syntax",
+			src:  "package",
+			want: "expected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate([]byte(tt.src), "service.go", "Service")
+
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "service_txx.go")
+
+	require.NoError(t, run("testdata/service.go", "Service", output))
+
+	got, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	want, err := os.ReadFile("testdata/service_txx.golden")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	require.Error(t, run(filepath.Join(dir, "missing.go"), "Service", output))
+	require.Error(t, run("testdata/service.go", "Missing", output))
+}
@@ -0,0 +1,56 @@
+// Command txxgen generates transactional decorators for service interfaces.
+//
+// Every method of the decorated interface must take a context.Context first and return an error last.
+// The decorator runs each method with Manager.Ensure, the transaction options being set
+// by directives in the method comments:
+//
+//	//txx:readonly  run in a read-only transaction
+//	//txx:new       always run in a new transaction, with Manager.Wrap
+//	//txx:skip      run without any transaction handling
+//
+// Typical usage, next to the interface declaration:
+//
+//	//go:generate go run github.com/MartyHub/txx/cmd/txxgen -type Service
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the interface to decorate (required)")
+	source := flag.String("source", os.Getenv("GOFILE"), "file declaring the interface")
+	output := flag.String("output", "", "output file, <source>_txx.go by default")
+	flag.Parse()
+
+	if *typeName == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(*source, ".go") + "_txx.go"
+	}
+
+	if err := run(*source, *typeName, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "txxgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(source, typeName, output string) error {
+	src, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	result, err := generate(src, source, typeName)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, result, 0o644) //nolint:gosec,mnd
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	stdsql "database/sql"
+	"time"
+
+	"example.com/model"
+)
+
+type Service interface {
+	//txx:readonly
+	Find(ctx context.Context, id int64, at time.Time) (*model.User, bool, error)
+	Import(context.Context, []model.User, ...stdsql.NullString) error
+	//txx:new
+	Rotate(c context.Context, d, err string) (map[string][]byte, error)
+	//txx:skip
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,229 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+var savepoints atomic.Uint64 //nolint:gochecknoglobals
+
+// DB is a *sql.DB-shaped handle routing statements through the transaction of the context, if any.
+//
+// It lets code written against a database handle participate in txx transactions without changes.
+type DB struct {
+	db *sql.DB
+}
+
+// NewDB returns a DB for given database.
+func NewDB(db *sql.DB) *DB {
+	return &DB{db: db}
+}
+
+// ExecContext executes a query without returning any rows,
+// in the current transaction if any, or on the database otherwise.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return ExecContext(ctx, db.db, query, args...)
+}
+
+// QueryContext executes a query returning rows,
+// in the current transaction if any, or on the database otherwise.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return QueryContext(ctx, db.db, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row,
+// in the current transaction if any, or on the database otherwise.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return QueryRowContext(ctx, db.db, query, args...)
+}
+
+// PrepareContext creates a prepared statement,
+// bound to the current transaction if any, or on the database otherwise.
+//
+// A statement bound to a transaction is closed when the transaction completes.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return PrepareContext(ctx, db.db, query)
+}
+
+// Querier executes statements, either in a transaction or on a database.
+//
+// It is implemented by *sql.DB, *sql.Tx, DB, Tx and Current.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// QuerierFor returns the current transaction if any, db otherwise.
+//
+// Code written against the returned Querier runs the same in and out of a transaction.
+func QuerierFor(ctx context.Context, db *sql.DB) Querier {
+	if current := Get(ctx); current.IsValid() {
+		return current
+	}
+
+	return db
+}
+
+// ExecContext executes a query without returning any rows,
+// in the current transaction if any, or on db otherwise.
+func ExecContext(ctx context.Context, db *sql.DB, query string, args ...any) (sql.Result, error) {
+	return QuerierFor(ctx, db).ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query returning rows,
+// in the current transaction if any, or on db otherwise.
+func QueryContext(ctx context.Context, db *sql.DB, query string, args ...any) (*sql.Rows, error) {
+	return QuerierFor(ctx, db).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row,
+// in the current transaction if any, or on db otherwise.
+func QueryRowContext(ctx context.Context, db *sql.DB, query string, args ...any) *sql.Row {
+	return QuerierFor(ctx, db).QueryRowContext(ctx, query, args...)
+}
+
+// PrepareContext creates a prepared statement,
+// bound to the current transaction if any, or on db otherwise.
+//
+// A statement bound to a transaction is closed when the transaction completes.
+func PrepareContext(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	return QuerierFor(ctx, db).PrepareContext(ctx, query)
+}
+
+// BeginTx starts a transaction.
+//
+// Inside a current transaction, it returns a pseudo-transaction backed by a savepoint of
+// the current one instead: committing it releases the savepoint, and rolling it back
+// only undoes the statements executed since BeginTx, given options being ignored.
+// Statements of a pseudo-transaction are executed like through the Current methods.
+// A pseudo-transaction can't be begun in a snapshot, see SnapshotPool, failing with ErrSnapshotWrite.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if current := Get(ctx); current.IsValid() {
+		if err := current.s.checkWrite(); err != nil {
+			return nil, err
+		}
+
+		var backend transaction = sqlTransaction{tx: current.Tx, strategy: TxStrategy{}}
+		if current.s != nil {
+			backend = current.s.tx
+		}
+
+		if err := current.s.flush(ctx); err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("txx_%d", savepoints.Add(1))
+
+		if err := backend.Savepoint(ctx, name); err != nil {
+			return nil, err
+		}
+
+		return &Tx{tx: current.Tx, current: current, backend: backend, mark: current.s.mark(), savepoint: name}, nil
+	}
+
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{tx: tx}, nil
+}
+
+// Tx is a transaction begun by DB.BeginTx, either a real one or a savepoint-backed pseudo-transaction.
+type Tx struct {
+	tx *sql.Tx
+	// current transaction, its backend and the callbacks registered so far, for a pseudo-transaction.
+	current   Current
+	backend   transaction
+	mark      savepointMark
+	savepoint string
+	done      atomic.Bool
+}
+
+// Commit the transaction, or release the savepoint of a pseudo-transaction.
+func (tx *Tx) Commit() error {
+	if !tx.done.CompareAndSwap(false, true) {
+		return sql.ErrTxDone
+	}
+
+	if tx.savepoint == "" {
+		return tx.tx.Commit()
+	}
+
+	ctx := context.Background()
+
+	if err := tx.current.s.flush(ctx); err != nil {
+		return err
+	}
+
+	return tx.backend.Release(ctx, tx.savepoint)
+}
+
+// Rollback the transaction, or roll back to the savepoint of a pseudo-transaction.
+func (tx *Tx) Rollback() error {
+	if !tx.done.CompareAndSwap(false, true) {
+		return sql.ErrTxDone
+	}
+
+	if tx.savepoint == "" {
+		return tx.tx.Rollback()
+	}
+
+	ctx := context.Background()
+	s := tx.current.s
+
+	s.discard()
+	s.invalidate()
+	s.rollbackTo(ctx, tx.mark, nil)
+
+	if err := tx.backend.RollbackTo(ctx, tx.savepoint); err != nil {
+		return err
+	}
+
+	return tx.backend.Release(ctx, tx.savepoint)
+}
+
+// ExecContext executes a query without returning any rows in the transaction.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx.savepoint != "" {
+		return tx.current.ExecContext(ctx, query, args...)
+	}
+
+	return tx.tx.ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query returning rows in the transaction.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx.savepoint != "" {
+		return tx.current.QueryContext(ctx, query, args...)
+	}
+
+	return tx.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row in the transaction.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if tx.savepoint != "" {
+		return tx.current.QueryRowContext(ctx, query, args...)
+	}
+
+	return tx.tx.QueryRowContext(ctx, query, args...)
+}
+
+// PrepareContext creates a prepared statement bound to the transaction.
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if tx.savepoint != "" {
+		return tx.current.PrepareContext(ctx, query)
+	}
+
+	return tx.tx.PrepareContext(ctx, query)
+}
+
+// StmtContext returns a transaction-specific prepared statement from an existing statement.
+func (tx *Tx) StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	return tx.tx.StmtContext(ctx, stmt)
+}
@@ -0,0 +1,251 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(t *testing.T, ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+},
+) []string {
+	t.Helper()
+
+	rows, err := q.QueryContext(ctx, "SELECT name FROM person ORDER BY id")
+	require.NoError(t, err)
+
+	result, err := scanAll[string](rows)
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestDB_routing(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	tdb := NewDB(db)
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := tdb.ExecContext(ctx, "INSERT INTO person (id, name) VALUES (3, 'Carol')")
+		require.NoError(t, err)
+
+		var name string
+
+		require.NoError(t, tdb.QueryRowContext(ctx, "SELECT name FROM person WHERE id = 3").Scan(&name))
+		assert.Equal(t, "Carol", name)
+		assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, ctx, tdb))
+		assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), tdb))
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), tdb))
+}
+
+func TestDB_PrepareContext(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	tdb := NewDB(db)
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		stmt, err := tdb.PrepareContext(ctx, "INSERT INTO person (name) VALUES (?)")
+		require.NoError(t, err)
+
+		_, err = stmt.ExecContext(ctx, "Carol")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, ctx, tdb))
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+
+	stmt, err := tdb.PrepareContext(context.Background(), "INSERT INTO person (name) VALUES (?)")
+	require.NoError(t, err)
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec("Dave")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Alice", "Bob", "Dave"}, names(t, context.Background(), tdb))
+}
+
+func TestDB_BeginTx(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	tdb := NewDB(db)
+	ctx := context.Background()
+
+	tx, err := tdb.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, ctx, tx))
+	require.NoError(t, tx.Rollback())
+	require.ErrorIs(t, tx.Commit(), sql.ErrTxDone)
+
+	tx, err = tdb.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	stmt, err := tdb.PrepareContext(ctx, "INSERT INTO person (name) VALUES (?)")
+	require.NoError(t, err)
+
+	defer stmt.Close()
+
+	_, err = tx.StmtContext(ctx, stmt).ExecContext(ctx, "Dave")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	require.ErrorIs(t, tx.Rollback(), sql.ErrTxDone)
+
+	assert.Equal(t, []string{"Alice", "Bob", "Dave"}, names(t, ctx, tdb))
+}
+
+func TestDB_BeginTx_savepoint(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	tdb := NewDB(db)
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := tdb.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		inner, err := tdb.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = inner.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+		require.NoError(t, err)
+
+		stmt, err := inner.PrepareContext(ctx, "INSERT INTO person (name) VALUES (?)")
+		require.NoError(t, err)
+
+		_, err = stmt.ExecContext(ctx, "Eve")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob", "Carol", "Dave", "Eve"}, names(t, ctx, inner))
+
+		require.NoError(t, inner.Rollback())
+		require.ErrorIs(t, inner.Rollback(), sql.ErrTxDone)
+
+		committed, err := tdb.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = committed.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Frank')")
+		require.NoError(t, err)
+		require.NoError(t, committed.Commit())
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol", "Frank"}, names(t, context.Background(), tdb))
+}
+
+func TestDB_BeginTx_current(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	tdb := NewDB(db)
+	m := New(db, WithMaxStatements(1))
+
+	require.ErrorIs(t, m.Wrap(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		inner, err := tdb.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = inner.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+		require.ErrorIs(t, err, ErrReadOnlyViolation)
+
+		assert.Equal(t, []string{"Alice", "Bob"}, names(t, ctx, inner))
+
+		_, err = inner.QueryContext(ctx, "SELECT 1")
+		require.ErrorIs(t, err, ErrStatementLimitExceeded, "statements should be counted")
+
+		return inner.Rollback()
+	}), ErrStatementLimitExceeded)
+}
+
+func TestDB_BeginTx_snapshot(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(itemSchema))
+	p, _ := snapshotPool(t, db)
+
+	_, err := NewDB(db).BeginTx(p.WithSnapshot(context.Background()), nil)
+	require.ErrorIs(t, err, ErrSnapshotWrite)
+}
+
+func TestExecContext(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	count := func(ctx context.Context) int {
+		var n int
+
+		require.NoError(t, QueryRowContext(ctx, db, "SELECT COUNT(*) FROM person").Scan(&n))
+
+		return n
+	}
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := ExecContext(ctx, db, "INSERT INTO person (id, name) VALUES (?, ?)", 3, "Carol")
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, count(ctx))
+		assert.Equal(t, 2, count(context.Background()), "uncommitted write should be invisible outside")
+
+		rows, err := QueryContext(ctx, db, "SELECT name FROM person WHERE id = ?", 3)
+		require.NoError(t, err)
+
+		got, err := scanAll[string](rows)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Carol"}, got)
+
+		return nil
+	}))
+
+	assert.Equal(t, 3, count(context.Background()))
+
+	_, err := ExecContext(context.Background(), db, "DELETE FROM person WHERE id = ?", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count(context.Background()))
+}
+
+func TestPrepareContext(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.Error(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		stmt, err := PrepareContext(ctx, db, "INSERT INTO person (name) VALUES (?)")
+		require.NoError(t, err)
+
+		_, err = stmt.ExecContext(ctx, "Carol")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, ctx, NewDB(db)))
+
+		return fail(ctx)
+	}))
+
+	stmt, err := PrepareContext(context.Background(), db, "INSERT INTO person (name) VALUES (?)")
+	require.NoError(t, err)
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec("Dave")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Alice", "Bob", "Dave"}, names(t, context.Background(), NewDB(db)))
+}
+
+func TestQuerierFor(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+
+	assert.Same(t, db, QuerierFor(context.Background(), db))
+	assert.Nil(t, Get(context.Background()).Executor())
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		assert.Equal(t, current, QuerierFor(ctx, db))
+		assert.Equal(t, current, current.Executor())
+
+		return nil
+	}))
+}
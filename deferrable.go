@@ -0,0 +1,36 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNotDeferrable is returned when a deferrable transaction is requested with options
+// other than serializable read-only.
+var ErrNotDeferrable = errors.New("txx: only serializable read-only transactions can be deferrable")
+
+const setDeferrable = "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE, READ ONLY, DEFERRABLE"
+
+// WithDeferrable makes transactions PostgreSQL SERIALIZABLE READ ONLY DEFERRABLE ones,
+// suited to long analytical reads as they never cause serialization failures.
+//
+// The transaction options must be SerializableReadOnly ones, otherwise ErrNotDeferrable is returned.
+func WithDeferrable() Option {
+	return func(m *Manager) {
+		m.r.setups = append(m.r.setups, setup{
+			check: checkDeferrable,
+			apply: func(ctx context.Context, tx transaction, _ *scope) error {
+				return tx.Exec(ctx, setDeferrable)
+			},
+		})
+	}
+}
+
+func checkDeferrable(opts *sql.TxOptions) error {
+	if opts == nil || !opts.ReadOnly || opts.Isolation != sql.LevelSerializable {
+		return ErrNotDeferrable
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializableReadOnly(t *testing.T) {
+	opts := SerializableReadOnly()
+
+	require.NotNil(t, opts)
+	assert.True(t, opts.ReadOnly)
+	assert.Equal(t, sql.LevelSerializable, opts.Isolation)
+}
+
+func Test_checkDeferrable(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *sql.TxOptions
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "nil",
+			opts:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:    "read-only",
+			opts:    ReadOnly(),
+			wantErr: assert.Error,
+		},
+		{
+			name:    "serializable",
+			opts:    &sql.TxOptions{Isolation: sql.LevelSerializable},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "repeatable read read-only",
+			opts:    &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "serializable read-only",
+			opts:    SerializableReadOnly(),
+			wantErr: assert.NoError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.wantErr(t, checkDeferrable(tt.opts))
+		})
+	}
+}
+
+func TestWithDeferrable(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithDeferrable())
+
+	require.NoError(t, m.Wrap(context.Background(), SerializableReadOnly(), noop))
+	assert.Equal(t, []string{"begin", setDeferrable, "commit"}, backend.calls)
+}
+
+func TestWithDeferrable_rejected(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithDeferrable())
+
+	require.ErrorIs(t, m.Wrap(context.Background(), ReadOnly(), noop), ErrNotDeferrable)
+	assert.Empty(t, backend.calls, "no transaction should be begun")
+}
+
+func TestWithDeferrable_execError(t *testing.T) {
+	errExec := errors.New("exec") //nolint:goerr113
+	backend := &fakeBackend{execErr: errExec}
+	m := newManager(backend, WithDeferrable())
+
+	require.ErrorIs(t, m.Wrap(context.Background(), SerializableReadOnly(), noop), errExec)
+	assert.Equal(t, []string{"begin", setDeferrable, "rollback"}, backend.calls)
+}
+
+func noop(_ context.Context) error {
+	return nil
+}
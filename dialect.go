@@ -0,0 +1,44 @@
+package txx
+
+import "fmt"
+
+// Dialect of the SQL database, for features depending on the engine.
+type Dialect int
+
+// Dialects.
+const (
+	// DialectGeneric means no engine-specific feature is used.
+	DialectGeneric Dialect = iota
+	// DialectPostgres is PostgreSQL.
+	DialectPostgres
+	// DialectCockroach is CockroachDB.
+	DialectCockroach
+	// DialectMySQL is MySQL or MariaDB.
+	DialectMySQL
+	// DialectSQLite is SQLite.
+	DialectSQLite
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectGeneric:
+		return "generic"
+	case DialectPostgres:
+		return "postgres"
+	case DialectCockroach:
+		return "cockroach"
+	case DialectMySQL:
+		return "mysql"
+	case DialectSQLite:
+		return "sqlite"
+	}
+
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+// WithDialect declares the dialect of the database, DialectGeneric by default.
+func WithDialect(d Dialect) Option {
+	return func(m *Manager) {
+		m.r.dialect = d
+	}
+}
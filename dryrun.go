@@ -0,0 +1,30 @@
+package txx
+
+import "context"
+
+// WithDryRun makes every transaction of the Manager a dry run, see DryRun.
+func WithDryRun() Option {
+	return func(m *Manager) {
+		m.r.dryRun = true
+	}
+}
+
+type dryRunKey struct{}
+
+// DryRun returns a copy of ctx whose transactions are rolled back instead of committed,
+// to preview what work would change.
+//
+// A successful dry run still reports success: Wrap returns nil, while its Info has DryRun set, Cause being
+// CauseDryRun, and an EventDryRun is published. Errors and panics are handled like in any other transaction.
+// Transactions reused by Ensure, and ones begun in their context, are dry runs too.
+// Functions registered by OnCommit are not called, while the ones registered by OnRollback are, with a nil cause.
+func DryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// isDryRun reports if transactions begun in given context are dry runs.
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+
+	return dryRun
+}
@@ -0,0 +1,92 @@
+package txx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insertCarol(ctx context.Context) error {
+	_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (id, name) VALUES (3, 'Carol')")
+
+	return err
+}
+
+func TestDryRun(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var info Info
+
+	m := New(db, WithFinally(func(i Info) { info = i }))
+	events, unsubscribe := m.Subscribe(4)
+
+	defer unsubscribe()
+
+	require.NoError(t, m.Wrap(DryRun(context.Background()), nil, func(ctx context.Context) error {
+		if err := insertCarol(ctx); err != nil {
+			return err
+		}
+
+		return m.Ensure(ctx, nil, func(ctx context.Context) error {
+			assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, ctx, NewDB(db)))
+
+			return Get(ctx).Commit(ctx)
+		})
+	}))
+
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(db)), "table should be unchanged")
+	assert.False(t, info.Committed)
+	assert.True(t, info.DryRun)
+	assert.Equal(t, CauseDryRun, info.Cause)
+	require.NoError(t, info.Err)
+
+	assert.Equal(t, EventBegin, (<-events).Kind)
+	assert.Equal(t, EventDryRun, (<-events).Kind)
+}
+
+func TestWithDryRun(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithDryRun())
+
+	require.NoError(t, m.Wrap(context.Background(), nil, insertCarol))
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(db)))
+
+	var info Info
+
+	m = New(db, WithDryRun(), WithFinally(func(i Info) { info = i }))
+
+	require.Error(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		if err := insertCarol(ctx); err != nil {
+			return err
+		}
+
+		return fail(ctx)
+	}))
+
+	assert.False(t, info.DryRun, "a failed dry run should be reported as a rollback")
+	assert.Equal(t, CauseError, info.Cause)
+
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("test")
+		})
+	})
+
+	assert.Equal(t, CausePanic, info.Cause)
+}
+
+func TestDryRun_callbacks(t *testing.T) {
+	var calls []string
+
+	require.NoError(t, Wrap(DryRun(context.Background()), testdb.Open(t, inMemory), nil, func(ctx context.Context) error {
+		require.NoError(t, OnCommit(ctx, record(&calls, "commit")))
+		OnRollback(ctx, recordCause(&calls, "rollback"))
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"rollback"}, calls)
+}
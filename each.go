@@ -0,0 +1,81 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// EachError is returned by WrapEach when a chunk failed, the chunks before it being committed.
+type EachError struct {
+	// Index of the item whose function failed, -1 if the chunk failed to begin or commit.
+	Index int
+	// Chunk is the index of the failed chunk, starting at 0.
+	Chunk int
+	// Committed is the number of chunks committed.
+	Committed int
+	// Processed is the number of items committed, to resume from.
+	Processed int
+	// Err is the error of the chunk.
+	Err error
+}
+
+func (e *EachError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("chunk #%d: %v", e.Chunk, e.Err)
+	}
+
+	return fmt.Sprintf("chunk #%d, item #%d: %v", e.Chunk, e.Index, e.Err)
+}
+
+func (e *EachError) Unwrap() error {
+	return e.Err
+}
+
+// WrapEach runs f for every item, in chunks of chunkSize items each run by Wrap, for batches too large
+// for one transaction. All items are run in a single chunk if chunkSize is not positive.
+//
+// Function onChunk, if not nil, is called after each chunk committed, with its index and duration, like for progress.
+// Processing stops at the first error, its chunk being rolled back: a *EachError tells the failing item
+// and how many chunks and items were committed, so that processing can resume from items[Processed:].
+func WrapEach[T any](
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	items []T,
+	chunkSize int,
+	f func(ctx context.Context, item T) error,
+	onChunk func(ctx context.Context, chunk int, d time.Duration),
+) error {
+	if chunkSize <= 0 {
+		chunkSize = len(items)
+	}
+
+	for chunk, start := 0, 0; start < len(items); chunk, start = chunk+1, start+chunkSize {
+		end := min(start+chunkSize, len(items))
+		failed := -1
+		begun := time.Now()
+
+		err := Wrap(ctx, db, opts, func(ctx context.Context) error {
+			for i := start; i < end; i++ {
+				if err := f(ctx, items[i]); err != nil {
+					failed = i
+
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return &EachError{Index: failed, Chunk: chunk, Committed: chunk, Processed: start, Err: err}
+		}
+
+		if onChunk != nil {
+			onChunk(ctx, chunk, time.Since(begun))
+		}
+	}
+
+	return nil
+}
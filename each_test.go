@@ -0,0 +1,148 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapEach(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	people := []string{"Carol", "Dave", "Eve", "Frank", "Grace"}
+
+	tests := []struct {
+		name      string
+		items     []string
+		chunkSize int
+		fail      string
+		wantErr   *EachError
+		wantNames []string
+		wantHooks []int
+	}{
+		{
+			name:      "chunks",
+			items:     people,
+			chunkSize: 2,
+			wantNames: append([]string{"Alice", "Bob"}, people...),
+			wantHooks: []int{0, 1, 2},
+		},
+		{
+			name:      "single chunk",
+			items:     people,
+			chunkSize: 0,
+			wantNames: append([]string{"Alice", "Bob"}, people...),
+			wantHooks: []int{0},
+		},
+		{
+			name:      "negative chunk size",
+			items:     people[:1],
+			chunkSize: -1,
+			wantNames: []string{"Alice", "Bob", "Carol"},
+			wantHooks: []int{0},
+		},
+		{
+			name:      "empty",
+			chunkSize: 2,
+			wantNames: []string{"Alice", "Bob"},
+		},
+		{
+			name:      "first item of later chunk",
+			items:     people,
+			chunkSize: 2,
+			fail:      "Grace",
+			wantErr:   &EachError{Index: 4, Chunk: 2, Committed: 2, Processed: 4, Err: errFailed},
+			wantNames: []string{"Alice", "Bob", "Carol", "Dave", "Eve", "Frank"},
+			wantHooks: []int{0, 1},
+		},
+		{
+			name:      "item in chunk",
+			items:     people,
+			chunkSize: 2,
+			fail:      "Frank",
+			wantErr:   &EachError{Index: 3, Chunk: 1, Committed: 1, Processed: 2, Err: errFailed},
+			wantNames: []string{"Alice", "Bob", "Carol", "Dave"},
+			wantHooks: []int{0},
+		},
+		{
+			name:      "first item",
+			items:     people,
+			chunkSize: 2,
+			fail:      "Carol",
+			wantErr:   &EachError{Index: 0, Err: errFailed},
+			wantNames: []string{"Alice", "Bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+			var hooks []int
+
+			err := WrapEach(context.Background(), db, nil, tt.items, tt.chunkSize,
+				func(ctx context.Context, name string) error {
+					if name == tt.fail {
+						return errFailed
+					}
+
+					_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", name)
+
+					return err
+				},
+				func(_ context.Context, chunk int, d time.Duration) {
+					assert.Positive(t, d)
+
+					hooks = append(hooks, chunk)
+				},
+			)
+
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				var e *EachError
+
+				require.ErrorAs(t, err, &e)
+				assert.Equal(t, tt.wantErr, e)
+				assert.ErrorIs(t, err, errFailed)
+			}
+
+			assert.Equal(t, tt.wantNames, names(t, context.Background(), db))
+			assert.Equal(t, tt.wantHooks, hooks)
+		})
+	}
+}
+
+func TestWrapEach_commitFailed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := WrapEach(ctx, db, nil, []string{"Carol", "Dave"}, 1, func(ctx context.Context, name string) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", name)
+
+		cancel()
+
+		return err
+	}, nil)
+
+	var e *EachError
+
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, -1, e.Index, "failure should not be blamed on the item")
+	assert.Equal(t, 0, e.Committed)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), db))
+}
+
+func TestEachError_Error(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+
+	assert.Equal(t, "chunk #2, item #4: failed", (&EachError{Index: 4, Chunk: 2, Err: errFailed}).Error())
+	assert.Equal(t, "chunk #2: failed", (&EachError{Index: -1, Chunk: 2, Err: errFailed}).Error())
+}
@@ -0,0 +1,143 @@
+package txx
+
+import (
+	"fmt"
+	"sync"
+)
+
+// EventKind is the kind of a transaction lifecycle Event.
+type EventKind int
+
+// Event kinds.
+const (
+	// EventBegin is published once a transaction is begun.
+	EventBegin EventKind = iota
+	// EventCommit is published once a transaction is committed.
+	EventCommit
+	// EventRollback is published once a transaction is rolled back or failed to commit.
+	EventRollback
+	// EventRetry is published once a transaction is rolled back to be retried.
+	EventRetry
+	// EventDryRun is published once a dry run succeeded, see DryRun.
+	EventDryRun
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventBegin:
+		return "begin"
+	case EventCommit:
+		return "commit"
+	case EventRollback:
+		return "rollback"
+	case EventRetry:
+		return "retry"
+	case EventDryRun:
+		return "dry_run"
+	}
+
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event of a transaction lifecycle, see Manager.Subscribe.
+type Event struct {
+	Kind EventKind
+	// Info of the transaction: only ID, Opts and Labels are set for EventBegin.
+	Info Info
+}
+
+// Overflow is the policy applied when publishing to a subscriber whose buffer is full.
+type Overflow int
+
+// Overflow policies.
+const (
+	// OverflowDrop drops the event for that subscriber, so that slow subscribers never delay transactions.
+	OverflowDrop Overflow = iota
+	// OverflowBlock waits for the subscriber to receive the event, delaying the transaction.
+	OverflowBlock
+)
+
+// WithOverflow sets the policy applied to subscribers whose buffer is full, OverflowDrop by default.
+func WithOverflow(o Overflow) Option {
+	return func(m *Manager) {
+		m.overflow = o
+	}
+}
+
+type subscriber struct {
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe returns a channel receiving the lifecycle events of every transaction of the Manager,
+// buffering up to given number of events, see WithOverflow.
+//
+// The returned function unsubscribes and closes the channel; it may be called several times.
+func (m *Manager) Subscribe(buffer int) (<-chan Event, func()) {
+	s := &subscriber{
+		events: make(chan Event, buffer),
+		done:   make(chan struct{}),
+	}
+
+	m.subsMu.Lock()
+	m.subs[s] = struct{}{}
+	m.subsMu.Unlock()
+
+	return s.events, func() {
+		s.once.Do(func() {
+			close(s.done)
+
+			m.subsMu.Lock()
+			delete(m.subs, s)
+			close(s.events)
+			m.subsMu.Unlock()
+		})
+	}
+}
+
+// publish given event to every subscriber.
+func (m *Manager) publish(kind EventKind, info Info) {
+	m.subsMu.RLock()
+	defer m.subsMu.RUnlock()
+
+	event := Event{Kind: kind, Info: info}
+
+	for s := range m.subs {
+		if m.overflow == OverflowBlock {
+			select {
+			case s.events <- event:
+			case <-s.done:
+			}
+
+			continue
+		}
+
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+}
+
+// begun publishes the begin of a transaction.
+func (m *Manager) begun(c *completion) {
+	info := Info{ID: c.id, Opts: c.opts, Labels: c.labels}
+
+	m.logBegin(info, false)
+	m.publish(EventBegin, info)
+}
+
+// completed publishes the completion of a transaction.
+func (m *Manager) completed(info Info, retrying bool) {
+	switch {
+	case info.Committed:
+		m.publish(EventCommit, info)
+	case retrying:
+		m.publish(EventRetry, info)
+	case info.DryRun:
+		m.publish(EventDryRun, info)
+	default:
+		m.publish(EventRollback, info)
+	}
+}
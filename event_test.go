@@ -0,0 +1,106 @@
+package txx
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func kinds(events <-chan Event, n int) []EventKind {
+	result := make([]EventKind, 0, n)
+
+	for i := 0; i < n; i++ {
+		result = append(result, (<-events).Kind)
+	}
+
+	return result
+}
+
+func TestManager_Subscribe(t *testing.T) {
+	m := newManager(&fakeBackend{execs: []error{driver.ErrBadConn}}, WithReplay(Replay{}))
+	first, cancelFirst := m.Subscribe(10)
+	second, cancelSecond := m.Subscribe(10)
+
+	defer cancelSecond()
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+	require.NoError(t, m.r.attempts(context.Background(), nil, func(ctx context.Context, tx transaction) error {
+		return tx.Exec(ctx, "SELECT 1")
+	}))
+
+	want := []EventKind{EventBegin, EventCommit, EventBegin, EventRollback, EventBegin, EventRetry, EventBegin, EventCommit}
+
+	assert.Equal(t, want, kinds(first, len(want)))
+	assert.Equal(t, want, kinds(second, len(want)))
+
+	cancelFirst()
+	cancelFirst()
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+
+	_, open := <-first
+	assert.False(t, open, "unsubscribed channel should be closed")
+	assert.Equal(t, []EventKind{EventBegin, EventCommit}, kinds(second, 2))
+}
+
+func TestManager_Subscribe_info(t *testing.T) {
+	m := newManager(&fakeBackend{})
+	events, cancel := m.Subscribe(2)
+
+	defer cancel()
+
+	require.NoError(t, m.Wrap(context.Background(), ReadOnly(), noop))
+
+	begin, commit := <-events, <-events
+
+	assert.Equal(t, begin.Info.ID, commit.Info.ID)
+	assert.Equal(t, ReadOnly(), begin.Info.Opts)
+	assert.False(t, begin.Info.Committed)
+	assert.True(t, commit.Info.Committed)
+}
+
+func TestWithOverflow_drop(t *testing.T) {
+	m := newManager(&fakeBackend{})
+	events, cancel := m.Subscribe(1)
+
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	}
+
+	assert.Len(t, events, 1, "events should be dropped once the buffer is full")
+}
+
+func TestWithOverflow_block(t *testing.T) {
+	m := newManager(&fakeBackend{}, WithOverflow(OverflowBlock))
+	events, cancel := m.Subscribe(0)
+	result := make(chan error, 1)
+
+	go func() {
+		result <- m.Wrap(context.Background(), nil, noop)
+	}()
+
+	assert.Equal(t, EventBegin, (<-events).Kind)
+
+	select {
+	case <-result:
+		t.Fatal("transaction should wait for the subscriber")
+	default:
+	}
+
+	assert.Equal(t, EventCommit, (<-events).Kind)
+	require.NoError(t, <-result)
+
+	go func() {
+		result <- m.Wrap(context.Background(), nil, noop)
+	}()
+
+	cancel()
+
+	require.NoError(t, <-result, "unsubscribing should release blocked transactions")
+}
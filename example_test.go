@@ -0,0 +1,72 @@
+package txx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/MartyHub/txx"
+	_ "modernc.org/sqlite"
+)
+
+// accounts is a repository written once against txx.Querier.
+type accounts struct {
+	db *sql.DB
+}
+
+func (a accounts) open(ctx context.Context, owner string) error {
+	_, err := txx.QuerierFor(ctx, a.db).ExecContext(ctx, "INSERT INTO account (owner) VALUES (?)", owner)
+
+	return err
+}
+
+func (a accounts) count(ctx context.Context) (int, error) {
+	var n int
+
+	err := txx.QuerierFor(ctx, a.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM account").Scan(&n)
+
+	return n, err
+}
+
+func ExampleQuerierFor() {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		panic(err)
+	}
+
+	defer db.Close()
+
+	db.SetMaxOpenConns(1) // a single in-memory database
+
+	if _, err = db.Exec("CREATE TABLE account (owner TEXT NOT NULL)"); err != nil {
+		panic(err)
+	}
+
+	repo := accounts{db: db}
+	ctx := context.Background()
+
+	// without transaction
+	if err = repo.open(ctx, "alice"); err != nil {
+		panic(err)
+	}
+
+	// in a rolled back transaction
+	_ = txx.Wrap(ctx, db, nil, func(ctx context.Context) error {
+		if err := repo.open(ctx, "bob"); err != nil {
+			return err
+		}
+
+		n, err := repo.count(ctx)
+		fmt.Println("in transaction:", n, err)
+
+		return errors.New("changed my mind") //nolint:goerr113
+	})
+
+	n, err := repo.count(ctx)
+	fmt.Println("after rollback:", n, err)
+
+	// Output:
+	// in transaction: 2 <nil>
+	// after rollback: 1 <nil>
+}
@@ -0,0 +1,151 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EXPLAIN statement prefixes.
+const (
+	// ExplainPostgres explains a PostgreSQL statement without running it.
+	ExplainPostgres = "EXPLAIN (FORMAT JSON) "
+	// ExplainSQLite explains a SQLite statement without running it.
+	ExplainSQLite = "EXPLAIN QUERY PLAN "
+)
+
+const (
+	defaultExplainTimeout = time.Second
+	explainSavepoint      = "txx_explain"
+)
+
+// Explain configures the capture of the plans of slow statements.
+type Explain struct {
+	// Threshold is the duration above which a statement is explained, every statement if zero.
+	Threshold time.Duration
+	// Timeout bounds the EXPLAIN statement, 1 second if not positive.
+	Timeout time.Duration
+	// Prefix is prepended to the statement to explain it, ExplainPostgres if empty.
+	//
+	// It must not make the database run the statement, like EXPLAIN ANALYZE does,
+	// otherwise data-modifying statements would be run twice.
+	Prefix string
+	// Handle is called with every captured plan.
+	Handle func(ctx context.Context, plan Plan)
+}
+
+// Plan of a slow statement, see WithExplain.
+type Plan struct {
+	// Query is the SQL text of the statement, without its arguments.
+	Query string
+	// Duration of the statement.
+	Duration time.Duration
+	// Text of the plan: one line per row returned by the EXPLAIN statement, columns separated by tabs.
+	Text string
+}
+
+// WithExplain captures the plan of statements executed through the Current methods taking longer than
+// the threshold, by running an EXPLAIN of the statement in the same transaction.
+//
+// Failed statements are not explained. An EXPLAIN failure is ignored: the statement result is
+// returned as is and no plan is handled. The EXPLAIN runs in a savepoint, rolled back on failure,
+// so that it doesn't abort the transaction on databases like PostgreSQL. Since a query is explained while its rows are open,
+// drivers not supporting concurrent statements on a connection can't explain queries.
+func WithExplain(cfg Explain) Option {
+	return func(m *Manager) {
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = defaultExplainTimeout
+		}
+
+		if cfg.Prefix == "" {
+			cfg.Prefix = ExplainPostgres
+		}
+
+		m.r.explain = &cfg
+	}
+}
+
+// explainSlow explains given statement started at given time if it took longer than the threshold.
+func (s *scope) explainSlow(ctx context.Context, tx *sql.Tx, query string, args []any, start time.Time) {
+	if s == nil || s.explain == nil {
+		return
+	}
+
+	d := s.now().Sub(start)
+	if d < s.explain.Threshold {
+		return
+	}
+
+	// a failed statement aborts a PostgreSQL transaction: the EXPLAIN is protected by a savepoint
+	var backend transaction = sqlTransaction{tx: tx, strategy: TxStrategy{}}
+	if s.tx != nil {
+		backend = s.tx
+	}
+
+	sctx := context.WithoutCancel(ctx)
+	if err := backend.Savepoint(sctx, explainSavepoint); err != nil {
+		return
+	}
+
+	ectx, cancel := context.WithTimeout(sctx, s.explain.Timeout)
+	defer cancel()
+
+	text, err := explain(ectx, tx, s.explain.Prefix+query, args)
+	if err != nil {
+		_ = backend.RollbackTo(sctx, explainSavepoint)
+	}
+
+	_ = backend.Release(sctx, explainSavepoint)
+
+	if err == nil {
+		s.explain.Handle(ctx, Plan{Query: query, Duration: d, Text: text})
+	}
+}
+
+func explain(ctx context.Context, tx *sql.Tx, query string, args []any) (string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var lines []string
+
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		fields := make([]string, len(values))
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+
+			fields[i] = fmt.Sprint(value)
+		}
+
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
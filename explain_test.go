@@ -0,0 +1,131 @@
+package txx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func explaining(t *testing.T, cfg Explain) (*Manager, *[]Plan) {
+	t.Helper()
+
+	var plans []Plan
+
+	cfg.Handle = func(_ context.Context, plan Plan) {
+		plans = append(plans, plan)
+	}
+
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	_, err := db.Exec("CREATE TABLE item (id INTEGER)")
+	require.NoError(t, err)
+
+	return New(db, WithExplain(cfg)), &plans
+}
+
+func TestWithExplain(t *testing.T) {
+	m, plans := explaining(t, Explain{Prefix: ExplainSQLite})
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		_, err := current.ExecContext(ctx, "INSERT INTO item (id) VALUES (?)", 1)
+		require.NoError(t, err)
+
+		rows, err := current.QueryContext(ctx, "SELECT id FROM item WHERE id > ?", 0)
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+
+		var n int
+
+		require.NoError(t, current.QueryRowContext(ctx, "SELECT COUNT(*) FROM item").Scan(&n))
+		assert.Equal(t, 1, n, "explained statements should not be run again")
+
+		_, err = current.ExecContext(ctx, "INSERT INTO unknown (id) VALUES (1)")
+		require.Error(t, err)
+
+		return nil
+	}))
+
+	require.Len(t, *plans, 3)
+	assert.Equal(t, "INSERT INTO item (id) VALUES (?)", (*plans)[0].Query)
+	assert.Equal(t, "SELECT id FROM item WHERE id > ?", (*plans)[1].Query)
+	assert.Contains(t, (*plans)[1].Text, "SCAN item")
+	assert.Equal(t, "SELECT COUNT(*) FROM item", (*plans)[2].Query)
+}
+
+func TestWithExplain_threshold(t *testing.T) {
+	m, plans := explaining(t, Explain{Threshold: time.Hour, Prefix: ExplainSQLite})
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO item (id) VALUES (1)")
+
+		return err
+	}))
+
+	assert.Empty(t, *plans)
+}
+
+func TestWithExplain_clock(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+
+	var plans []Plan
+
+	db := fakedb.Open(t, &fakedb.Driver{
+		Exec: func(_ context.Context, query string, _ []any) (fakedb.Result, error) {
+			if strings.HasPrefix(query, "INSERT") {
+				c.Advance(time.Minute)
+			}
+
+			return fakedb.Result{}, nil
+		},
+	})
+	m := New(db, WithClock(c), WithExplain(Explain{
+		Threshold: time.Second,
+		Handle: func(_ context.Context, plan Plan) {
+			plans = append(plans, plan)
+		},
+	}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "UPDATE item SET id = 2")
+		require.NoError(t, err)
+
+		_, err = Get(ctx).ExecContext(ctx, "INSERT INTO item (id) VALUES (1)")
+
+		return err
+	}))
+
+	require.Len(t, plans, 1, "statements should be measured by the clock of the Manager")
+	assert.Equal(t, "INSERT INTO item (id) VALUES (1)", plans[0].Query)
+	assert.Equal(t, time.Minute, plans[0].Duration)
+}
+
+func TestWithExplain_failure(t *testing.T) {
+	m, plans := explaining(t, Explain{})
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO item (id) VALUES (1)")
+		require.NoError(t, err)
+
+		_, err = Get(ctx).Tx.ExecContext(ctx, "RELEASE SAVEPOINT "+explainSavepoint)
+		require.Error(t, err, "savepoint of the EXPLAIN should be released")
+
+		var n int
+
+		require.NoError(t, Get(ctx).Tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM item").Scan(&n))
+		assert.Equal(t, 1, n, "rolling back the EXPLAIN should keep the statement")
+
+		return nil
+	}), "EXPLAIN (FORMAT JSON) is not supported by SQLite and should be ignored")
+
+	assert.Empty(t, *plans)
+}
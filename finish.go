@@ -0,0 +1,166 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadyFinished is returned when a transaction already committed or rolled back is completed again.
+//
+// It wraps ErrTransactionFinished.
+var ErrAlreadyFinished = fmt.Errorf("txx: already finished: %w", ErrTransactionFinished)
+
+// Errors wrapping the driver errors of the transaction lifecycle, telling them apart from function errors,
+// which are returned as is.
+var (
+	// ErrBeginFailed wraps the error of beginning a transaction.
+	ErrBeginFailed = errors.New("txx: begin failed")
+	// ErrCommitFailed wraps the error of committing a transaction.
+	ErrCommitFailed = errors.New("txx: commit failed")
+	// ErrRollbackFailed wraps the error of rolling back a transaction, joined to the error causing the rollback.
+	ErrRollbackFailed = errors.New("txx: rollback failed")
+)
+
+// ErrManagedTransaction is returned by Wrap when the transaction it manages was committed or rolled back
+// directly with Tx.Commit or Tx.Rollback, instead of Commit or Rollback, so that it could not commit it.
+var ErrManagedTransaction = errors.New("txx: transaction finished outside of txx")
+
+// ending is how a transaction was ended, see scope.end.
+type ending struct {
+	committed  bool
+	rolledBack bool
+	// dryRun reports if the commit was replaced by a rollback, see DryRun.
+	dryRun bool
+	err    error
+}
+
+// Commit the transaction before the function run by Wrap returns, cleanups like temporary tables
+// being dropped first.
+//
+// The transaction is then finished: IsValid reports false and Wrap doesn't complete it again.
+// A commit error is also returned by Wrap, unless the function returns another error.
+// Commit must be preferred to calling Tx.Commit directly.
+//
+// It returns ErrAlreadyFinished if the transaction is already finished, ErrNoTransaction if it was not
+// begun by txx, like with Set, and ErrSnapshotWrite if it is shared by a SnapshotPool.
+func (c Current) Commit(ctx context.Context) error {
+	return c.s.finish(ctx, true)
+}
+
+// Rollback the transaction before the function run by Wrap returns, see Commit.
+func (c Current) Rollback(ctx context.Context) error {
+	return c.s.finish(ctx, false)
+}
+
+func (s *scope) finish(ctx context.Context, commit bool) error {
+	if s == nil {
+		return ErrNoTransaction
+	}
+
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+
+	return s.end(ctx, commit)
+}
+
+// end the transaction once, committing it if commit and cleanups succeed, rolling it back otherwise.
+func (s *scope) end(ctx context.Context, commit bool) error {
+	s.mu.Lock()
+	if s.finishing || s.closed {
+		s.mu.Unlock()
+
+		return ErrAlreadyFinished
+	}
+
+	s.finishing = true
+	s.mu.Unlock()
+
+	var (
+		e   ending
+		err error
+	)
+
+	if commit {
+		err = s.getRollbackOnly()
+	}
+
+	if commit && err == nil {
+		err = s.flush(ctx)
+	} else {
+		s.discard()
+	}
+
+	if cleanupErr := s.cleanup(ctx); err == nil {
+		err = cleanupErr
+	}
+
+	switch {
+	case commit && (errors.Is(err, ErrRollback) || s.quiet && errors.Is(err, ErrRollbackOnly)):
+		// requested by the function, a success
+		e.rolledBack, e.err = true, joinRollback(nil, s.tx.Rollback(ctx))
+	case err != nil || !commit:
+		e.rolledBack, e.err = true, joinRollback(err, s.tx.Rollback(ctx))
+	case s.dryRun:
+		e.rolledBack, e.err = true, joinRollback(nil, s.tx.Rollback(ctx))
+		e.dryRun = e.err == nil
+	default:
+		if err = s.tx.Commit(ctx); errors.Is(err, sql.ErrTxDone) && ctx.Err() == nil {
+			// not aborted by database/sql due to ctx
+			err = fmt.Errorf("%w: %w: %w", ErrCommitFailed, ErrManagedTransaction, err)
+		} else if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCommitFailed, err)
+		}
+
+		e.err = s.wrap(err)
+		e.committed = e.err == nil
+	}
+
+	s.mu.Lock()
+	s.ending = &e
+	s.mu.Unlock()
+
+	return e.err
+}
+
+// joinRollback joins the error of a rollback to given error, if any.
+//
+// sql.ErrTxDone is ignored: the transaction was already aborted, like by the driver.
+func joinRollback(err, rollbackErr error) error {
+	if rollbackErr == nil || errors.Is(rollbackErr, sql.ErrTxDone) {
+		return err
+	}
+
+	return errors.Join(err, fmt.Errorf("%w: %w", ErrRollbackFailed, rollbackErr))
+}
+
+// isFinishing reports if the transaction is being ended or was, see end,
+// or if a statement reported it done, like once committed directly with Tx.Commit.
+func (s *scope) isFinishing() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.finishing || s.txDone
+}
+
+// ended returns how the transaction was ended, if it was.
+func (s *scope) ended() (ending, bool) {
+	if s == nil {
+		return ending{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ending == nil {
+		return ending{}, false
+	}
+
+	return *s.ending, true
+}
@@ -0,0 +1,135 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrent_Commit(t *testing.T) {
+	backend := &fakeBackend{}
+
+	var infos []Info
+
+	m := newManager(backend, WithFinally(func(info Info) { infos = append(infos, info) }))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		require.NoError(t, current.Commit(ctx))
+		require.ErrorIs(t, current.Commit(ctx), ErrAlreadyFinished)
+		require.ErrorIs(t, current.Rollback(ctx), ErrAlreadyFinished)
+		assert.False(t, IsInTx(ctx))
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"begin", "commit"}, backend.calls)
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Committed)
+}
+
+func TestCurrent_Commit_thenError(t *testing.T) {
+	backend := &fakeBackend{}
+
+	var infos []Info
+
+	m := newManager(backend, WithFinally(func(info Info) { infos = append(infos, info) }))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, Get(ctx).Commit(ctx))
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"begin", "commit"}, backend.calls, "committed work can't be rolled back")
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Committed)
+}
+
+func TestCurrent_Rollback(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var infos []Info
+
+	m := New(db, WithFinally(func(info Info) { infos = append(infos, info) }))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		_, err := current.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		require.NoError(t, current.Rollback(ctx))
+		require.ErrorIs(t, current.Commit(ctx), ErrAlreadyFinished)
+
+		return nil
+	}))
+
+	assert.Equal(t, 2, countPeople(t, db))
+	require.Len(t, infos, 1)
+	assert.False(t, infos[0].Committed)
+	assert.Equal(t, CauseRollbackOnly, infos[0].Cause)
+}
+
+func TestCurrent_Commit_cleanups(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var name string
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		if err := stage(&name)(ctx); err != nil {
+			return err
+		}
+
+		current := Get(ctx)
+
+		_, err := current.ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		return current.Commit(ctx)
+	}))
+
+	assert.Equal(t, 3, countPeople(t, db))
+}
+
+func TestCurrent_Commit_noTransaction(t *testing.T) {
+	ctx := Set(context.Background(), &sql.Tx{}, nil)
+
+	require.ErrorIs(t, Get(ctx).Commit(ctx), ErrNoTransaction)
+	require.ErrorIs(t, Get(context.Background()).Rollback(ctx), ErrNoTransaction)
+}
+
+func TestWrap_committedDirectly(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		// a callee breaking the rules
+		require.NoError(t, Get(ctx).Tx.Commit())
+
+		_, err = Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Eve')")
+		require.ErrorIs(t, err, ErrTxFinished)
+		assert.False(t, Get(ctx).IsValid(), "the statement should have reported the transaction done")
+
+		return Ensure(ctx, db, nil, func(ctx context.Context) error {
+			assert.True(t, Get(ctx).IsValid(), "a new transaction should be begun")
+
+			_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+
+			return err
+		})
+	})
+
+	require.ErrorIs(t, err, ErrManagedTransaction)
+	require.ErrorIs(t, err, ErrCommitFailed)
+	require.ErrorIs(t, err, sql.ErrTxDone)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol", "Dave"}, names(t, context.Background(), db))
+}
@@ -0,0 +1,65 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrIncompatibleTransaction is matched by every IncompatibleTransactionError, see WithStrictReuse.
+var ErrIncompatibleTransaction = errors.New("txx: incompatible transaction")
+
+// IncompatibleTransactionError is returned with WithStrictReuse when Ensure would begin a second transaction,
+// the current one not matching the requested options.
+type IncompatibleTransactionError struct {
+	// Current are the options of the current transaction.
+	Current *sql.TxOptions
+	// Requested are the options requested, merged with WithDefaultOptions.
+	Requested *sql.TxOptions
+}
+
+func (e *IncompatibleTransactionError) Error() string {
+	return fmt.Sprintf(
+		"txx: incompatible transaction: current %s, requested %s",
+		describeOptions(e.Current), describeOptions(e.Requested),
+	)
+}
+
+// Is reports if target is ErrIncompatibleTransaction.
+func (e *IncompatibleTransactionError) Is(target error) bool {
+	return target == ErrIncompatibleTransaction //nolint:errorlint
+}
+
+// describeOptions returns the isolation level and access mode of given options.
+func describeOptions(opts *sql.TxOptions) string {
+	o := normalize(opts, sql.LevelDefault)
+	if o.ReadOnly {
+		return o.Isolation.String() + " read-only"
+	}
+
+	return o.Isolation.String() + " read-write"
+}
+
+// WithStrictReuse makes Ensure and the like return an *IncompatibleTransactionError when the current transaction
+// doesn't match the requested options, instead of beginning a second, independent transaction while it is open,
+// which deadlocks on SQLite and escapes the isolation of the first one elsewhere. It is meant for development,
+// to find such calls: by default, the second transaction is begun.
+//
+// See WithNestedWrapPolicy for Wrap called in a transaction.
+func WithStrictReuse() Option {
+	return func(m *Manager) {
+		m.strictReuse = true
+	}
+}
+
+// checkIncompatible returns an *IncompatibleTransactionError with WithStrictReuse if a transaction is current,
+// a new one being required to match given options.
+func (m *Manager) checkIncompatible(ctx context.Context, opts *sql.TxOptions) error {
+	current := Get(ctx)
+	if !m.strictReuse || !current.IsValid() {
+		return nil
+	}
+
+	return &IncompatibleTransactionError{Current: current.Opts, Requested: opts}
+}
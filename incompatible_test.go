@@ -0,0 +1,90 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStrictReuse(t *testing.T) {
+	tests := []struct {
+		name      string
+		outer     *sql.TxOptions
+		requested *sql.TxOptions
+		want      string
+	}{
+		{
+			name:      "read-only in read-write",
+			requested: ReadOnly(),
+			want:      "txx: incompatible transaction: current Default read-write, requested Default read-only",
+		},
+		{
+			name:      "read-write in read-only",
+			outer:     ReadOnly(),
+			requested: &sql.TxOptions{},
+			want:      "txx: incompatible transaction: current Default read-only, requested Default read-write",
+		},
+		{
+			name:      "isolation increase",
+			requested: Serializable(),
+			want:      "txx: incompatible transaction: current Default read-write, requested Serializable read-write",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, inMemory)
+			m := New(db, WithStrictReuse())
+			begun := 0
+
+			m.Use(func(next RunFunc) RunFunc {
+				return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+					begun++
+
+					return next(ctx, db, opts, f)
+				}
+			})
+
+			require.NoError(t, m.Wrap(context.Background(), tt.outer, func(ctx context.Context) error {
+				errs := []error{
+					m.Ensure(ctx, tt.requested, noop),
+					m.Nested(ctx, tt.requested, noop),
+				}
+
+				for _, err := range errs {
+					require.ErrorIs(t, err, ErrIncompatibleTransaction)
+
+					var e *IncompatibleTransactionError
+
+					require.ErrorAs(t, err, &e)
+					assert.Equal(t, tt.outer, e.Current)
+					assert.Equal(t, tt.requested, e.Requested)
+					assert.EqualError(t, err, tt.want)
+				}
+
+				return m.Ensure(ctx, tt.outer, noop)
+			}))
+
+			assert.Equal(t, 1, begun, "no second transaction should be begun")
+		})
+	}
+}
+
+func TestWithStrictReuse_default(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	m := New(db)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		outer := Get(ctx).ID()
+
+		return m.Ensure(ctx, ReadOnly(), func(ctx context.Context) error {
+			assert.NotEqual(t, outer, Get(ctx).ID(), "a second transaction should be begun")
+
+			return nil
+		})
+	}))
+}
@@ -0,0 +1,158 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RollbackCause categorizes why a transaction was rolled back.
+//
+// Its String value is suitable as a metrics label.
+type RollbackCause int
+
+// Rollback causes.
+const (
+	// CauseNone means the transaction was not rolled back.
+	CauseNone RollbackCause = iota
+	// CauseError means the callback returned an error.
+	CauseError
+	// CausePanic means the callback panicked.
+	CausePanic
+	// CauseCanceled means the context was canceled.
+	CauseCanceled
+	// CauseDeadlineExceeded means the context deadline was exceeded.
+	CauseDeadlineExceeded
+	// CauseRollbackOnly means the transaction was marked rollback-only.
+	CauseRollbackOnly
+	// CauseRetry means the transaction was rolled back to be retried.
+	CauseRetry
+	// CauseForced means the transaction was forcibly rolled back by its lifetime or a shutdown.
+	CauseForced
+	// CauseDryRun means the transaction succeeded but was rolled back as a dry run, see DryRun.
+	CauseDryRun
+)
+
+func (c RollbackCause) String() string {
+	switch c {
+	case CauseNone:
+		return "none"
+	case CauseError:
+		return "error"
+	case CausePanic:
+		return "panic"
+	case CauseCanceled:
+		return "canceled"
+	case CauseDeadlineExceeded:
+		return "deadline_exceeded"
+	case CauseRollbackOnly:
+		return "rollback_only"
+	case CauseRetry:
+		return "retry"
+	case CauseForced:
+		return "forced"
+	case CauseDryRun:
+		return "dry_run"
+	}
+
+	return fmt.Sprintf("RollbackCause(%d)", int(c))
+}
+
+// Info describes a completed transaction.
+type Info struct {
+	// ID identifies the transaction, unique within the process.
+	ID   uint64
+	Opts *sql.TxOptions
+	// Committed reports if the transaction was committed successfully.
+	Committed bool
+	// Cause of the rollback, CauseNone if the transaction was not rolled back.
+	Cause RollbackCause
+	// Err is the error which caused the rollback or the commit error.
+	Err error
+	// Schema set as search path by WithSchema, if any.
+	Schema string
+	// AsOfSystemTime is the expression set by WithAsOfSystemTime, if any.
+	AsOfSystemTime string
+	// Labels extracted by WithLabelsFromContext, if any.
+	Labels map[string]string
+	// Duration of the transaction, from begin to its commit or rollback.
+	Duration time.Duration
+	// NonTransactional reports if the work ran without transaction, see NoTxStrategy.
+	NonTransactional bool
+	// StatementLimitExceeded reports if the transaction was rolled back for exceeding WithMaxStatements.
+	StatementLimitExceeded bool
+	// DryRun reports if the transaction succeeded but was rolled back as a dry run, see DryRun.
+	DryRun bool
+	// CallbackErr joins the panics of the callbacks registered by OnCommit and OnRollback, if any.
+	CallbackErr error
+}
+
+// completion gathers what happened to a transaction, see rollbackCause.
+type completion struct {
+	id           uint64
+	tx           transaction
+	opts         *sql.TxOptions
+	err          error
+	panicked     bool
+	committed    bool
+	rolledBack   bool
+	rollbackOnly bool
+	retrying     bool
+	forced       bool
+	dryRun       bool
+	schema       string
+	asOf         string
+	labels       map[string]string
+	duration     time.Duration
+	// statementLimitExceeded reports if the transaction exceeded WithMaxStatements.
+	statementLimitExceeded bool
+	callbackErr            error
+}
+
+// rollbackCause returns the category of the rollback described by c.
+//
+// This is the single place deciding the category so every consumer agrees.
+func rollbackCause(c completion) RollbackCause {
+	switch {
+	case !c.rolledBack:
+		return CauseNone
+	case c.forced || errors.Is(c.err, ErrShuttingDown):
+		return CauseForced
+	case c.dryRun:
+		return CauseDryRun
+	case c.panicked:
+		return CausePanic
+	case c.retrying:
+		return CauseRetry
+	case errors.Is(c.err, context.Canceled):
+		return CauseCanceled
+	case errors.Is(c.err, context.DeadlineExceeded):
+		return CauseDeadlineExceeded
+	case c.rollbackOnly || c.err == nil:
+		return CauseRollbackOnly
+	}
+
+	return CauseError
+}
+
+func (c completion) info() Info {
+	_, nonTransactional := c.tx.(*noTransaction)
+
+	return Info{
+		ID:                     c.id,
+		Opts:                   c.opts,
+		Committed:              c.committed,
+		Cause:                  rollbackCause(c),
+		Err:                    c.err,
+		Schema:                 c.schema,
+		AsOfSystemTime:         c.asOf,
+		Labels:                 c.labels,
+		Duration:               c.duration,
+		NonTransactional:       nonTransactional,
+		StatementLimitExceeded: c.statementLimitExceeded,
+		DryRun:                 c.dryRun,
+		CallbackErr:            c.callbackErr,
+	}
+}
@@ -0,0 +1,165 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackCause_String(t *testing.T) {
+	tests := []struct {
+		cause RollbackCause
+		want  string
+	}{
+		{cause: CauseNone, want: "none"},
+		{cause: CauseError, want: "error"},
+		{cause: CausePanic, want: "panic"},
+		{cause: CauseCanceled, want: "canceled"},
+		{cause: CauseDeadlineExceeded, want: "deadline_exceeded"},
+		{cause: CauseRollbackOnly, want: "rollback_only"},
+		{cause: CauseRetry, want: "retry"},
+		{cause: CauseForced, want: "forced"},
+		{cause: CauseDryRun, want: "dry_run"},
+		{cause: RollbackCause(42), want: "RollbackCause(42)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cause.String())
+		})
+	}
+}
+
+func Test_rollbackCause(t *testing.T) {
+	errTest := errors.New("test") //nolint:goerr113
+
+	tests := []struct {
+		name string
+		c    completion
+		want RollbackCause
+	}{
+		{
+			name: "committed",
+			c:    completion{committed: true},
+			want: CauseNone,
+		},
+		{
+			name: "commit error",
+			c:    completion{err: errTest},
+			want: CauseNone,
+		},
+		{
+			name: "error",
+			c:    completion{rolledBack: true, err: errTest},
+			want: CauseError,
+		},
+		{
+			name: "panic",
+			c:    completion{rolledBack: true, panicked: true, err: errTest},
+			want: CausePanic,
+		},
+		{
+			name: "canceled",
+			c:    completion{rolledBack: true, err: fmt.Errorf("query: %w", context.Canceled)},
+			want: CauseCanceled,
+		},
+		{
+			name: "deadline exceeded",
+			c:    completion{rolledBack: true, err: context.DeadlineExceeded},
+			want: CauseDeadlineExceeded,
+		},
+		{
+			name: "rollback-only",
+			c:    completion{rolledBack: true, rollbackOnly: true},
+			want: CauseRollbackOnly,
+		},
+		{
+			name: "retry",
+			c:    completion{rolledBack: true, retrying: true, err: context.Canceled},
+			want: CauseRetry,
+		},
+		{
+			name: "forced",
+			c:    completion{rolledBack: true, forced: true, err: errTest},
+			want: CauseForced,
+		},
+		{
+			name: "shutting down",
+			c:    completion{rolledBack: true, err: ErrShuttingDown},
+			want: CauseForced,
+		},
+		{
+			name: "dry run",
+			c:    completion{rolledBack: true, dryRun: true},
+			want: CauseDryRun,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rollbackCause(tt.c))
+		})
+	}
+}
+
+func TestManager_rollbackCauses(t *testing.T) {
+	var infos []Info
+
+	m := New(testdb.Open(t, inMemory), WithFinally(func(info Info) {
+		infos = append(infos, info)
+	}))
+
+	canceled, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+	require.Error(t, m.Wrap(canceled, nil, func(_ context.Context) error {
+		cancel()
+
+		return canceled.Err()
+	}))
+	require.Error(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return fmt.Errorf("query: %w", context.DeadlineExceeded)
+	}))
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("test")
+		})
+	})
+
+	gate, result := gated(t, m)
+	expired, expire := context.WithCancel(context.Background())
+	expire()
+
+	require.Error(t, m.Shutdown(expired))
+	close(gate)
+	require.Error(t, <-result)
+
+	causes := make([]RollbackCause, 0, len(infos))
+	for _, info := range infos {
+		causes = append(causes, info.Cause)
+	}
+
+	assert.Equal(
+		t,
+		[]RollbackCause{CauseNone, CauseError, CauseCanceled, CauseDeadlineExceeded, CausePanic, CauseForced},
+		causes,
+	)
+	assert.True(t, infos[0].Committed)
+
+	stats := m.Stats()
+
+	assert.Equal(t, uint64(1), stats.Committed)
+	assert.Equal(t, map[RollbackCause]uint64{
+		CauseError:            1,
+		CauseCanceled:         1,
+		CauseDeadlineExceeded: 1,
+		CausePanic:            1,
+		CauseForced:           1,
+	}, stats.RolledBack)
+}
@@ -0,0 +1,88 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RunFunc runs function f in a new transaction with given options on given database.
+type RunFunc func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error
+
+// Interceptor decorates the RunFunc of a Manager, to add behavior around every transaction begun by Wrap.
+//
+// An interceptor may change the context, options or function passed to next,
+// short-circuit by not calling next, and observe or replace the error returned by next,
+// which is the final outcome of the transaction.
+type Interceptor func(next RunFunc) RunFunc
+
+// Use adds interceptors around the transactions of the Manager.
+//
+// Interceptors run in the order given, the first one being the outermost,
+// after the ones added by previous calls. The db passed to next is the database of the Manager,
+// substituting another one has no effect.
+func (m *Manager) Use(interceptors ...Interceptor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.interceptors = append(m.interceptors, interceptors...)
+	m.run = m.core
+
+	for i := len(m.interceptors) - 1; i >= 0; i-- {
+		m.run = m.interceptors[i](m.run)
+	}
+}
+
+// UseOnReuse adds interceptors around the functions run by Ensure in a reused transaction,
+// like Use for new transactions.
+//
+// The context passed to them carries the reused transaction, and the error they return is the one of
+// the function only, the transaction completing later.
+func (m *Manager) UseOnReuse(interceptors ...Interceptor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reuseInterceptors = append(m.reuseInterceptors, interceptors...)
+	m.reuse = m.reused
+
+	for i := len(m.reuseInterceptors) - 1; i >= 0; i-- {
+		m.reuse = m.reuseInterceptors[i](m.reuse)
+	}
+}
+
+// gate is the built-in Interceptor rejecting transactions once Shutdown has been called.
+func (m *Manager) gate(next RunFunc) RunFunc {
+	return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+		if err := m.acquire(); err != nil {
+			return err
+		}
+
+		defer m.release()
+
+		return next(ctx, db, opts, f)
+	}
+}
+
+// core is the RunFunc beginning and completing transactions.
+func (m *Manager) core(ctx context.Context, _ *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	return m.r.attempts(ctx, opts, func(ctx context.Context, tx transaction) error {
+		cancel, _ := ctx.Value(rollbackCauseKey{}).(context.CancelCauseFunc)
+
+		if err := m.register(Get(ctx).ID(), tx, cancel); err != nil {
+			return err
+		}
+
+		return f(ctx)
+	})
+}
+
+// reused is the RunFunc running functions in a reused transaction.
+func (m *Manager) reused(ctx context.Context, _ *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	current := Get(ctx)
+	labels := current.s.getLabels()
+
+	m.logBegin(Info{ID: current.ID(), Opts: opts, Labels: labels}, true)
+
+	return runReused(m.r.metrics, m.clock, opts, labels, func() error {
+		return f(ctx)
+	})
+}
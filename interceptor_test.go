@@ -0,0 +1,127 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recording(calls *[]string, name string) Interceptor {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+			*calls = append(*calls, name+" before")
+			err := next(ctx, db, opts, f)
+			*calls = append(*calls, name+" after")
+
+			return err
+		}
+	}
+}
+
+func TestManager_Use(t *testing.T) {
+	b := &fakeBackend{}
+	m := newManager(b)
+
+	var calls []string
+
+	m.Use(recording(&calls, "a"), recording(&calls, "b"))
+	m.Use(recording(&calls, "c"))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		calls = append(calls, "f")
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"a before", "b before", "c before", "f", "c after", "b after", "a after"}, calls)
+	assert.Equal(t, []string{"begin", "commit"}, b.calls)
+}
+
+func TestManager_Use_shortCircuit(t *testing.T) {
+	b := &fakeBackend{}
+	m := newManager(b)
+	errBlocked := errors.New("blocked") //nolint:goerr113
+
+	m.Use(func(_ RunFunc) RunFunc {
+		return func(_ context.Context, _ *sql.DB, _ *sql.TxOptions, _ func(ctx context.Context) error) error {
+			return errBlocked
+		}
+	})
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, noop), errBlocked)
+	assert.Empty(t, b.calls)
+}
+
+func TestManager_Use_outcome(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+
+	tests := []struct {
+		name string
+		b    *fakeBackend
+		f    func(ctx context.Context) error
+		want error
+	}{
+		{name: "committed", b: &fakeBackend{}, f: noop},
+		{name: "begin", b: &fakeBackend{beginErr: errFailed}, f: noop, want: errFailed},
+		{name: "function", b: &fakeBackend{}, f: func(_ context.Context) error { return errFailed }, want: errFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(tt.b)
+
+			var observed []error
+
+			m.Use(func(next RunFunc) RunFunc {
+				return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+					err := next(ctx, db, opts, f)
+					observed = append(observed, err)
+
+					return err
+				}
+			})
+
+			err := m.Wrap(context.Background(), nil, tt.f)
+
+			assert.ErrorIs(t, err, tt.want)
+			assert.Equal(t, []error{err}, observed)
+		})
+	}
+}
+
+func TestManager_Use_shutdown(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	m.Use(recording(&calls, "a"))
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, noop), ErrShuttingDown)
+	assert.Empty(t, calls, "shutdown should reject before interceptors")
+}
+
+func TestManager_UseOnReuse(t *testing.T) {
+	m := newManager(&fakeBackend{})
+
+	var calls []string
+
+	m.UseOnReuse(recording(&calls, "a"), recording(&calls, "b"))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		calls = append(calls, "wrap")
+
+		return m.Ensure(ctx, nil, func(inner context.Context) error {
+			assert.Equal(t, Get(ctx).ID(), Get(inner).ID())
+			calls = append(calls, "f")
+
+			return nil
+		})
+	}))
+
+	assert.Equal(t, []string{"wrap", "a before", "b before", "f", "b after", "a after"}, calls)
+}
@@ -0,0 +1,51 @@
+// Package clock abstracts time so that time-based behavior can be tested deterministically.
+package clock
+
+import "time"
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a Timer sending the current time on its channel after duration d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc returns a Timer calling f in its own goroutine after duration d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered, nil for AfterFunc timers.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing, it returns false if the Timer already fired or was stopped.
+	Stop() bool
+}
+
+// Real is the Clock of the time package.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) NewTimer(d time.Duration) Timer {
+	t := time.NewTimer(d)
+
+	return realTimer{t: t, c: t.C}
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{t: time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	t *time.Timer
+	c <-chan time.Time
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
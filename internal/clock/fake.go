@@ -0,0 +1,139 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when told to.
+//
+// Timers due are fired by Advance: AfterFunc functions are called synchronously.
+//
+// A Fake is safe for concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a Fake Clock set to given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, nil)
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, fn)
+}
+
+// Advance moves the time forward by duration d, firing the timers due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	now := f.now
+
+	var due []*fakeTimer
+
+	pending := f.timers[:0]
+
+	for _, t := range f.timers {
+		if t.when.After(now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+
+	f.timers = pending
+	f.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].when.Before(due[j].when)
+	})
+
+	for _, t := range due {
+		t.fire(now)
+	}
+}
+
+// Timers returns the number of timers waiting to fire.
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.timers)
+}
+
+func (f *Fake) add(d time.Duration, fn func()) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{clock: f, when: f.now.Add(d), f: fn}
+
+	if fn == nil {
+		t.c = make(chan time.Time, 1)
+	}
+
+	if d <= 0 {
+		if fn == nil {
+			t.c <- f.now
+		} else {
+			go fn()
+		}
+
+		return t
+	}
+
+	f.timers = append(f.timers, t)
+
+	return t
+}
+
+func (f *Fake) remove(t *fakeTimer) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	clock *Fake
+	when  time.Time
+	f     func()
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+	} else {
+		t.c <- now
+	}
+}
@@ -0,0 +1,82 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake_Now(t *testing.T) {
+	start := time.Unix(0, 0)
+	f := NewFake(start)
+
+	assert.Equal(t, start, f.Now())
+
+	f.Advance(time.Second)
+
+	assert.Equal(t, start.Add(time.Second), f.Now())
+}
+
+func TestFake_NewTimer(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	timer := f.NewTimer(time.Second)
+
+	f.Advance(time.Second - 1)
+
+	select {
+	case <-timer.C():
+		t.Fatal("timer should not have fired")
+	default:
+	}
+
+	f.Advance(1)
+
+	assert.Equal(t, f.Now(), <-timer.C())
+	assert.False(t, timer.Stop())
+	assert.Equal(t, 0, f.Timers())
+}
+
+func TestFake_AfterFunc(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+
+	var fired []int
+
+	f.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	f.AfterFunc(time.Second, func() { fired = append(fired, 1) })
+	stopped := f.AfterFunc(time.Second, func() { fired = append(fired, 3) })
+
+	require.True(t, stopped.Stop())
+	assert.Nil(t, stopped.C())
+
+	f.Advance(3 * time.Second)
+
+	assert.Equal(t, []int{1, 2}, fired)
+}
+
+func TestFake_immediate(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	done := make(chan struct{})
+
+	f.AfterFunc(0, func() { close(done) })
+
+	<-done
+	<-f.NewTimer(0).C()
+}
+
+func TestReal(t *testing.T) {
+	var c Clock = Real{}
+
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+
+	timer := c.NewTimer(time.Hour)
+
+	assert.NotNil(t, timer.C())
+	assert.True(t, timer.Stop())
+
+	timer = c.AfterFunc(time.Hour, func() {})
+
+	assert.Nil(t, timer.C())
+	assert.True(t, timer.Stop())
+}
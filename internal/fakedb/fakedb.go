@@ -0,0 +1,305 @@
+// Package fakedb is a database/sql driver without database, whose calls are scripted and recorded, for tests.
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Driver is a scriptable driver, see Open.
+//
+// Its scripts must be set before opening connections. Nil scripts succeed: statements have no effect,
+// queries return no rows. Every call is recorded, see Calls.
+type Driver struct {
+	// Connect, if not nil, returns the error of opening a connection.
+	Connect func() error
+	// Begin, if not nil, returns the error of beginning a transaction with given options.
+	Begin func(opts *sql.TxOptions) error
+	// Exec, if not nil, returns the result of executing given query with given arguments.
+	Exec func(ctx context.Context, query string, args []any) (Result, error)
+	// Query, if not nil, returns the rows of given query with given arguments.
+	Query func(ctx context.Context, query string, args []any) (*Rows, error)
+	// Commit, if not nil, returns the error of committing a transaction.
+	Commit func() error
+	// Rollback, if not nil, returns the error of rolling back a transaction.
+	Rollback func() error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Result is the result of a statement.
+type Result struct {
+	LastInsertID int64
+	RowsAffected int64
+}
+
+// Rows are the rows returned by a query.
+type Rows struct {
+	Columns []string
+	// Values of the rows, one value per column.
+	Values [][]any
+}
+
+// Call is a call made to a Driver.
+type Call struct {
+	// Method is "connect", "begin", "exec", "query", "commit" or "rollback".
+	Method string
+	Query  string
+	// Args are the arguments of the statement, as given.
+	Args []any
+}
+
+// String returns the method of the call, followed by its query if any, like "exec DELETE FROM item".
+func (c Call) String() string {
+	if c.Query == "" {
+		return c.Method
+	}
+
+	return c.Method + " " + c.Query
+}
+
+// Open returns a database of given driver, closed by the cleanup of tb.
+func Open(tb testing.TB, d *Driver) *sql.DB {
+	tb.Helper()
+
+	db := sql.OpenDB(connector{d: d})
+
+	tb.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db
+}
+
+// Calls returns the calls made so far.
+func (d *Driver) Calls() []Call {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Call(nil), d.calls...)
+}
+
+// Strings returns the calls made so far, see Call.String.
+func (d *Driver) Strings() []string {
+	calls := d.Calls()
+	result := make([]string, len(calls))
+
+	for i, c := range calls {
+		result[i] = c.String()
+	}
+
+	return result
+}
+
+func (d *Driver) call(method, query string, args []driver.NamedValue) []any {
+	var values []any
+
+	if len(args) > 0 {
+		values = make([]any, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+	}
+
+	d.mu.Lock()
+	d.calls = append(d.calls, Call{Method: method, Query: query, Args: values})
+	d.mu.Unlock()
+
+	return values
+}
+
+func (d *Driver) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := d.call("exec", query, args)
+
+	if d.Exec == nil {
+		return result{}, nil
+	}
+
+	r, err := d.Exec(ctx, query, values)
+
+	return result{r: r}, err
+}
+
+func (d *Driver) query(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := d.call("query", query, args)
+
+	if d.Query == nil {
+		return &rows{}, nil
+	}
+
+	r, err := d.Query(ctx, query, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{rows: r}, nil
+}
+
+// script records a call and returns the error of given script, if any.
+func (d *Driver) script(method string, f func() error) error {
+	d.call(method, "", nil)
+
+	if f == nil {
+		return nil
+	}
+
+	return f()
+}
+
+type connector struct {
+	d *Driver
+}
+
+func (c connector) Connect(_ context.Context) (driver.Conn, error) {
+	if err := c.d.script("connect", c.d.Connect); err != nil {
+		return nil, err
+	}
+
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return c
+}
+
+func (c connector) Open(_ string) (driver.Conn, error) {
+	return c.Connect(context.Background())
+}
+
+type conn struct {
+	d *Driver
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) {
+	return stmt{d: c.d, query: query}, nil
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	err := c.d.script("begin", func() error {
+		if c.d.Begin == nil {
+			return nil
+		}
+
+		return c.d.Begin(&sql.TxOptions{Isolation: sql.IsolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tx(c), nil
+}
+
+// CheckNamedValue keeps arguments as given, for Calls.
+func (c conn) CheckNamedValue(_ *driver.NamedValue) error {
+	return nil
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.d.exec(ctx, query, args)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.d.query(ctx, query, args)
+}
+
+type tx struct {
+	d *Driver
+}
+
+func (t tx) Commit() error {
+	return t.d.script("commit", t.d.Commit)
+}
+
+func (t tx) Rollback() error {
+	return t.d.script("rollback", t.d.Rollback)
+}
+
+type stmt struct {
+	d     *Driver
+	query string
+}
+
+func (s stmt) Close() error {
+	return nil
+}
+
+func (s stmt) NumInput() int {
+	return -1
+}
+
+func (s stmt) Exec(_ []driver.Value) (driver.Result, error) {
+	panic("fakedb: ExecContext expected")
+}
+
+func (s stmt) Query(_ []driver.Value) (driver.Rows, error) {
+	panic("fakedb: QueryContext expected")
+}
+
+func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.d.exec(ctx, s.query, args)
+}
+
+func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.d.query(ctx, s.query, args)
+}
+
+// CheckNamedValue keeps arguments as given, for Calls.
+func (s stmt) CheckNamedValue(_ *driver.NamedValue) error {
+	return nil
+}
+
+type result struct {
+	r Result
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.r.LastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.r.RowsAffected, nil
+}
+
+type rows struct {
+	rows *Rows
+	next int
+}
+
+func (r *rows) Columns() []string {
+	if r.rows == nil {
+		return nil
+	}
+
+	return r.rows.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.rows == nil || r.next >= len(r.rows.Values) {
+		return io.EOF
+	}
+
+	for i, v := range r.rows.Values[r.next] {
+		dest[i] = v
+	}
+
+	r.next++
+
+	return nil
+}
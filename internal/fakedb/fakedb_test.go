@@ -0,0 +1,55 @@
+package fakedb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver(t *testing.T) {
+	errCommit := errors.New("commit failed")
+	d := &Driver{
+		Exec: func(_ context.Context, _ string, _ []any) (Result, error) {
+			return Result{RowsAffected: 2}, nil
+		},
+		Query: func(_ context.Context, _ string, args []any) (*Rows, error) {
+			return &Rows{Columns: []string{"name"}, Values: [][]any{{args[0]}}}, nil
+		},
+		Commit: func() error { return errCommit },
+	}
+	db := Open(t, d)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM item WHERE id = ?", 1)
+	require.NoError(t, err)
+
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	var name string
+
+	require.NoError(t, tx.QueryRowContext(ctx, "SELECT ?", "Alice").Scan(&name))
+	assert.Equal(t, "Alice", name)
+	require.ErrorIs(t, tx.Commit(), errCommit)
+
+	assert.Equal(
+		t,
+		[]string{"connect", "begin", "exec DELETE FROM item WHERE id = ?", "query SELECT ?", "commit"},
+		d.Strings(),
+	)
+	assert.Equal(t, []any{1}, d.Calls()[2].Args)
+}
+
+func TestDriver_Connect(t *testing.T) {
+	errConnect := errors.New("connection refused")
+	db := Open(t, &Driver{Connect: func() error { return errConnect }})
+
+	require.ErrorIs(t, db.PingContext(context.Background()), errConnect)
+}
@@ -0,0 +1,81 @@
+package testdb
+
+import "strings"
+
+// Statement is a statement of a SQL script.
+type Statement struct {
+	// Line of the script the statement starts at.
+	Line  int
+	Query string
+	// Args are the arguments of the statement, if any.
+	Args []any
+}
+
+// SplitSQL splits given SQL script into statements, ignoring semicolons in quotes and comments.
+func SplitSQL(script string) []Statement {
+	var (
+		result []Statement
+		buf    strings.Builder
+		line   = 1
+		start  = 0
+		quote  rune
+	)
+
+	flush := func() {
+		if query := strings.TrimSpace(buf.String()); query != "" {
+			result = append(result, Statement{Line: start, Query: query})
+		}
+
+		buf.Reset()
+
+		start = 0
+	}
+
+	runes := []rune(script)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+			if i < len(runes) {
+				line++
+				buf.WriteRune('\n')
+			}
+
+			continue
+		case r == ';':
+			flush()
+
+			continue
+		}
+
+		if start == 0 && !isSpace(r) {
+			start = line
+		}
+
+		if r == '\n' {
+			line++
+		}
+
+		buf.WriteRune(r)
+	}
+
+	flush()
+
+	return result
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
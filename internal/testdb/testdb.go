@@ -0,0 +1,199 @@
+// Package testdb opens SQLite databases for tests, see txxtest.NewDB.
+package testdb
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BusyTimeout is the default time to wait for locks.
+const BusyTimeout = 5 * time.Second
+
+//nolint:gochecknoglobals
+var (
+	databases    atomic.Uint64
+	unsafeInName = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// Option configures a database returned by Open.
+type Option func(o *options)
+
+type options struct {
+	file        bool
+	path        string
+	journal     string
+	sharedCache bool
+	busyTimeout time.Duration
+	// maxIdle connections, if not negative
+	maxIdle int
+	schemas []schema
+}
+
+// schema is a SQL script applied by Open.
+type schema struct {
+	name   string
+	script string
+	fsys   fs.FS
+}
+
+// WithFile makes Open open a database file in a temporary directory, in WAL mode so that readers
+// don't block writers, instead of an in-memory database.
+func WithFile() Option {
+	return func(o *options) {
+		o.file = true
+	}
+}
+
+// WithPath makes Open open given database file, like WithFile, so that several databases share it.
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.file = true
+		o.path = path
+	}
+}
+
+// WithJournalMode makes Open use given journal mode for a database file, rather than WAL,
+// like "DELETE" for readers blocking writers.
+func WithJournalMode(mode string) Option {
+	return func(o *options) {
+		o.journal = mode
+	}
+}
+
+// WithBusyTimeout makes Open wait for locks up to given duration, rather than BusyTimeout.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.busyTimeout = d
+	}
+}
+
+// WithMaxIdleConns makes Open keep at most n idle connections of a database file, see sql.DB.SetMaxIdleConns.
+func WithMaxIdleConns(n int) Option {
+	return func(o *options) {
+		o.maxIdle = n
+	}
+}
+
+// WithSharedCache makes Open open an in-memory database shared by several connections,
+// instead of a single connection. Shared-cache connections lock whole tables.
+func WithSharedCache() Option {
+	return func(o *options) {
+		o.sharedCache = true
+	}
+}
+
+// WithSchema makes Open apply given SQL script, a list of statements separated by semicolons.
+func WithSchema(script string) Option {
+	return func(o *options) {
+		o.schemas = append(o.schemas, schema{name: "schema", script: script})
+	}
+}
+
+// WithSchemaFS makes Open apply given SQL files of fsys, in order, see WithSchema.
+func WithSchemaFS(fsys fs.FS, names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.schemas = append(o.schemas, schema{name: name, fsys: fsys})
+		}
+	}
+}
+
+// Open opens a SQLite database for test tb, closed by its cleanup, with foreign keys enabled
+// and a busy timeout of BusyTimeout.
+//
+// By default, the database is in-memory and uniquely named, so that parallel tests don't collide,
+// on a single connection as every in-memory connection has its own database otherwise.
+// See WithFile and WithSharedCache for databases allowing concurrent connections.
+//
+// It requires the modernc.org/sqlite driver to be registered.
+func Open(tb testing.TB, opts ...Option) *sql.DB {
+	tb.Helper()
+
+	o := options{journal: "WAL", busyTimeout: BusyTimeout, maxIdle: -1}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	name := unsafeInName.ReplaceAllString(tb.Name(), "_") + "_" + strconv.FormatUint(databases.Add(1), 10)
+	query := url.Values{"_pragma": {
+		"foreign_keys(1)", "busy_timeout(" + strconv.FormatInt(o.busyTimeout.Milliseconds(), 10) + ")",
+	}}
+
+	var dsn string
+
+	switch {
+	case o.file:
+		if o.path == "" {
+			o.path = filepath.Join(tb.TempDir(), name+".db")
+		}
+
+		query["_pragma"] = append(query["_pragma"], "journal_mode("+o.journal+")")
+		dsn = "file:" + (&url.URL{Path: o.path}).EscapedPath()
+	case o.sharedCache:
+		query.Set("mode", "memory")
+		query.Set("cache", "shared")
+		dsn = "file:" + name
+	default:
+		query.Set("mode", "memory")
+		dsn = "file:" + name
+	}
+
+	db, err := sql.Open("sqlite", dsn+"?"+query.Encode())
+	if err != nil {
+		tb.Fatalf("testdb: opening database: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	if !o.file {
+		// connections must stay open, an in-memory database vanishing with its last one
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+
+		if !o.sharedCache {
+			db.SetMaxOpenConns(1)
+		}
+	} else if o.maxIdle >= 0 {
+		db.SetMaxIdleConns(o.maxIdle)
+	}
+
+	for _, s := range o.schemas {
+		if err = s.apply(db); err != nil {
+			tb.Fatalf("testdb: %v", err)
+		}
+	}
+
+	return db
+}
+
+func (s schema) apply(db *sql.DB) error {
+	script := s.script
+
+	if s.fsys != nil {
+		data, err := fs.ReadFile(s.fsys, s.name)
+		if err != nil {
+			return err
+		}
+
+		script = string(data)
+	}
+
+	for _, stmt := range SplitSQL(script) {
+		if _, err := db.Exec(stmt.Query); err != nil {
+			return fmt.Errorf("%s:%d: %w", s.name, stmt.Line, err)
+		}
+	}
+
+	return nil
+}
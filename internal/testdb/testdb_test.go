@@ -0,0 +1,53 @@
+package testdb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestSchema_apply(t *testing.T) {
+	db := Open(t)
+
+	err := schema{name: "broken.sql", script: "CREATE TABLE a (id INTEGER);\nCREATE TABLE a (id INTEGER);"}.apply(db)
+
+	require.ErrorContains(t, err, "broken.sql:2:")
+}
+
+func TestOpen_path(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+	writer := Open(t, WithPath(path), WithSchema("CREATE TABLE a (id INTEGER)"))
+	reader := Open(t, WithPath(path), WithBusyTimeout(10*time.Millisecond))
+
+	_, err := writer.Exec("INSERT INTO a VALUES (1)")
+	require.NoError(t, err)
+
+	var timeout, n int
+
+	require.NoError(t, reader.QueryRow("PRAGMA busy_timeout").Scan(&timeout))
+	assert.Equal(t, 10, timeout)
+	require.NoError(t, reader.QueryRow("SELECT COUNT(*) FROM a").Scan(&n))
+	assert.Equal(t, 1, n, "databases should share the file")
+}
+
+func TestOpen_journalMode(t *testing.T) {
+	db := Open(t, WithFile(), WithJournalMode("DELETE"))
+
+	var mode string
+
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	assert.Equal(t, "delete", mode)
+}
+
+func TestSplitSQL(t *testing.T) {
+	script := "CREATE TABLE a (name TEXT);\n-- a; comment\nINSERT INTO a VALUES ('x;y');\n\n  ;"
+
+	assert.Equal(t, []Statement{
+		{Line: 1, Query: "CREATE TABLE a (name TEXT)"},
+		{Line: 3, Query: "INSERT INTO a VALUES ('x;y')"},
+	}, SplitSQL(script))
+}
@@ -0,0 +1,102 @@
+// Package txxgenexample is an example of decorator generated by txxgen.
+package txxgenexample
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/MartyHub/txx"
+)
+
+//go:generate go run ../../cmd/txxgen -type Store
+
+// ErrNotInTx is returned by SQLStore methods called outside of a transaction.
+var ErrNotInTx = errors.New("not in a transaction")
+
+// Store persists names.
+type Store interface {
+	// Count returns the number of names.
+	//
+	//txx:readonly
+	Count(ctx context.Context) (int, error)
+	// Add given names.
+	Add(ctx context.Context, names ...string) error
+	// Rename the name with given ID, returning the number of renamed rows.
+	//
+	//txx:new
+	Rename(ctx context.Context, id int64, name string) (int64, error)
+	// Ping the store.
+	//
+	//txx:skip
+	Ping(ctx context.Context) error
+}
+
+// SQLStore is a Store expecting to be called in transactions.
+type SQLStore struct {
+	DB *sql.DB
+}
+
+func (s SQLStore) Count(ctx context.Context) (int, error) {
+	current, err := current(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !current.Opts.ReadOnly {
+		return 0, fmt.Errorf("read-only transaction expected") //nolint:goerr113
+	}
+
+	var n int
+
+	err = current.QueryRowContext(ctx, "SELECT COUNT(*) FROM name").Scan(&n)
+
+	return n, err
+}
+
+func (s SQLStore) Add(ctx context.Context, names ...string) error {
+	current, err := current(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err = current.ExecContext(ctx, "INSERT INTO name (value) VALUES (?)", name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s SQLStore) Rename(ctx context.Context, id int64, name string) (int64, error) {
+	current, err := current(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := current.ExecContext(ctx, "UPDATE name SET value = ? WHERE id = ?", name, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s SQLStore) Ping(ctx context.Context) error {
+	if txx.IsInTx(ctx) {
+		return fmt.Errorf("no transaction expected") //nolint:goerr113
+	}
+
+	return s.DB.PingContext(ctx)
+}
+
+func current(ctx context.Context) (txx.Current, error) {
+	current := txx.Get(ctx)
+	if !current.IsValid() {
+		return current, ErrNotInTx
+	}
+
+	return current, nil
+}
@@ -0,0 +1,69 @@
+package txxgenexample
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newStore(t *testing.T) *StoreTx {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec("CREATE TABLE name (id INTEGER PRIMARY KEY, value TEXT NOT NULL UNIQUE)")
+	require.NoError(t, err)
+
+	return NewStoreTx(txx.New(db), SQLStore{DB: db})
+}
+
+func TestStoreTx(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Add(ctx, "a", "b"))
+
+	n, err := store.Count(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	renamed, err := store.Rename(ctx, 1, "c")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), renamed)
+	require.NoError(t, store.Ping(ctx))
+}
+
+func TestStoreTx_rollback(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	require.Error(t, store.Add(ctx, "a", "b", "a"))
+
+	n, err := store.Count(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "all names should be rolled back")
+}
+
+func TestSQLStore(t *testing.T) {
+	store := newStore(t).Next
+	ctx := context.Background()
+
+	_, err := store.Count(ctx)
+
+	require.ErrorIs(t, err, ErrNotInTx)
+	require.ErrorIs(t, store.Add(ctx, "a"), ErrNotInTx)
+}
@@ -0,0 +1,63 @@
+// Code generated by txxgen. DO NOT EDIT.
+
+package txxgenexample
+
+import (
+	"context"
+
+	"github.com/MartyHub/txx"
+)
+
+// StoreTx decorates a Store, running its methods in transactions.
+type StoreTx struct {
+	*txx.Manager
+
+	Next Store
+}
+
+// NewStoreTx returns a StoreTx running next methods in transactions of m.
+func NewStoreTx(m *txx.Manager, next Store) *StoreTx {
+	return &StoreTx{Manager: m, Next: next}
+}
+
+var _ Store = (*StoreTx)(nil)
+
+// Count runs Next.Count in a read-only transaction.
+func (d *StoreTx) Count(ctx context.Context) (r0 int, err error) {
+	err = d.Manager.Ensure(ctx, txx.ReadOnly(), func(ctx context.Context) error {
+		var err error
+
+		r0, err = d.Next.Count(ctx)
+
+		return err
+	})
+
+	return r0, err
+}
+
+// Add runs Next.Add in a transaction.
+func (d *StoreTx) Add(ctx context.Context, names ...string) (err error) {
+	err = d.Manager.Ensure(ctx, nil, func(ctx context.Context) error {
+		return d.Next.Add(ctx, names...)
+	})
+
+	return err
+}
+
+// Rename runs Next.Rename in a new transaction.
+func (d *StoreTx) Rename(ctx context.Context, id int64, name string) (r0 int64, err error) {
+	err = d.Manager.Wrap(ctx, nil, func(ctx context.Context) error {
+		var err error
+
+		r0, err = d.Next.Rename(ctx, id, name)
+
+		return err
+	})
+
+	return r0, err
+}
+
+// Ping runs Next.Ping without transaction handling.
+func (d *StoreTx) Ping(ctx context.Context) (err error) {
+	return d.Next.Ping(ctx)
+}
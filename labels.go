@@ -0,0 +1,73 @@
+package txx
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// DefaultMaxLabels is the default maximum number of labels of a transaction, see WithLabelsFromContext.
+const DefaultMaxLabels = 8
+
+// WithLabelsFromContext extracts labels of every transaction from the context given to Wrap,
+// like the endpoint or request ID, reported in Info.Labels for logging, metrics and tracing.
+//
+// Labels are extracted once, right before begin, so that completion records carry them
+// even if the context is gone. Labels are metric dimensions: values must have a low cardinality.
+// At most DefaultMaxLabels labels are kept, see WithMaxLabels.
+func WithLabelsFromContext(f func(ctx context.Context) map[string]string) Option {
+	return func(m *Manager) {
+		m.r.labels = f
+	}
+}
+
+// WithMaxLabels sets the maximum number of labels of a transaction, DefaultMaxLabels by default.
+//
+// Extra labels are dropped, keeping the first ones in key order.
+func WithMaxLabels(n int) Option {
+	return func(m *Manager) {
+		m.r.maxLabels = n
+	}
+}
+
+// Labels returns the labels of the transaction extracted by WithLabelsFromContext, nil if none.
+func (c Current) Labels() map[string]string {
+	return maps.Clone(c.s.getLabels())
+}
+
+func (s *scope) getLabels() map[string]string {
+	if s == nil {
+		return nil
+	}
+
+	return s.labels
+}
+
+// extractLabels returns a copy of the labels of given context, nil if none.
+func (r runner) extractLabels(ctx context.Context) map[string]string {
+	if r.labels == nil {
+		return nil
+	}
+
+	labels := r.labels(ctx)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	limit := r.maxLabels
+	if limit <= 0 {
+		limit = DefaultMaxLabels
+	}
+
+	if len(labels) <= limit {
+		return maps.Clone(labels)
+	}
+
+	result := make(map[string]string, limit)
+
+	for _, key := range slices.Sorted(maps.Keys(labels))[:limit] {
+		result[key] = labels[key]
+	}
+
+	return result
+}
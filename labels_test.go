@@ -0,0 +1,94 @@
+package txx
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type labelsKey struct{}
+
+func labelsFrom(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+
+	return labels
+}
+
+// sink is a fake metrics sink counting transactions per series.
+type sink map[string]int
+
+func (s sink) record(info Info) {
+	series := make([]string, 0, len(info.Labels))
+	for _, key := range slices.Sorted(maps.Keys(info.Labels)) {
+		series = append(series, key+"="+info.Labels[key])
+	}
+
+	s[fmt.Sprintf("committed=%t,cause=%s,%s", info.Committed, info.Cause, strings.Join(series, ","))]++
+}
+
+func TestWithLabelsFromContext(t *testing.T) {
+	metrics := sink{}
+	m := New(testdb.Open(t, inMemory), WithLabelsFromContext(labelsFrom), WithFinally(metrics.record))
+	labels := map[string]string{"endpoint": "/orders"}
+	ctx := context.WithValue(context.Background(), labelsKey{}, labels)
+
+	require.NoError(t, m.Wrap(ctx, nil, func(_ context.Context) error {
+		labels["endpoint"] = "changed"
+
+		return nil
+	}))
+
+	labels["endpoint"] = "/orders"
+
+	require.Error(t, m.Wrap(ctx, nil, fail))
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+
+	assert.Equal(t, sink{
+		"committed=true,cause=none,endpoint=/orders":   1,
+		"committed=false,cause=error,endpoint=/orders": 1,
+		"committed=true,cause=none,":                   1,
+	}, metrics)
+}
+
+func TestWithMaxLabels(t *testing.T) {
+	labels := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		labels[fmt.Sprintf("l%d", i)] = "v"
+	}
+
+	ctx := context.WithValue(context.Background(), labelsKey{}, labels)
+
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{name: "default", want: DefaultMaxLabels},
+		{name: "custom", opts: []Option{WithMaxLabels(2)}, want: 2},
+		{name: "above count", opts: []Option{WithMaxLabels(20)}, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+
+			opts := append([]Option{WithLabelsFromContext(labelsFrom), WithFinally(func(info Info) {
+				got = info.Labels
+			})}, tt.opts...)
+
+			require.NoError(t, New(testdb.Open(t, inMemory), opts...).Wrap(ctx, nil, noop))
+			assert.Len(t, got, tt.want)
+
+			for i := 0; i < tt.want; i++ {
+				assert.Contains(t, got, fmt.Sprintf("l%d", i), "first labels in key order should be kept")
+			}
+		})
+	}
+}
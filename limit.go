@@ -0,0 +1,47 @@
+package txx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStatementLimitExceeded is returned when a statement would exceed the limit set by WithMaxStatements.
+var ErrStatementLimitExceeded = errors.New("txx: statement limit exceeded")
+
+// WithMaxStatements limits the number of statements a transaction may execute through Current methods,
+// and so through the helpers of the package, unlimited if not positive.
+//
+// A statement exceeding the limit is not executed: ErrStatementLimitExceeded is returned and the transaction
+// is marked rollback-only, so it is rolled back even if the error is ignored.
+// Transactions reused by Ensure share the limit of the outer transaction.
+// Statements executed directly on Get(ctx).Tx are not counted.
+func WithMaxStatements(n int) Option {
+	return func(m *Manager) {
+		m.r.maxStatements = n
+	}
+}
+
+// count a statement about to be executed, returning an error if it exceeds the limit.
+//
+// It must be called with s.mu locked.
+func (s *scope) countStatement() error {
+	if s.maxStatements > 0 && s.count >= s.maxStatements {
+		if s.rollbackOnly == nil {
+			s.rollbackOnly = fmt.Errorf("%w: more than %d", ErrStatementLimitExceeded, s.maxStatements)
+		}
+
+		return s.rollbackOnly
+	}
+
+	s.count++
+
+	return nil
+}
+
+// getRollbackOnly returns why the transaction must be rolled back, if it must.
+func (s *scope) getRollbackOnly() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rollbackOnly
+}
@@ -0,0 +1,91 @@
+package txx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxStatements(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var infos []Info
+
+	m := New(db, WithMaxStatements(2), WithFinally(func(info Info) { infos = append(infos, info) }))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		for _, name := range []string{"Carol", "Dave", "Eve"} {
+			if err := insertPerson(ctx, db, name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, ErrStatementLimitExceeded)
+	assert.Equal(t, 2, countPeople(t, db))
+	require.Len(t, infos, 1)
+	assert.False(t, infos[0].Committed)
+	assert.Equal(t, CauseRollbackOnly, infos[0].Cause)
+	assert.True(t, infos[0].StatementLimitExceeded)
+}
+
+func TestWithMaxStatements_ignored(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithMaxStatements(1))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+		require.ErrorIs(t, insertPerson(ctx, db, "Dave"), ErrStatementLimitExceeded)
+
+		_, err := QueryOne[int](ctx, db, "SELECT COUNT(*) FROM person")
+		require.ErrorIs(t, err, ErrStatementLimitExceeded)
+		require.ErrorIs(t, Get(ctx).Commit(ctx), ErrStatementLimitExceeded)
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, ErrStatementLimitExceeded)
+	assert.Equal(t, 2, countPeople(t, db), "transaction should be rolled back")
+}
+
+func TestWithMaxStatements_reused(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithMaxStatements(2))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+
+		return m.Ensure(ctx, nil, func(ctx context.Context) error {
+			require.NoError(t, insertPerson(ctx, db, "Dave"))
+
+			return insertPerson(ctx, db, "Eve")
+		})
+	})
+
+	require.ErrorIs(t, err, ErrStatementLimitExceeded)
+	assert.Equal(t, 2, countPeople(t, db))
+}
+
+func TestWithMaxStatements_uncounted(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithMaxStatements(1))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		tx := Get(ctx).Tx
+
+		for _, name := range []string{"Carol", "Dave"} {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", name); err != nil {
+				return err
+			}
+		}
+
+		return insertPerson(ctx, db, "Eve")
+	}))
+
+	assert.Equal(t, 5, countPeople(t, db))
+}
@@ -0,0 +1,128 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrLockReadOnly is returned when rows are locked in a read-only transaction, see SelectForUpdate.
+	ErrLockReadOnly = errors.New("txx: rows can't be locked in a read-only transaction")
+	// ErrLockTimeout wraps the errors of locking reads which timed out or failed to lock rows,
+	// so that a retry classifier can retry them with errors.Is.
+	ErrLockTimeout = errors.New("txx: lock timeout")
+)
+
+// LockOption changes how SelectForUpdate waits for rows locked by other transactions.
+type LockOption int
+
+// Lock options.
+const (
+	// NoWait fails right away with ErrLockTimeout instead of waiting for locked rows.
+	NoWait LockOption = iota + 1
+	// SkipLocked skips locked rows.
+	SkipLocked
+)
+
+// SelectForUpdate runs a query locking the selected rows in the current read-write transaction,
+// and scans every row into a T, see Select.
+//
+// The locking clause of the dialect is appended to the query, FOR UPDATE followed by NOWAIT or
+// SKIP LOCKED if NoWait or SkipLocked is given among args. It is omitted for DialectSQLite,
+// SQLite locking the whole database on write instead.
+//
+// It returns ErrNoTransaction if there is no transaction, and ErrLockReadOnly if it is read-only.
+// Errors caused by lock timeouts, or locked rows with NoWait, wrap ErrLockTimeout.
+func SelectForUpdate[T any](ctx context.Context, db *sql.DB, query string, args ...any) ([]T, error) {
+	current := Get(ctx)
+	if !current.IsValid() {
+		return nil, ErrNoTransaction
+	}
+
+	if current.Opts != nil && current.Opts.ReadOnly {
+		return nil, ErrLockReadOnly
+	}
+
+	args, opts := lockOptions(args)
+
+	rows, err := QuerierFor(ctx, db).QueryContext(ctx, forUpdate(current.s.getDialect(), query, opts), args...)
+	if err != nil {
+		return nil, lockError(err)
+	}
+
+	result, err := scanAll[T](rows)
+	if err != nil {
+		return nil, lockError(err)
+	}
+
+	return result, nil
+}
+
+// lockOptions splits given query arguments from the lock options among them.
+func lockOptions(args []any) ([]any, []LockOption) {
+	var opts []LockOption
+
+	result := args[:0:0]
+
+	for _, arg := range args {
+		if opt, ok := arg.(LockOption); ok {
+			opts = append(opts, opt)
+		} else {
+			result = append(result, arg)
+		}
+	}
+
+	return result, opts
+}
+
+// forUpdate appends the locking clause of given dialect and options to query.
+func forUpdate(d Dialect, query string, opts []LockOption) string {
+	if d == DialectSQLite {
+		return query
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(strings.TrimRight(strings.TrimSpace(query), ";"))
+	sb.WriteString(" FOR UPDATE")
+
+	for _, opt := range opts {
+		switch opt {
+		case NoWait:
+			sb.WriteString(" NOWAIT")
+		case SkipLocked:
+			sb.WriteString(" SKIP LOCKED")
+		}
+	}
+
+	return sb.String()
+}
+
+// lockError wraps given error with ErrLockTimeout if caused by a lock.
+//
+// Drivers are not imported: PostgreSQL errors are recognized by their SQLSTATE, lock_not_available,
+// and other engines by their message.
+func lockError(err error) error {
+	var state interface{ SQLState() string }
+
+	if errors.As(err, &state) && state.SQLState() == "55P03" {
+		return fmt.Errorf("%w: %w", ErrLockTimeout, err)
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, pattern := range []string{
+		"lock wait timeout exceeded", // MySQL 1205
+		"nowait is set",              // MySQL 3572
+		"database is locked",         // SQLite busy
+	} {
+		if strings.Contains(msg, pattern) {
+			return fmt.Errorf("%w: %w", ErrLockTimeout, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "SQLSTATE " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+func TestSelectForUpdate(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithDialect(DialectSQLite))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		got, err := SelectForUpdate[person](ctx, db, "SELECT id, name FROM person WHERE id > ? ORDER BY id", 0, NoWait)
+		if err != nil {
+			return err
+		}
+
+		require.Len(t, got, 2)
+		assert.Equal(t, "Alice", got[0].Name)
+		assert.Equal(t, "Bob", got[1].Name)
+
+		return nil
+	}))
+}
+
+func TestSelectForUpdate_errors(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	query := "SELECT name FROM person"
+
+	_, err := SelectForUpdate[string](context.Background(), db, query)
+	require.ErrorIs(t, err, ErrNoTransaction)
+
+	require.NoError(t, Wrap(context.Background(), db, ReadOnly(), func(ctx context.Context) error {
+		_, err := SelectForUpdate[string](ctx, db, query)
+		assert.ErrorIs(t, err, ErrLockReadOnly)
+
+		return nil
+	}))
+}
+
+func TestForUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		opts    []LockOption
+		want    string
+	}{
+		{name: "generic", want: "SELECT * FROM item FOR UPDATE"},
+		{name: "noWait", dialect: DialectPostgres, opts: []LockOption{NoWait}, want: "SELECT * FROM item FOR UPDATE NOWAIT"},
+		{
+			name:    "skipLocked",
+			dialect: DialectMySQL,
+			opts:    []LockOption{SkipLocked},
+			want:    "SELECT * FROM item FOR UPDATE SKIP LOCKED",
+		},
+		{name: "sqlite", dialect: DialectSQLite, opts: []LockOption{NoWait}, want: "SELECT * FROM item;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, forUpdate(tt.dialect, "SELECT * FROM item;", tt.opts))
+		})
+	}
+}
+
+func TestLockOptions(t *testing.T) {
+	args, opts := lockOptions([]any{1, SkipLocked, "a"})
+
+	assert.Equal(t, []any{1, "a"}, args)
+	assert.Equal(t, []LockOption{SkipLocked}, opts)
+}
+
+func TestLockError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "postgres", err: sqlStateError("55P03"), want: true},
+		{name: "postgresOther", err: sqlStateError("23505")},
+		{name: "mysql", err: errors.New("Error 1205: Lock wait timeout exceeded"), want: true}, //nolint:goerr113
+		{name: "sqlite", err: errors.New("database is locked (5) (SQLITE_BUSY)"), want: true},  //nolint:goerr113
+		{name: "other", err: errors.New("syntax error")},                                       //nolint:goerr113
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lockError(tt.err)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, errors.Is(err, ErrLockTimeout))
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// LockingMode is when a SQLite transaction takes the write lock of the database.
+type LockingMode int
+
+// Locking modes, see https://www.sqlite.org/lang_transaction.html.
+const (
+	// LockDeferred takes locks on first use, the default of BEGIN: a transaction reading before writing
+	// may fail with SQLITE_BUSY when upgrading its lock, other writers getting in between.
+	LockDeferred LockingMode = iota
+	// LockImmediate takes the write lock on begin, like BEGIN IMMEDIATE, waiting for other writers.
+	LockImmediate
+	// LockExclusive takes the exclusive lock on begin, like BEGIN EXCLUSIVE, preventing other readers too
+	// outside of WAL mode.
+	LockExclusive
+)
+
+func (l LockingMode) String() string {
+	switch l {
+	case LockDeferred:
+		return "DEFERRED"
+	case LockImmediate:
+		return "IMMEDIATE"
+	case LockExclusive:
+		return "EXCLUSIVE"
+	}
+
+	return fmt.Sprintf("LockingMode(%d)", int(l))
+}
+
+type lockingKey struct{}
+
+// WithLockingMode begins the read-write transactions of a Manager with given locking mode, SQLite only.
+//
+// database/sql always begins deferred transactions: for another mode, the transaction is rolled back right
+// after its begin, before any lock is taken, and begun again with BEGIN IMMEDIATE or BEGIN EXCLUSIVE
+// on the same connection. Read-only transactions, which never take the write lock, are left deferred.
+func WithLockingMode(mode LockingMode) Option {
+	return func(m *Manager) {
+		m.r.locking = mode
+	}
+}
+
+// SetLockingMode returns a copy of ctx whose transactions are begun with given locking mode,
+// overriding the one of the Manager, see WithLockingMode.
+//
+// Ensure doesn't reuse a transaction of a weaker mode, like a deferred one for LockImmediate.
+func SetLockingMode(ctx context.Context, mode LockingMode) context.Context {
+	return context.WithValue(ctx, lockingKey{}, mode)
+}
+
+// lockingMode returns the locking mode requested by ctx, if any.
+func lockingMode(ctx context.Context) (LockingMode, bool) {
+	mode, found := ctx.Value(lockingKey{}).(LockingMode)
+
+	return mode, found
+}
+
+// lockingMode returns the locking mode of the transaction about to be begun in ctx.
+func (r runner) lockingMode(ctx context.Context, opts *sql.TxOptions) LockingMode {
+	if opts != nil && opts.ReadOnly {
+		return LockDeferred
+	}
+
+	if mode, found := lockingMode(ctx); found {
+		return mode
+	}
+
+	return r.locking
+}
+
+// lock begins tx again with given locking mode, rolling it back on failure.
+func lock(ctx context.Context, tx transaction, mode LockingMode) error {
+	if mode == LockDeferred {
+		return nil
+	}
+
+	err := tx.Exec(ctx, "ROLLBACK")
+	if err == nil {
+		err = tx.Exec(ctx, "BEGIN "+mode.String())
+	}
+
+	if err != nil {
+		_ = tx.Rollback(ctx)
+	}
+
+	return err
+}
+
+// getLocking returns the locking mode of the transaction, LockDeferred if not begun by txx.
+func (s *scope) getLocking() LockingMode {
+	if s == nil {
+		return LockDeferred
+	}
+
+	return s.locking
+}
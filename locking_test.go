@@ -0,0 +1,109 @@
+package txx
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLockingMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := testdb.Open(t, testdb.WithPath(path), testdb.WithJournalMode("DELETE"))
+
+	_, err := db.Exec("CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	// read then write, upgrading the lock
+	upgrade := func(ctx context.Context) error {
+		var n int
+
+		if err := Get(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM person").Scan(&n); err != nil {
+			return err
+		}
+
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", fmt.Sprint(n))
+
+		return err
+	}
+
+	run := func(m *Manager) int32 {
+		var (
+			wg     sync.WaitGroup
+			failed atomic.Int32
+		)
+
+		for range 8 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for range 5 {
+					if m.Wrap(context.Background(), nil, upgrade) != nil {
+						failed.Add(1)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		return failed.Load()
+	}
+
+	t.Logf("deferred transactions failed: %d", run(New(db)))
+	assert.Zero(t, run(New(db, WithLockingMode(LockImmediate))), "immediate transactions should wait instead")
+}
+
+func TestWithLockingMode_lock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := testdb.Open(t, testdb.WithPath(path), testdb.WithJournalMode("DELETE"), testdb.WithBusyTimeout(0))
+	other := testdb.Open(t, testdb.WithPath(path), testdb.WithJournalMode("DELETE"), testdb.WithBusyTimeout(0))
+
+	_, err := db.Exec("CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	insert := func(context.Context) error {
+		_, err := other.Exec("INSERT INTO person (name) VALUES ('Carol')")
+
+		return err
+	}
+
+	require.NoError(t, New(db).Wrap(context.Background(), nil, insert), "deferred transaction should not lock")
+	require.NoError(t, New(db, WithLockingMode(LockImmediate)).Wrap(context.Background(), ReadOnly(), insert),
+		"read-only transaction should be deferred")
+	require.ErrorContains(t, New(db, WithLockingMode(LockImmediate)).Wrap(context.Background(), nil, insert),
+		"locked", "immediate transaction should hold the write lock")
+	require.ErrorContains(t, Wrap(SetLockingMode(context.Background(), LockExclusive), db, nil, insert), "locked")
+}
+
+func TestSetLockingMode_ensure(t *testing.T) {
+	db := testdb.Open(t, testdb.WithPath(filepath.Join(t.TempDir(), "test.db")), testdb.WithJournalMode("DELETE"))
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		deferred := Get(ctx).ID()
+
+		return Ensure(SetLockingMode(ctx, LockImmediate), db, nil, func(ctx context.Context) error {
+			immediate := Get(ctx).ID()
+			assert.NotEqual(t, deferred, immediate, "deferred transaction should not be reused")
+
+			return Ensure(SetLockingMode(ctx, LockImmediate), db, nil, func(ctx context.Context) error {
+				assert.Equal(t, immediate, Get(ctx).ID(), "immediate transaction should be reused")
+
+				return nil
+			})
+		})
+	}))
+}
+
+func TestLockingMode_String(t *testing.T) {
+	assert.Equal(t, "IMMEDIATE", LockImmediate.String())
+	assert.Equal(t, "LockingMode(9)", LockingMode(9).String())
+}
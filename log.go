@@ -0,0 +1,134 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// WithLogger logs the completion of every transaction to given logger,
+// at Info level if committed or a successful dry run, Warn level otherwise, panics being logged before
+// being propagated. Begins and reuses by Ensure are logged at Debug level.
+//
+// Every completed transaction is logged unless a sampling policy is set with WithLogSampling.
+func WithLogger(l *slog.Logger) Option {
+	return func(m *Manager) {
+		m.logger = l
+	}
+}
+
+// WithLogSampling sets the policy deciding if a completed transaction is logged, see WithLogger and SampleLogs.
+func WithLogSampling(sample func(info Info) bool) Option {
+	return func(m *Manager) {
+		m.sample = sample
+	}
+}
+
+// SampleLogs returns a sampling policy logging every transaction not committed or taking at least slow,
+// and the given rate, between 0 and 1, of the other ones.
+//
+// Sampling is deterministic by transaction ID, so that every record of a transaction is kept or dropped alike.
+func SampleLogs(rate float64, slow time.Duration) func(info Info) bool {
+	return func(info Info) bool {
+		if !info.Committed || info.Duration >= slow {
+			return true
+		}
+
+		return sampled(info.ID, rate)
+	}
+}
+
+// sampled reports if given ID falls in the sampled rate, spreading sequential IDs with SplitMix64.
+func sampled(id uint64, rate float64) bool {
+	h := id + 0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return float64(h>>11)/(1<<53) < rate
+}
+
+// log the completion of a transaction, if enabled and sampled.
+func (m *Manager) log(info Info) {
+	if m.logger == nil || (m.sample != nil && !m.sample(info)) {
+		return
+	}
+
+	level, msg := slog.LevelInfo, "transaction committed"
+
+	switch {
+	case info.DryRun:
+		msg = "transaction dry run completed"
+	case !info.Committed:
+		level, msg = slog.LevelWarn, "transaction rolled back"
+	}
+
+	attrs := []slog.Attr{
+		slog.Uint64("tx_id", info.ID),
+		slog.Bool("read_only", info.Opts != nil && info.Opts.ReadOnly),
+		slog.Float64("duration_ms", float64(info.Duration)/float64(time.Millisecond)),
+	}
+
+	if !info.Committed {
+		attrs = append(attrs, slog.String("cause", info.Cause.String()))
+	}
+
+	if len(info.Labels) > 0 {
+		attrs = append(attrs, labelsAttr(info.Labels))
+	}
+
+	if info.Err != nil {
+		attrs = append(attrs, slog.Any("error", info.Err))
+	}
+
+	if info.CallbackErr != nil {
+		attrs = append(attrs, slog.Any("callback_error", info.CallbackErr))
+	}
+
+	m.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// logBegin logs the begin of a transaction, or its reuse by Ensure, at Debug level if enabled.
+func (m *Manager) logBegin(info Info, reused bool) {
+	if m.logger == nil || !m.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	msg := "transaction begun"
+	if reused {
+		msg = "transaction reused"
+	}
+
+	var o sql.TxOptions
+
+	if info.Opts != nil {
+		o = *info.Opts
+	}
+
+	attrs := []slog.Attr{
+		slog.Uint64("tx_id", info.ID),
+		slog.Bool("read_only", o.ReadOnly),
+		slog.String("isolation", o.Isolation.String()),
+		slog.Bool("reused", reused),
+	}
+
+	if len(info.Labels) > 0 {
+		attrs = append(attrs, labelsAttr(info.Labels))
+	}
+
+	m.logger.LogAttrs(context.Background(), slog.LevelDebug, msg, attrs...)
+}
+
+// labelsAttr returns a group of given labels, in key order.
+func labelsAttr(labels map[string]string) slog.Attr {
+	attrs := make([]any, 0, len(labels))
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, slog.String(key, labels[key]))
+	}
+
+	return slog.Group("labels", attrs...)
+}
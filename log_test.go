@@ -0,0 +1,197 @@
+package txx
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a slog.Handler recording every record of at least its level, Info by default.
+type recorder struct {
+	level   slog.Level
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (r *recorder) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= r.level
+}
+
+func (r *recorder) Handle(_ context.Context, record slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, record.Clone())
+
+	return nil
+}
+
+func (r *recorder) WithAttrs(_ []slog.Attr) slog.Handler {
+	return r
+}
+
+func (r *recorder) WithGroup(_ string) slog.Handler {
+	return r
+}
+
+func attrs(record slog.Record) map[string]any {
+	result := make(map[string]any)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		result[attr.Key] = attr.Value.Any()
+
+		return true
+	})
+
+	return result
+}
+
+func TestWithLogger(t *testing.T) {
+	rec := &recorder{}
+	m := newManager(&fakeBackend{}, WithLogger(slog.New(rec)))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+
+	require.Len(t, rec.records, 2)
+
+	assert.Equal(t, slog.LevelInfo, rec.records[0].Level)
+	assert.Equal(t, "transaction committed", rec.records[0].Message)
+	assert.Contains(t, attrs(rec.records[0]), "tx_id")
+	assert.Contains(t, attrs(rec.records[0]), "duration_ms")
+	assert.NotContains(t, attrs(rec.records[0]), "error")
+
+	assert.Equal(t, slog.LevelWarn, rec.records[1].Level)
+	assert.Equal(t, "transaction rolled back", rec.records[1].Message)
+	assert.Equal(t, "error", attrs(rec.records[1])["cause"])
+	assert.EqualError(t, attrs(rec.records[1])["error"].(error), "test") //nolint:forcetypeassert
+}
+
+func TestSampleLogs(t *testing.T) {
+	rec := &recorder{}
+	m := newManager(&fakeBackend{}, WithLogger(slog.New(rec)), WithLogSampling(SampleLogs(0.25, time.Hour)))
+
+	for i := 0; i < 400; i++ {
+		require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	}
+
+	assert.InDelta(t, 100, len(rec.records), 30, "successes should respect the rate")
+
+	rec.records = nil
+
+	for i := 0; i < 50; i++ {
+		require.Error(t, m.Wrap(context.Background(), nil, fail))
+	}
+
+	assert.Len(t, rec.records, 50, "failures should always be logged")
+}
+
+func TestSampleLogs_slow(t *testing.T) {
+	rec := &recorder{}
+	c := clock.NewFake(time.Unix(0, 0))
+	m := newManager(&fakeBackend{}, WithClock(c), WithLogger(slog.New(rec)), WithLogSampling(SampleLogs(0, time.Second)))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	require.NoError(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		c.Advance(time.Second)
+
+		return nil
+	}))
+
+	require.Len(t, rec.records, 1, "only the slow transaction should be logged")
+	assert.InDelta(t, 1000.0, attrs(rec.records[0])["duration_ms"], 0)
+}
+
+func TestSampleLogs_deterministic(t *testing.T) {
+	sample := SampleLogs(0.5, time.Hour)
+
+	for id := uint64(0); id < 100; id++ {
+		info := Info{ID: id, Committed: true}
+
+		assert.Equal(t, sample(info), sample(info))
+		assert.False(t, SampleLogs(0, time.Hour)(info))
+		assert.True(t, SampleLogs(1, time.Hour)(info))
+	}
+}
+
+func TestWithLogger_lifecycle(t *testing.T) {
+	rec := &recorder{level: slog.LevelDebug}
+	m := newManager(&fakeBackend{}, WithLogger(slog.New(rec)))
+
+	require.NoError(t, m.Wrap(context.Background(), Serializable(), func(ctx context.Context) error {
+		return m.Ensure(ctx, ReadOnly(), func(ctx context.Context) error {
+			return m.Ensure(ctx, ReadOnly(), noop)
+		})
+	}))
+
+	messages := make([]string, 0, len(rec.records))
+	for _, record := range rec.records {
+		messages = append(messages, record.Message)
+	}
+
+	assert.Equal(t, []string{
+		"transaction begun",
+		"transaction begun",
+		"transaction reused",
+		"transaction committed",
+		"transaction committed",
+	}, messages)
+	assert.Equal(t, slog.LevelDebug, rec.records[2].Level)
+
+	begun := attrs(rec.records[0])
+	assert.Equal(t, false, begun["read_only"])
+	assert.Equal(t, "Serializable", begun["isolation"])
+	assert.Equal(t, false, begun["reused"])
+
+	reused := attrs(rec.records[2])
+	assert.Equal(t, true, reused["read_only"])
+	assert.Equal(t, true, reused["reused"])
+	assert.Equal(t, attrs(rec.records[1])["tx_id"], reused["tx_id"])
+}
+
+func TestWithLogger_panic(t *testing.T) {
+	rec := &recorder{}
+	m := newManager(&fakeBackend{}, WithLogger(slog.New(rec)))
+
+	require.PanicsWithValue(t, "test", func() {
+		_ = m.Wrap(context.Background(), ReadOnly(), func(_ context.Context) error {
+			panic("test")
+		})
+	})
+
+	require.Len(t, rec.records, 1, "the panic should be logged before being propagated")
+	assert.Equal(t, slog.LevelWarn, rec.records[0].Level)
+	assert.Equal(t, "panic", attrs(rec.records[0])["cause"])
+	assert.Equal(t, true, attrs(rec.records[0])["read_only"])
+	assert.Contains(t, attrs(rec.records[0]), "duration_ms")
+	assert.EqualError(t, attrs(rec.records[0])["error"].(error), "panic: test") //nolint:forcetypeassert
+}
+
+func TestWithLogger_labels(t *testing.T) {
+	rec := &recorder{level: slog.LevelDebug}
+	m := newManager(&fakeBackend{}, WithLogger(slog.New(rec)), WithLabelsFromContext(
+		func(_ context.Context) map[string]string {
+			return map[string]string{"request_id": "42", "endpoint": "/orders"}
+		},
+	))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return m.Ensure(ctx, nil, noop)
+	}))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+
+	require.Len(t, rec.records, 5)
+
+	for _, record := range rec.records {
+		assert.Equal(t, []slog.Attr{
+			slog.String("endpoint", "/orders"),
+			slog.String("request_id", "42"),
+		}, attrs(record)["labels"], record.Message)
+	}
+}
@@ -0,0 +1,409 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+// ErrShuttingDown is returned when a new transaction is requested from a Manager being shut down.
+var ErrShuttingDown = errors.New("txx: manager is shutting down")
+
+// Manager binds a database and tracks the transactions it begins.
+//
+// A Manager is safe for concurrent use.
+type Manager struct {
+	db      *sql.DB
+	r       runner
+	clock   Clock
+	breaker *breaker
+	finally func(info Info)
+	// skipReadOnly runs read-only work without a transaction.
+	skipReadOnly bool
+	// defaults are the options of transactions, see WithDefaultOptions.
+	defaults *sql.TxOptions
+	// compatible overrides the reuse decision of current transactions, see WithCompatibility.
+	compatible Compatibility
+	logger     *slog.Logger
+	sample     func(info Info) bool
+	overflow   Overflow
+	nested     NestedWrapPolicy
+	// strictReuse rejects Ensure beginning a second transaction, see WithStrictReuse.
+	strictReuse bool
+
+	mu                sync.Mutex
+	interceptors      []Interceptor
+	run               RunFunc
+	reuseInterceptors []Interceptor
+	reuse             RunFunc
+	active            map[uint64]*active // by transaction ID
+	pending           int
+	shutdown          bool
+	forced            bool
+	drained           chan struct{}
+	committed         uint64
+	rolledBack        map[RollbackCause]uint64
+
+	subsMu sync.RWMutex
+	subs   map[*subscriber]struct{}
+}
+
+// active transaction of a Manager.
+type active struct {
+	tx transaction
+	// cancel the context of the transaction function with the cause of a forced rollback.
+	cancel context.CancelCauseFunc
+	// forced reports if the transaction was forcibly rolled back.
+	forced bool
+}
+
+// Option configures a Manager.
+type Option func(m *Manager)
+
+// Stats of a Manager.
+type Stats struct {
+	// Active is the number of transactions currently open.
+	Active int
+	// Circuit is the state of the circuit breaker, CircuitClosed if not enabled.
+	Circuit CircuitState
+	// Committed is the number of committed transactions.
+	Committed uint64
+	// RolledBack is the number of rolled back transactions by cause.
+	RolledBack map[RollbackCause]uint64
+}
+
+// WithFinally registers a callback invoked with the Info of every completed transaction.
+func WithFinally(f func(info Info)) Option {
+	return func(m *Manager) {
+		m.finally = f
+	}
+}
+
+// WithDefaultIsolation declares the isolation level LevelDefault resolves to with the connected driver,
+// like LevelReadCommitted for PostgreSQL.
+//
+// Ensure then reuses a transaction begun with the default level when the declared level is requested,
+// and conversely, including the package-level Ensure within the transactions of the Manager.
+// Without a declaration, LevelDefault is unknown, see Current.NewTransactionRequired.
+func WithDefaultIsolation(level sql.IsolationLevel) Option {
+	return func(m *Manager) {
+		m.r.isolation = level
+	}
+}
+
+// WithDefaultOptions sets the options of the transactions of a Manager, used when nil options are given.
+//
+// Given options are merged with them: a LevelDefault isolation is replaced by the default one,
+// while ReadOnly is always taken from given options, see EnsureReadOnly.
+func WithDefaultOptions(opts *sql.TxOptions) Option {
+	return func(m *Manager) {
+		if opts != nil {
+			defaults := *opts
+			m.defaults = &defaults
+		}
+	}
+}
+
+// New returns a Manager for given database.
+func New(db *sql.DB, opts ...Option) *Manager {
+	m := newManager(sqlBeginner{db: db}, opts...)
+	m.db = db
+
+	return m
+}
+
+func newManager(b beginner, opts ...Option) *Manager {
+	m := &Manager{
+		r:          runner{b: b, strict: strictFromEnv()},
+		clock:      clock.Real{},
+		active:     make(map[uint64]*active),
+		drained:    make(chan struct{}),
+		rolledBack: make(map[RollbackCause]uint64),
+		subs:       make(map[*subscriber]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.breaker != nil {
+		m.breaker.clock = m.clock
+		m.r.b = breakerBeginner{beginner: m.r.b, breaker: m.breaker}
+	}
+
+	m.r.begun = m.begun
+	m.r.finish = m.finish
+	m.r.clock = m.clock
+	m.Use(m.gate)
+	m.UseOnReuse()
+
+	return m
+}
+
+// Stats returns the Manager statistics.
+func (m *Manager) Stats() Stats {
+	m.mu.Lock()
+	result := Stats{
+		Active:     len(m.active),
+		Committed:  m.committed,
+		RolledBack: make(map[RollbackCause]uint64, len(m.rolledBack)),
+	}
+
+	for cause, n := range m.rolledBack {
+		result.RolledBack[cause] = n
+	}
+	m.mu.Unlock()
+
+	if m.breaker != nil {
+		result.Circuit = m.breaker.State()
+	}
+
+	return result
+}
+
+// Ensure function f run in a transaction with given options.
+//
+// See the package-level Ensure function and SkipTxForReadOnly.
+func (m *Manager) Ensure(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	opts = m.options(opts)
+	current := Get(ctx)
+	if m.transactionRequired(ctx, opts) {
+		if m.skipReadOnly && !current.IsValid() && opts != nil && opts.ReadOnly {
+			return f(ctx)
+		}
+
+		if err := m.checkIncompatible(ctx, opts); err != nil {
+			return err
+		}
+
+		return m.wrap(ctx, opts, f)
+	}
+
+	return m.reuseTx(ctx, opts, reusing(f))
+}
+
+// EnsureReadOnly is Ensure with the default options, see WithDefaultOptions, but read-only.
+func (m *Manager) EnsureReadOnly(ctx context.Context, f func(ctx context.Context) error) error {
+	opts := ReadOnly()
+	if m.defaults != nil {
+		opts.Isolation = m.defaults.Isolation
+	}
+
+	return m.Ensure(ctx, opts, f)
+}
+
+// Wrap function f in a new transaction with given options.
+//
+// See the package-level Wrap function.
+// Once Shutdown has been called, Wrap fails with ErrShuttingDown.
+// Interceptors added by Use run around the transaction.
+// See WithNestedWrapPolicy for calls in a transaction.
+func (m *Manager) Wrap(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	ctx, err := m.checkNested(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.wrap(ctx, m.options(opts), f)
+}
+
+// options returns given options merged with the default ones, see WithDefaultOptions.
+func (m *Manager) options(opts *sql.TxOptions) *sql.TxOptions {
+	if m.defaults == nil {
+		return opts
+	}
+
+	result := *m.defaults
+
+	if opts != nil {
+		result = *opts
+
+		if result.Isolation == sql.LevelDefault {
+			result.Isolation = m.defaults.Isolation
+		}
+	}
+
+	return &result
+}
+
+func (m *Manager) wrap(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	m.mu.Lock()
+	run := m.run
+	m.mu.Unlock()
+
+	return run(ctx, m.db, opts, f)
+}
+
+// reuseTx runs function f in the current transaction, see UseOnReuse.
+func (m *Manager) reuseTx(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	m.mu.Lock()
+	reuse := m.reuse
+	m.mu.Unlock()
+
+	return reuse(ctx, m.db, opts, f)
+}
+
+// Shutdown stops the Manager from beginning new transactions and waits for active ones to complete.
+//
+// If given context expires first, remaining transactions are rolled back and the context error is returned.
+// The contexts of their functions are canceled right before, context.Cause reporting ErrShuttingDown.
+// Reusing an already active transaction with Ensure is still allowed while draining.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.shutdown {
+		m.shutdown = true
+
+		if m.pending == 0 {
+			close(m.drained)
+		}
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	m.forced = true
+	actives := make([]*active, 0, len(m.active))
+
+	for _, a := range m.active {
+		a.forced = true
+		actives = append(actives, a)
+	}
+	m.mu.Unlock()
+
+	// rolled back without the lock, which the completion of the transactions takes
+	for _, a := range actives {
+		if a.cancel != nil {
+			a.cancel(ErrShuttingDown)
+		}
+
+		_ = a.tx.Rollback(ctx)
+	}
+
+	return ctx.Err()
+}
+
+// Prewarm concurrently establishes n connections of the database pool, at most its MaxOpenConns,
+// and returns them to the pool, so that first transactions don't pay for connecting.
+//
+// Connections beyond the MaxIdleConns of the database are closed right away.
+// Failures are joined in the returned error. Nothing is done if n is not positive.
+func (m *Manager) Prewarm(ctx context.Context, n int) error {
+	if m.db == nil || n <= 0 {
+		return nil
+	}
+
+	if limit := m.db.Stats().MaxOpenConnections; limit > 0 && n > limit {
+		n = limit
+	}
+
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			conns[i], errs[i] = m.db.Conn(ctx)
+			if errs[i] == nil {
+				errs[i] = conns[i].PingContext(ctx)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn != nil {
+			errs = append(errs, conn.Close())
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("txx: prewarming connections: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) acquire() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shutdown {
+		return ErrShuttingDown
+	}
+
+	m.pending++
+
+	return nil
+}
+
+func (m *Manager) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending--
+
+	if m.shutdown && m.pending == 0 {
+		close(m.drained)
+	}
+}
+
+func (m *Manager) register(id uint64, tx transaction, cancel context.CancelCauseFunc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.forced {
+		return ErrShuttingDown
+	}
+
+	m.active[id] = &active{tx: tx, cancel: cancel}
+
+	return nil
+}
+
+func (m *Manager) finish(c *completion) {
+	info := m.count(c)
+
+	if m.finally != nil {
+		m.finally(info)
+	}
+
+	m.log(info)
+	m.completed(info, c.retrying)
+}
+
+// count the completion of the transaction described by c, returning its Info.
+func (m *Manager) count(c *completion) Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a, found := m.active[c.id]; found {
+		c.forced = a.forced
+	}
+
+	delete(m.active, c.id)
+
+	info := c.info()
+	if info.Committed {
+		m.committed++
+	} else if info.Cause != CauseNone {
+		m.rolledBack[info.Cause]++
+	}
+
+	return info
+}
@@ -0,0 +1,310 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gated starts a Wrap in background whose function blocks until the returned gate is closed.
+func gated(t *testing.T, m *Manager) (chan<- struct{}, <-chan error) {
+	t.Helper()
+
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	result := make(chan error, 1)
+
+	go func() {
+		result <- m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			close(started)
+			<-gate
+
+			_, err := Get(ctx).Tx.ExecContext(ctx, "SELECT 1")
+
+			return err
+		})
+	}()
+
+	<-started
+
+	return gate, result
+}
+
+func TestManager_Ensure(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+	tx := &sql.Tx{}
+
+	require.NoError(t, m.Ensure(context.Background(), nil, checkTxExists))
+	require.NoError(t, m.Ensure(Set(context.Background(), tx, nil), nil, checkTxEquals(tx)))
+	require.Error(t, m.Ensure(context.Background(), nil, fail))
+}
+
+func TestManager_Wrap(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+}
+
+func TestManager_Shutdown(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+	gate, result := gated(t, m)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- m.Shutdown(context.Background())
+	}()
+
+	require.Eventually(t, func() bool {
+		return m.Wrap(context.Background(), nil, checkTxExists) != nil
+	}, time.Second, time.Millisecond)
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, checkTxExists), ErrShuttingDown)
+	require.ErrorIs(t, m.Ensure(context.Background(), nil, checkTxExists), ErrShuttingDown)
+
+	select {
+	case <-done:
+		t.Fatal("shutdown should wait for active transaction")
+	default:
+	}
+
+	close(gate)
+
+	require.NoError(t, <-result)
+	require.NoError(t, <-done)
+}
+
+func TestManager_Shutdown_reuse(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	result := make(chan error, 1)
+
+	go func() {
+		result <- m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			close(started)
+			<-gate
+
+			return m.Ensure(ctx, nil, checkTxExists)
+		})
+	}()
+
+	<-started
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- m.Shutdown(context.Background())
+	}()
+
+	require.Eventually(t, func() bool {
+		return m.Wrap(context.Background(), nil, checkTxExists) != nil
+	}, time.Second, time.Millisecond)
+
+	close(gate)
+
+	require.NoError(t, <-result)
+	require.NoError(t, <-done)
+}
+
+func TestManager_Shutdown_timeout(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+	gate, result := gated(t, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, m.Shutdown(ctx), context.Canceled)
+
+	close(gate)
+
+	assert.ErrorIs(t, <-result, context.Canceled)
+}
+
+func TestManager_Shutdown_cause(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+	started := make(chan struct{})
+	result := make(chan error, 1)
+
+	go func() {
+		result <- m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return context.Cause(ctx)
+		})
+	}()
+
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, m.Shutdown(ctx), context.Canceled)
+	assert.ErrorIs(t, <-result, ErrShuttingDown)
+}
+
+func TestManager_Shutdown_idle(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.ErrorIs(t, m.Wrap(context.Background(), nil, checkTxExists), ErrShuttingDown)
+}
+
+// countingDB returns a database whose connections are counted, see countCalls.
+func countingDB(t *testing.T) (*sql.DB, *fakedb.Driver) {
+	t.Helper()
+
+	d := &fakedb.Driver{}
+
+	return fakedb.Open(t, d), d
+}
+
+func TestManager_Prewarm(t *testing.T) {
+	db, d := countingDB(t)
+	db.SetMaxIdleConns(4)
+
+	require.NoError(t, New(db).Prewarm(context.Background(), 3))
+
+	assert.Equal(t, int32(3), countCalls(d, "connect"))
+	assert.Equal(t, 0, db.Stats().InUse)
+	assert.Equal(t, 3, db.Stats().Idle)
+
+	require.NoError(t, New(db).Prewarm(context.Background(), 2))
+	assert.Equal(t, int32(3), countCalls(d, "connect"), "idle connections should be reused")
+}
+
+func TestManager_Prewarm_maxOpenConns(t *testing.T) {
+	db, d := countingDB(t)
+	db.SetMaxOpenConns(2)
+
+	require.NoError(t, New(db).Prewarm(context.Background(), 5))
+
+	assert.Equal(t, int32(2), countCalls(d, "connect"))
+	assert.Equal(t, 0, db.Stats().InUse)
+}
+
+func TestManager_Prewarm_notPositive(t *testing.T) {
+	db, d := countingDB(t)
+
+	require.NoError(t, New(db).Prewarm(context.Background(), 0))
+	require.NoError(t, New(db).Prewarm(context.Background(), -1))
+
+	assert.Equal(t, int32(0), countCalls(d, "connect"))
+}
+
+func TestManager_Prewarm_canceled(t *testing.T) {
+	db, d := countingDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, New(db).Prewarm(ctx, 3), context.Canceled)
+
+	assert.Equal(t, int32(0), countCalls(d, "connect"))
+	assert.Equal(t, 0, db.Stats().InUse)
+}
+
+func TestManager_Ensure_defaultIsolation(t *testing.T) {
+	tx := &sql.Tx{}
+	ctx := Set(context.Background(), tx, nil)
+	readCommitted := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+
+	m := New(testdb.Open(t, inMemory), WithDefaultIsolation(sql.LevelReadCommitted))
+
+	require.NoError(t, m.Ensure(ctx, readCommitted, checkTxEquals(tx)))
+	require.Error(t, New(testdb.Open(t, inMemory)).Ensure(ctx, readCommitted, checkTxEquals(tx)))
+}
+
+func TestManager_defaultIsolation_packageLevel(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	readCommitted := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+
+	require.NoError(t, New(db, WithDefaultIsolation(sql.LevelReadCommitted)).Wrap(
+		context.Background(), nil, func(ctx context.Context) error {
+			assert.False(t, Get(ctx).NewTransactionRequired(readCommitted))
+
+			return Ensure(ctx, db, readCommitted, checkTxEquals(Get(ctx).Tx))
+		},
+	))
+
+	require.NoError(t, New(db).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		assert.True(t, Get(ctx).NewTransactionRequired(readCommitted), "LevelDefault should be unknown")
+
+		return nil
+	}))
+}
+
+func TestManager_defaultOptions(t *testing.T) {
+	serializable := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	tests := []struct {
+		name     string
+		defaults *sql.TxOptions
+		opts     *sql.TxOptions
+		want     *sql.TxOptions
+	}{
+		{name: "none", opts: ReadOnly(), want: ReadOnly()},
+		{name: "nil", defaults: SerializableReadOnly(), want: SerializableReadOnly()},
+		{name: "merged", defaults: serializable, opts: ReadOnly(), want: SerializableReadOnly()},
+		{
+			name:     "override",
+			defaults: SerializableReadOnly(),
+			opts:     &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+			want:     &sql.TxOptions{Isolation: sql.LevelReadCommitted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(&fakeBackend{}, WithDefaultOptions(tt.defaults))
+
+			require.NoError(t, m.Ensure(context.Background(), tt.opts, func(ctx context.Context) error {
+				assert.Equal(t, tt.want, Get(ctx).Opts, "Ensure")
+
+				return nil
+			}))
+
+			require.NoError(t, m.Wrap(context.Background(), tt.opts, func(ctx context.Context) error {
+				assert.Equal(t, tt.want, Get(ctx).Opts, "Wrap")
+
+				return nil
+			}))
+		})
+	}
+}
+
+func TestManager_EnsureReadOnly(t *testing.T) {
+	serializable := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	require.NoError(t, newManager(&fakeBackend{}, WithDefaultOptions(serializable)).EnsureReadOnly(
+		context.Background(),
+		func(ctx context.Context) error {
+			assert.Equal(t, SerializableReadOnly(), Get(ctx).Opts)
+
+			return nil
+		},
+	))
+
+	require.NoError(t, newManager(&fakeBackend{}).EnsureReadOnly(context.Background(), func(ctx context.Context) error {
+		assert.Equal(t, ReadOnly(), Get(ctx).Opts)
+
+		return nil
+	}))
+
+	m := newManager(&fakeBackend{}, WithDefaultOptions(serializable))
+	serializable.ReadOnly = true
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		assert.Equal(t, &sql.TxOptions{Isolation: sql.LevelSerializable}, Get(ctx).Opts, "defaults should be copied")
+
+		return nil
+	}))
+}
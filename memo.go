@@ -0,0 +1,95 @@
+package txx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// WithMemoizedSelects memoizes the results of the Select and QueryOne helpers within each transaction,
+// so that an identical query, with the same SQL, arguments and result type, doesn't reach the database again.
+//
+// Memoized results are cleared by any write executed through the Current methods, the DB helpers
+// and ExecReturning, as well as by rolling back a savepoint. Writes hidden in queries, or concurrent
+// transactions committing, are not seen by memoized queries: only enable it for transactions reading
+// data they don't modify otherwise, at REPEATABLE READ or above for strict consistency.
+//
+// Arguments are compared by their Go syntax representation, so pointer arguments never match.
+// At most maxRows rows are memoized per transaction, further results not being memoized.
+func WithMemoizedSelects(maxRows int) Option {
+	return func(m *Manager) {
+		m.r.memoRows = maxRows
+	}
+}
+
+type memoKey struct {
+	typ   reflect.Type
+	one   bool
+	query string
+	args  string
+}
+
+// memoized returns the memoized result of given query if any, otherwise runs it with f and memoizes its result.
+func memoized[T any](ctx context.Context, one bool, query string, args []any, f func() ([]T, error)) ([]T, error) {
+	s := Get(ctx).s
+	if s == nil || s.memoLimit <= 0 {
+		return f()
+	}
+
+	key := memoKey{
+		typ:   reflect.TypeOf((*T)(nil)).Elem(),
+		one:   one,
+		query: query,
+		args:  fmt.Sprintf("%#v", args),
+	}
+
+	if cached, found := s.memoized(key); found {
+		return slices.Clone(cached.([]T)), nil //nolint:forcetypeassert
+	}
+
+	result, err := f()
+	if err == nil {
+		s.memoize(key, slices.Clone(result), len(result))
+	}
+
+	return result, err
+}
+
+func (s *scope) memoized(key memoKey) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, found := s.memo[key]
+
+	return result, found
+}
+
+func (s *scope) memoize(key memoKey, result any, rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.memoSize+rows > s.memoLimit {
+		return
+	}
+
+	if s.memo == nil {
+		s.memo = make(map[memoKey]any)
+	}
+
+	s.memo[key] = result
+	s.memoSize += rows
+}
+
+// invalidate clears memoized results, the transaction being written.
+func (s *scope) invalidate() {
+	if s == nil || s.memoLimit <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memo = nil
+	s.memoSize = 0
+}
@@ -0,0 +1,126 @@
+package txx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorded returns the number of statements recorded in the transaction of ctx.
+func recorded(ctx context.Context) int {
+	s := Get(ctx).s
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count
+}
+
+func TestWithMemoizedSelects(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithStatementContext(), WithMemoizedSelects(10))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		names, err := Select[string](ctx, db, "SELECT name FROM person ORDER BY id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob"}, names)
+
+		names[0] = "changed"
+
+		names, err = Select[string](ctx, db, "SELECT name FROM person ORDER BY id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob"}, names, "memoized results should not be shared")
+		assert.Equal(t, 1, recorded(ctx), "identical query should be memoized")
+
+		name, err := QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", name)
+
+		_, err = QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, recorded(ctx))
+
+		_, err = QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, recorded(ctx), "different arguments should not match")
+
+		_, err = QueryOne[int64](ctx, db, "SELECT name FROM person WHERE id = ?", 1)
+		require.Error(t, err, "different result type should not match")
+
+		_, err = NewDB(db).ExecContext(ctx, "UPDATE person SET name = 'Carol' WHERE id = 1")
+		require.NoError(t, err)
+
+		name, err = QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Carol", name, "write should clear memoized results")
+
+		return nil
+	}))
+}
+
+func TestWithMemoizedSelects_savepoint(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithMemoizedSelects(10))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		tx, err := NewDB(db).BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = tx.ExecContext(ctx, "UPDATE person SET name = 'Carol' WHERE id = 1")
+		require.NoError(t, err)
+
+		name, err := QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = 1")
+		require.NoError(t, err)
+		assert.Equal(t, "Carol", name)
+
+		require.NoError(t, tx.Rollback())
+
+		name, err = QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = 1")
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", name, "savepoint rollback should clear memoized results")
+
+		return nil
+	}))
+}
+
+func TestWithMemoizedSelects_limit(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithStatementContext(), WithMemoizedSelects(1))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		for i := 0; i < 2; i++ {
+			_, err := Select[string](ctx, db, "SELECT name FROM person")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, recorded(ctx), "results beyond the limit should not be memoized")
+
+		for i := 0; i < 2; i++ {
+			_, err := QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = 1")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 3, recorded(ctx))
+
+		return nil
+	}))
+}
+
+func TestWithMemoizedSelects_disabled(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, WithStatementContext())
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		for i := 0; i < 2; i++ {
+			_, err := Select[string](ctx, db, "SELECT name FROM person")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, recorded(ctx))
+
+		return nil
+	}))
+}
@@ -0,0 +1,168 @@
+package txx
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TxOutcome categorizes how a transaction, or a function run in a reused one, finished.
+//
+// Its String value is suitable as a metrics label.
+type TxOutcome int
+
+// Transaction outcomes.
+const (
+	// OutcomeCommitted means the transaction was committed successfully.
+	OutcomeCommitted TxOutcome = iota
+	// OutcomeRolledBack means the transaction was rolled back, see Info.Cause.
+	OutcomeRolledBack
+	// OutcomeCommitFailed means the commit of the transaction failed.
+	OutcomeCommitFailed
+	// OutcomeReused means the function ran in a reused transaction, which completes later.
+	OutcomeReused
+)
+
+func (o TxOutcome) String() string {
+	switch o {
+	case OutcomeCommitted:
+		return "committed"
+	case OutcomeRolledBack:
+		return "rolled_back"
+	case OutcomeCommitFailed:
+		return "commit_failed"
+	case OutcomeReused:
+		return "reused"
+	}
+
+	return fmt.Sprintf("TxOutcome(%d)", int(o))
+}
+
+// MetricsRecorder records transaction metrics, without txx depending on a metrics library.
+//
+// TxStarted is called once a transaction is begun, or when Ensure reuses the current one,
+// and TxFinished once it is completed, or once the function run in the reused transaction returns,
+// even if it panicked. Transactions failing to begin are not recorded.
+//
+// Both are given the labels of the transaction, see WithLabelsFromContext, which must not be modified.
+//
+// A MetricsRecorder must be safe for concurrent use.
+type MetricsRecorder interface {
+	TxStarted(reused bool, opts *sql.TxOptions, labels map[string]string)
+	// TxFinished is given the cause of the rollback, CauseNone in a reused transaction, the duration
+	// of the transaction, or of the function in a reused one, and the error which caused the rollback
+	// or the commit error.
+	TxFinished(outcome TxOutcome, cause RollbackCause, d time.Duration, err error, labels map[string]string)
+}
+
+// Recorders returns a MetricsRecorder recording to every given one, in order.
+func Recorders(rs ...MetricsRecorder) MetricsRecorder {
+	return recorders(nil).with(rs...)
+}
+
+// WithMetrics records the transactions of a Manager to given recorders,
+// in addition to the ones of previous calls and the global one, see SetGlobalMetricsRecorder.
+func WithMetrics(rs ...MetricsRecorder) Option {
+	return func(m *Manager) {
+		m.r.metrics = m.r.metrics.with(rs...)
+	}
+}
+
+type globalRecorder struct {
+	r MetricsRecorder
+}
+
+var globalMetrics atomic.Pointer[globalRecorder] //nolint:gochecknoglobals
+
+// SetGlobalMetricsRecorder records every transaction to given recorder, none if nil,
+// those of package-level functions like Wrap and Ensure as well as the ones of every Manager.
+func SetGlobalMetricsRecorder(r MetricsRecorder) {
+	if r == nil {
+		globalMetrics.Store(nil)
+	} else {
+		globalMetrics.Store(&globalRecorder{r: r})
+	}
+}
+
+// recorders is a composite MetricsRecorder.
+type recorders []MetricsRecorder
+
+// with returns a copy of rs with given recorders appended, nil ones being skipped.
+func (rs recorders) with(others ...MetricsRecorder) recorders {
+	result := append(recorders(nil), rs...)
+
+	for _, r := range others {
+		if r != nil {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+// withGlobal returns rs preceded by the global recorder, if any.
+func (rs recorders) withGlobal() recorders {
+	if g := globalMetrics.Load(); g != nil {
+		return append(recorders{g.r}, rs...)
+	}
+
+	return rs
+}
+
+func (rs recorders) TxStarted(reused bool, opts *sql.TxOptions, labels map[string]string) {
+	for _, r := range rs {
+		r.TxStarted(reused, opts, labels)
+	}
+}
+
+func (rs recorders) TxFinished(
+	outcome TxOutcome,
+	cause RollbackCause,
+	d time.Duration,
+	err error,
+	labels map[string]string,
+) {
+	for _, r := range rs {
+		r.TxFinished(outcome, cause, d, err, labels)
+	}
+}
+
+// outcome of the transaction described by c.
+func (c completion) outcome() TxOutcome {
+	switch {
+	case c.committed:
+		return OutcomeCommitted
+	case c.rolledBack:
+		return OutcomeRolledBack
+	}
+
+	return OutcomeCommitFailed
+}
+
+// runReused runs function f in a reused transaction with given options and labels, recording it to rs.
+func runReused(rs recorders, c Clock, opts *sql.TxOptions, labels map[string]string, f func() error) (err error) {
+	rs = rs.withGlobal()
+	if len(rs) == 0 {
+		return f()
+	}
+
+	rs.TxStarted(true, opts, labels)
+
+	start := c.Now()
+
+	defer func() {
+		p := recover()
+		if p != nil {
+			err = &PanicError{Value: p}
+		}
+
+		rs.TxFinished(OutcomeReused, CauseNone, c.Now().Sub(start), err, labels)
+
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return f()
+}
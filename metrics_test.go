@@ -0,0 +1,185 @@
+package txx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/MartyHub/txx/txxtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMetrics = errors.New("metrics") //nolint:gochecknoglobals
+
+func TestWithMetrics(t *testing.T) {
+	first, second := &txxtest.Metrics{}, &txxtest.Metrics{}
+	m := txx.New(txxtest.NewDB(t), txx.WithMetrics(first), txx.WithMetrics(nil, second))
+
+	require.NoError(t, m.Wrap(context.Background(), txx.Serializable(), func(ctx context.Context) error {
+		require.ErrorIs(t, m.Ensure(ctx, nil, func(_ context.Context) error {
+			return errMetrics
+		}), errMetrics)
+
+		return nil
+	}))
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		return errMetrics
+	}), errMetrics)
+
+	for _, metrics := range []*txxtest.Metrics{first, second} {
+		assert.Equal(t, []txxtest.Started{
+			{Opts: txx.Serializable()},
+			{Reused: true, Opts: nil},
+			{},
+		}, metrics.Started())
+
+		finished := metrics.Finished()
+		require.Len(t, finished, 3)
+
+		assert.Equal(t, txx.OutcomeReused, finished[0].Outcome)
+		assert.Equal(t, txx.CauseNone, finished[0].Cause)
+		require.ErrorIs(t, finished[0].Err, errMetrics)
+		assert.Equal(t, txx.OutcomeCommitted, finished[1].Outcome)
+		assert.Equal(t, txx.CauseNone, finished[1].Cause)
+		require.NoError(t, finished[1].Err)
+		assert.Equal(t, txx.OutcomeRolledBack, finished[2].Outcome)
+		assert.Equal(t, txx.CauseError, finished[2].Cause)
+		require.ErrorIs(t, finished[2].Err, errMetrics)
+	}
+}
+
+func TestWithMetrics_panic(t *testing.T) {
+	metrics := &txxtest.Metrics{}
+	m := txx.New(txxtest.NewDB(t), txx.WithMetrics(metrics))
+
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			return m.Ensure(ctx, nil, func(_ context.Context) error {
+				panic("boom")
+			})
+		})
+	})
+
+	finished := metrics.Finished()
+	require.Len(t, finished, 2)
+
+	var panicErr *txx.PanicError
+
+	for i, outcome := range []txx.TxOutcome{txx.OutcomeReused, txx.OutcomeRolledBack} {
+		assert.Equal(t, outcome, finished[i].Outcome)
+		require.ErrorAs(t, finished[i].Err, &panicErr)
+		assert.Equal(t, "boom", panicErr.Value)
+	}
+
+	assert.Equal(t, txx.CausePanic, finished[1].Cause)
+}
+
+func TestWithMetrics_labels(t *testing.T) {
+	type endpointKey struct{}
+
+	metrics := &txxtest.Metrics{}
+	m := txx.New(txxtest.NewDB(t), txx.WithMetrics(metrics), txx.WithLabelsFromContext(
+		func(ctx context.Context) map[string]string {
+			endpoint, _ := ctx.Value(endpointKey{}).(string)
+
+			return map[string]string{"endpoint": endpoint}
+		},
+	))
+	ctx := context.WithValue(context.Background(), endpointKey{}, "/orders")
+	labels := map[string]string{"endpoint": "/orders"}
+
+	require.NoError(t, m.Wrap(ctx, nil, func(ctx context.Context) error {
+		return m.Ensure(context.WithValue(ctx, endpointKey{}, "/other"), nil, func(_ context.Context) error {
+			return nil
+		})
+	}))
+
+	require.ErrorIs(t, m.Wrap(ctx, nil, func(_ context.Context) error {
+		return errMetrics
+	}), errMetrics)
+
+	assert.Equal(t, []txxtest.Started{
+		{Labels: labels},
+		{Reused: true, Labels: labels},
+		{Labels: labels},
+	}, metrics.Started(), "reuses should carry the labels of the transaction")
+
+	for _, finished := range metrics.Finished() {
+		assert.Equal(t, labels, finished.Labels, finished.Outcome.String())
+	}
+}
+
+func TestWithMetrics_reuse(t *testing.T) {
+	metrics := &txxtest.Metrics{}
+	m := txx.New(txxtest.NewDB(t), txx.WithMetrics(metrics))
+	noop := func(_ context.Context) error {
+		return nil
+	}
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, m.EnsureWith(ctx, nil, txx.PropagationMandatory, noop))
+		require.NoError(t, m.EnsureWith(ctx, nil, txx.PropagationSupports, noop))
+		require.NoError(t, m.EnsureWith(ctx, nil, txx.PropagationNotSupported, noop))
+
+		return m.Nested(ctx, nil, noop)
+	}))
+
+	require.NoError(t, m.EnsureWith(context.Background(), nil, txx.PropagationSupports, noop))
+
+	assert.Equal(t, 3, metrics.Count(txx.OutcomeReused), "Mandatory, Supports and Nested should reuse")
+	assert.Equal(t, 1, metrics.Count(txx.OutcomeCommitted))
+}
+
+func TestSetGlobalMetricsRecorder(t *testing.T) {
+	global, local := &txxtest.Metrics{}, &txxtest.Metrics{}
+	db := txxtest.NewDB(t)
+
+	txx.SetGlobalMetricsRecorder(global)
+	t.Cleanup(func() {
+		txx.SetGlobalMetricsRecorder(nil)
+	})
+
+	require.NoError(t, txx.Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		return txx.Ensure(ctx, db, nil, func(_ context.Context) error {
+			return nil
+		})
+	}))
+
+	require.NoError(t, txx.New(db, txx.WithMetrics(local)).Wrap(context.Background(), nil, func(_ context.Context) error {
+		return nil
+	}))
+
+	assert.Equal(t, []txxtest.Started{{}, {Reused: true}, {}}, global.Started())
+	assert.Equal(t, 1, global.Count(txx.OutcomeReused))
+	assert.Equal(t, 2, global.Count(txx.OutcomeCommitted))
+	assert.Equal(t, []txxtest.Started{{}}, local.Started())
+
+	txx.SetGlobalMetricsRecorder(nil)
+
+	require.NoError(t, txx.Wrap(context.Background(), db, nil, func(_ context.Context) error {
+		return nil
+	}))
+
+	assert.Len(t, global.Finished(), 3)
+}
+
+func TestRecorders(t *testing.T) {
+	first, second := &txxtest.Metrics{}, &txxtest.Metrics{}
+	r := txx.Recorders(first, nil, second)
+
+	r.TxStarted(false, txx.ReadOnly(), nil)
+	r.TxFinished(txx.OutcomeRolledBack, txx.CauseCanceled, 0, context.Canceled, nil)
+
+	for _, metrics := range []*txxtest.Metrics{first, second} {
+		assert.Equal(t, []txxtest.Started{{Opts: txx.ReadOnly()}}, metrics.Started())
+		assert.Equal(t, []txxtest.Finished{
+			{Outcome: txx.OutcomeRolledBack, Cause: txx.CauseCanceled, Err: context.Canceled},
+		}, metrics.Finished())
+	}
+
+	assert.Equal(t, "rolled_back", txx.OutcomeRolledBack.String())
+	assert.Equal(t, "TxOutcome(42)", txx.TxOutcome(42).String())
+}
@@ -0,0 +1,77 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// namedKey is the context key of the transaction slot of a name, see SetNamed.
+type namedKey string
+
+// SetNamed is Set for the transaction slot of given name, independent of the other slots.
+//
+// Slots let the transactions of several databases be current in the same context, one slot per database.
+// The empty name is the default slot of Set, Get and Ensure.
+func SetNamed(ctx context.Context, name string, tx *sql.Tx, opts *sql.TxOptions) context.Context {
+	if name == "" {
+		return Set(ctx, tx, opts)
+	}
+
+	return context.WithValue(ctx, namedKey(name), Get(Set(ctx, tx, opts)))
+}
+
+// GetNamed is Get for the transaction slot of given name, see SetNamed.
+func GetNamed(ctx context.Context, name string) Current {
+	if name == "" {
+		return Get(ctx)
+	}
+
+	result, _ := ctx.Value(namedKey(name)).(Current)
+
+	return result
+}
+
+// EnsureNamed is Ensure for the transaction slot of given name, see SetNamed:
+// the transaction of that slot is reused if it matches given options, otherwise a new one is begun on db.
+//
+// The transaction of the slot is only reachable with GetNamed: functions relying on the default slot,
+// like the Current methods of Get, OnCommit or Nested, see the transaction of the default slot, if any.
+func EnsureNamed(
+	ctx context.Context,
+	name string,
+	db Beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+) error {
+	if name == "" {
+		return Ensure(ctx, db, opts, f)
+	}
+
+	if !GetNamed(ctx, name).NewTransactionRequired(opts) {
+		return f(ctx)
+	}
+
+	return WrapNamed(ctx, name, db, opts, f)
+}
+
+// WrapNamed is Wrap for the transaction slot of given name, see EnsureNamed.
+func WrapNamed(
+	ctx context.Context,
+	name string,
+	db Beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+) error {
+	if name == "" {
+		return Wrap(ctx, db, opts, f)
+	}
+
+	outer := Get(ctx)
+
+	return Wrap(Detach(ctx), db, opts, func(tctx context.Context) error {
+		tctx = context.WithValue(tctx, namedKey(name), Get(tctx))
+
+		// the default slot is left as is
+		return f(context.WithValue(tctx, ctxKey, outer))
+	})
+}
@@ -0,0 +1,86 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureNamed(t *testing.T) {
+	primary := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	analytics := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	insert := func(ctx context.Context, name, person string) {
+		_, err := GetNamed(ctx, name).ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", person)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, EnsureNamed(context.Background(), "primary", primary, nil, func(ctx context.Context) error {
+		insert(ctx, "primary", "Carol")
+
+		id := GetNamed(ctx, "primary").ID()
+		assert.False(t, IsInTx(ctx), "default slot should not be set")
+
+		return EnsureNamed(ctx, "analytics", analytics, nil, func(ctx context.Context) error {
+			insert(ctx, "analytics", "Dave")
+
+			assert.NotEqual(t, id, GetNamed(ctx, "analytics").ID())
+
+			return EnsureNamed(ctx, "primary", primary, nil, func(ctx context.Context) error {
+				assert.Equal(t, id, GetNamed(ctx, "primary").ID(), "same name should reuse")
+				insert(ctx, "primary", "Eve")
+
+				return nil
+			})
+		})
+	}))
+
+	assert.Equal(t, []string{"Alice", "Bob", "Carol", "Eve"}, names(t, context.Background(), NewDB(primary)))
+	assert.Equal(t, []string{"Alice", "Bob", "Dave"}, names(t, context.Background(), NewDB(analytics)))
+}
+
+func TestEnsureNamed_rollback(t *testing.T) {
+	primary := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	analytics := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	errFail := errors.New("failed") //nolint:goerr113
+
+	require.NoError(t, Ensure(context.Background(), primary, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		current := Get(ctx)
+
+		require.ErrorIs(t, EnsureNamed(ctx, "analytics", analytics, nil, func(ctx context.Context) error {
+			assert.Equal(t, current.ID(), Get(ctx).ID(), "default slot should be kept")
+
+			_, err := GetNamed(ctx, "analytics").ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+			require.NoError(t, err)
+
+			return errFail
+		}), errFail)
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, context.Background(), NewDB(primary)))
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(analytics)))
+}
+
+func TestSetNamed(t *testing.T) {
+	tx := &sql.Tx{}
+	ctx := SetNamed(context.Background(), "analytics", tx, ReadOnly())
+
+	assert.Equal(t, tx, GetNamed(ctx, "analytics").Tx)
+	assert.Equal(t, ReadOnly(), GetNamed(ctx, "analytics").Opts)
+	assert.NotZero(t, GetNamed(ctx, "analytics").ID())
+	assert.False(t, GetNamed(ctx, "primary").IsValid())
+	assert.False(t, IsInTx(ctx))
+
+	ctx = SetNamed(ctx, "", tx, nil)
+	assert.Equal(t, tx, Get(ctx).Tx, "empty name should be the default slot")
+	assert.Equal(t, tx, GetNamed(ctx, "").Tx)
+}
@@ -0,0 +1,90 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// ErrNestedWrap is returned when Wrap is called in a transaction with NestedWrapDeny.
+var ErrNestedWrap = errors.New("txx: nested Wrap")
+
+// NestedWrapPolicy decides what Manager.Wrap does when called in a transaction, see WithNestedWrapPolicy.
+//
+// Such a Wrap begins a second, independent transaction on another connection, which may deadlock
+// with the first one or commit while it rolls back.
+type NestedWrapPolicy int
+
+// Nested Wrap policies.
+const (
+	// NestedWrapAllow begins the second transaction, the default.
+	NestedWrapAllow NestedWrapPolicy = iota
+	// NestedWrapWarn begins the second transaction and logs a warning with both call sites.
+	NestedWrapWarn
+	// NestedWrapDeny returns ErrNestedWrap with both call sites.
+	NestedWrapDeny
+)
+
+// WithNestedWrapPolicy sets what Wrap does when called in a transaction, NestedWrapAllow by default.
+//
+// Warnings are logged to the logger of WithLogger, the default slog logger otherwise.
+// Ensure beginning a new transaction because the current one doesn't match its options is not concerned,
+// see WithStrictReuse.
+func WithNestedWrapPolicy(p NestedWrapPolicy) Option {
+	return func(m *Manager) {
+		m.nested = p
+	}
+}
+
+// callerKey is the context key of the call site of the Wrap of the current transaction.
+type callerKey struct{}
+
+// checkNested applies the nested Wrap policy and returns the context recording the call site of Wrap.
+func (m *Manager) checkNested(ctx context.Context) (context.Context, error) {
+	if m.nested == NestedWrapAllow {
+		return ctx, nil
+	}
+
+	site := caller()
+
+	if IsInTx(ctx) {
+		outer, _ := ctx.Value(callerKey{}).(string)
+		if outer == "" {
+			outer = "unknown"
+		}
+
+		if m.nested == NestedWrapDeny {
+			return ctx, fmt.Errorf("%w at %s, in the transaction begun at %s", ErrNestedWrap, site, outer)
+		}
+
+		logger := m.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		logger.WarnContext(ctx, "txx: nested Wrap", slog.String("caller", site), slog.String("outer_caller", outer))
+	}
+
+	return context.WithValue(ctx, callerKey{}, site), nil
+}
+
+// caller returns the file and line of the first caller outside of this package, tests excepted.
+func caller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, "github.com/MartyHub/txx.") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
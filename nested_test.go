@@ -0,0 +1,70 @@
+package txx
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nestedWrap(m *Manager) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return m.Wrap(ctx, nil, noop)
+	}
+}
+
+func TestWithNestedWrapPolicy_allow(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, nestedWrap(m)))
+	assert.Equal(t, []string{"begin", "begin", "commit", "commit"}, backend.calls)
+}
+
+func TestWithNestedWrapPolicy_warn(t *testing.T) {
+	backend := &fakeBackend{}
+	rec := &recorder{}
+	m := newManager(backend, WithNestedWrapPolicy(NestedWrapWarn), WithLogger(slog.New(rec)))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, nestedWrap(m)))
+	assert.Equal(t, []string{"begin", "begin", "commit", "commit"}, backend.calls)
+
+	var warnings []slog.Record
+
+	for _, record := range rec.records {
+		if record.Message == "txx: nested Wrap" {
+			warnings = append(warnings, record)
+		}
+	}
+
+	require.Len(t, warnings, 1)
+	assert.Equal(t, slog.LevelWarn, warnings[0].Level)
+
+	got := attrs(warnings[0])
+	assert.Contains(t, got["caller"], "nested_test.go:14")
+	assert.Contains(t, got["outer_caller"], "nested_test.go:")
+	assert.NotEqual(t, got["caller"], got["outer_caller"])
+}
+
+func TestWithNestedWrapPolicy_deny(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithNestedWrapPolicy(NestedWrapDeny))
+
+	err := m.Wrap(context.Background(), nil, nestedWrap(m))
+
+	require.ErrorIs(t, err, ErrNestedWrap)
+	assert.Contains(t, err.Error(), "nested_test.go:14, in the transaction begun at ")
+	assert.Equal(t, []string{"begin", "rollback"}, backend.calls)
+}
+
+func TestWithNestedWrapPolicy_ensure(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithNestedWrapPolicy(NestedWrapDeny))
+
+	require.NoError(t, m.Wrap(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		return m.Ensure(ctx, nil, noop)
+	}))
+	assert.Equal(t, []string{"begin", "begin", "commit", "commit"}, backend.calls)
+}
@@ -0,0 +1,48 @@
+package txx
+
+import "context"
+
+// Done returns a channel closed once the transaction of the context is completed,
+// after its commit or rollback, Outcome then reporting which.
+//
+// Without a transaction begun by txx in the context, the returned channel is already closed.
+func Done(ctx context.Context) <-chan struct{} {
+	s := Get(ctx).s
+	if s == nil {
+		return closedChan()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return closedChan()
+	}
+
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+
+	return s.done
+}
+
+// Outcome returns the Info of the completed transaction of the context,
+// reporting false while it is not completed or without a transaction begun by txx.
+func Outcome(ctx context.Context) (Info, bool) {
+	s := Get(ctx).s
+	if s == nil {
+		return Info{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.outcome, s.closed
+}
+
+func closedChan() <-chan struct{} {
+	result := make(chan struct{})
+	close(result)
+
+	return result
+}
@@ -0,0 +1,95 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// await starts a goroutine waiting for the completion of the transaction of ctx.
+func await(ctx context.Context) <-chan Info {
+	result := make(chan Info, 1)
+	done := Done(ctx)
+
+	go func() {
+		<-done
+
+		info, _ := Outcome(ctx)
+		result <- info
+	}()
+
+	return result
+}
+
+func TestDone(t *testing.T) {
+	tests := []struct {
+		name          string
+		f             func(ctx context.Context) error
+		wantCommitted bool
+		wantCause     RollbackCause
+	}{
+		{name: "commit", f: noop, wantCommitted: true},
+		{name: "rollback", f: fail, wantCause: CauseError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result <-chan Info
+
+			err := New(testdb.Open(t, inMemory)).Wrap(context.Background(), nil, func(ctx context.Context) error {
+				result = await(ctx)
+
+				select {
+				case <-Done(ctx):
+					t.Error("transaction should not be done yet")
+				default:
+				}
+
+				_, completed := Outcome(ctx)
+				assert.False(t, completed)
+
+				return tt.f(ctx)
+			})
+
+			info := <-result
+
+			assert.Equal(t, tt.wantCommitted, info.Committed)
+			assert.Equal(t, tt.wantCause, info.Cause)
+			assert.Equal(t, err, info.Err)
+		})
+	}
+}
+
+func TestDone_nested(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	var result <-chan Info
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return m.Ensure(ctx, nil, func(ctx context.Context) error {
+			result = await(ctx)
+
+			return nil
+		})
+	}))
+
+	assert.True(t, (<-result).Committed, "nested Ensure should wait for the outermost transaction")
+}
+
+func TestDone_noTransaction(t *testing.T) {
+	for name, ctx := range map[string]context.Context{
+		"none": context.Background(),
+		"set":  Set(context.Background(), &sql.Tx{}, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			<-Done(ctx)
+
+			_, completed := Outcome(ctx)
+			assert.False(t, completed)
+		})
+	}
+}
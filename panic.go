@@ -0,0 +1,70 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPanic is matched by every PanicError, see WithRecoverPanics.
+var ErrPanic = errors.New("txx: panic")
+
+// PanicError is the cause of a rollback due to a panic of the function run by Wrap, see OnRollback.
+type PanicError struct {
+	// Value given to panic.
+	Value any
+	// Stack of the panicking goroutine, as formatted by debug.Stack, if captured.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Is reports if target is ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic //nolint:errorlint
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// WithRecoverPanics makes the transactions of a Manager return a panic of their function as a *PanicError,
+// after the rollback, instead of propagating it.
+//
+// The error matches ErrPanic, wraps the panic value if it is an error, and carries the stack of the panic.
+// Panics still propagate by default: this suits worker pools, where a failing job must not kill the process.
+func WithRecoverPanics() Option {
+	return func(m *Manager) {
+		m.r.recoverPanics = true
+	}
+}
+
+// WithOnPanic calls f when the function run by a transaction of the Manager panics,
+// with the panic value, the stack of the panic and the transaction, before its rollback,
+// and before the panic is propagated or recovered, see WithRecoverPanics.
+//
+// Function f is given the context of Wrap, and can read the Duration and options of the transaction,
+// which is still open. A panic of f is ignored, so that it doesn't mask the original panic.
+func WithOnPanic(f func(ctx context.Context, current Current, value any, stack []byte)) Option {
+	return func(m *Manager) {
+		m.r.onPanic = f
+	}
+}
+
+// panicked calls the onPanic hook, if any, ignoring its panic.
+func (r runner) panicked(ctx context.Context, current Current, value any, stack []byte) {
+	if r.onPanic == nil {
+		return
+	}
+
+	defer func() {
+		_ = recover()
+	}()
+
+	r.onPanic(ctx, current, value, stack)
+}
@@ -0,0 +1,119 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRecoverPanics(t *testing.T) {
+	errValue := errors.New("value") //nolint:goerr113
+
+	tests := []struct {
+		name  string
+		f     func()
+		value error
+		msg   string
+	}{
+		{name: "error", f: func() { panic(errValue) }, value: errValue, msg: "panic: value"},
+		{name: "string", f: func() { panic("boom") }, msg: "panic: boom"},
+		{
+			name: "runtime",
+			f: func() {
+				var m map[string]int
+
+				m["key"] = 1
+			},
+			msg: "panic: assignment to entry in nil map",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(testdb.Open(t, inMemory), WithRecoverPanics())
+
+			var infos []Info
+
+			m.finally = func(info Info) {
+				infos = append(infos, info)
+			}
+
+			err := m.Wrap(context.Background(), nil, func(_ context.Context) error {
+				tt.f()
+
+				return nil
+			})
+
+			require.ErrorIs(t, err, ErrPanic)
+			assert.EqualError(t, err, tt.msg)
+
+			if tt.value != nil {
+				require.ErrorIs(t, err, tt.value)
+			}
+
+			var panicErr *PanicError
+
+			require.ErrorAs(t, err, &panicErr)
+			assert.Contains(t, string(panicErr.Stack), "panic_test.go", "stack should locate the panic")
+
+			require.Len(t, infos, 1)
+			assert.Equal(t, CausePanic, infos[0].Cause)
+		})
+	}
+}
+
+func TestWithRecoverPanics_disabled(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("boom")
+		})
+	})
+}
+
+func TestWithOnPanic(t *testing.T) {
+	var (
+		observed any
+		stack    []byte
+		current  Current
+		open     bool
+	)
+
+	m := New(testdb.Open(t, inMemory), WithOnPanic(func(ctx context.Context, c Current, value any, s []byte) {
+		observed, current, stack = value, c, s
+
+		_, err := c.ExecContext(ctx, "SELECT 1")
+		open = err == nil
+	}))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = m.Wrap(context.Background(), ReadOnly(), func(_ context.Context) error {
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, "boom", observed)
+	assert.Contains(t, string(stack), "panic_test.go")
+	assert.Equal(t, ReadOnly(), current.Opts)
+	assert.NotZero(t, current.ID())
+	assert.True(t, open, "hook should be called before rollback")
+}
+
+func TestWithOnPanic_panic(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithOnPanic(func(_ context.Context, _ Current, _ any, _ []byte) {
+		panic("hook")
+	}))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("boom")
+		})
+	}, "panic of the hook should not mask the original one")
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+}
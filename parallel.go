@@ -0,0 +1,104 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"runtime/debug"
+	"sync"
+)
+
+// Parallel runs every function concurrently, each in its own goroutine and transaction run by Wrap,
+// like independent reads of a request, and returns the first error once every transaction is completed.
+//
+// Functions are given a context without the current transaction of ctx, a sql.Tx not being safe for concurrent use,
+// canceled by the first error. A panic of a function rolls back its transaction and is returned as a *PanicError,
+// carrying the stack of the panic, instead of crashing the process from another goroutine.
+// Named slots, see SetNamed, are not detached: functions must not use them.
+func Parallel(ctx context.Context, db Beginner, opts *sql.TxOptions, fs ...func(ctx context.Context) error) error {
+	return parallel(ctx, func(ctx context.Context, f func(ctx context.Context) error) error {
+		return Wrap(ctx, db, opts, f)
+	}, fs)
+}
+
+// Parallel is Parallel for the transactions of the Manager.
+func (m *Manager) Parallel(ctx context.Context, opts *sql.TxOptions, fs ...func(ctx context.Context) error) error {
+	return parallel(ctx, func(ctx context.Context, f func(ctx context.Context) error) error {
+		return m.Wrap(ctx, opts, f)
+	}, fs)
+}
+
+func parallel(
+	ctx context.Context,
+	wrap func(ctx context.Context, f func(ctx context.Context) error) error,
+	fs []func(ctx context.Context) error,
+) error {
+	ctx, cancel := context.WithCancelCause(Detach(ctx))
+	defer cancel(nil)
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+
+	for _, f := range fs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := recovered(ctx, wrap, f); err != nil {
+				once.Do(func() {
+					first = err
+					cancel(err)
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return first
+}
+
+// recovered runs f with wrap, returning its panic as a *PanicError.
+func recovered(
+	ctx context.Context,
+	wrap func(ctx context.Context, f func(ctx context.Context) error) error,
+	f func(ctx context.Context) error,
+) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+
+	return wrap(ctx, f)
+}
+
+// Go runs f in a new goroutine, in its own transaction run by Wrap, or without transaction if db is nil,
+// for background work started inside a transaction, like an audit write that must outlive its rollback.
+//
+// Function f is given a context without the current transaction of ctx, which its goroutine would race on,
+// and not canceled with ctx, whose values are kept. Go returns immediately a channel receiving the error of f,
+// a panic being returned as a *PanicError, then closed.
+func Go(ctx context.Context, db Beginner, opts *sql.TxOptions, f func(ctx context.Context) error) <-chan error {
+	wrap := func(ctx context.Context, f func(ctx context.Context) error) error {
+		if db == nil {
+			return f(ctx)
+		}
+
+		return Wrap(ctx, db, opts, f)
+	}
+
+	ctx = context.WithoutCancel(Detach(ctx))
+	result := make(chan error, 1)
+
+	go func() {
+		defer close(result)
+
+		result <- recovered(ctx, wrap, f)
+	}()
+
+	return result
+}
@@ -0,0 +1,155 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	var (
+		mu  sync.Mutex
+		ids = map[uint64]bool{}
+	)
+
+	count := func(ctx context.Context) error {
+		var n int
+
+		if err := Get(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM person").Scan(&n); err != nil {
+			return err
+		}
+
+		assert.Equal(t, 2, n)
+
+		mu.Lock()
+		ids[Get(ctx).ID()] = true
+		mu.Unlock()
+
+		return nil
+	}
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		outer := Get(ctx).ID()
+
+		require.NoError(t, Parallel(ctx, db, nil, count, count, count, count))
+		assert.NotContains(t, ids, outer, "outer transaction should not be shared")
+
+		return nil
+	}))
+
+	assert.Len(t, ids, 4, "every function should have its own transaction")
+	assert.Equal(t, 0, db.Stats().InUse, "every transaction should be completed")
+}
+
+func TestParallel_error(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := New(db).Parallel(context.Background(), nil,
+		func(ctx context.Context) error {
+			_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+			require.NoError(t, err)
+
+			return errFailed
+		},
+		func(ctx context.Context) error {
+			<-ctx.Done()
+
+			assert.ErrorIs(t, context.Cause(ctx), errFailed)
+
+			return ctx.Err()
+		},
+	)
+
+	require.ErrorIs(t, err, errFailed)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), db))
+}
+
+func TestParallel_panic(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Parallel(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		panic("boom")
+	})
+
+	var e *PanicError
+
+	require.ErrorAs(t, err, &e)
+	require.ErrorIs(t, err, ErrPanic)
+	assert.Equal(t, "boom", e.Value)
+	assert.Contains(t, string(e.Stack), "TestParallel_panic")
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), db), "should be rolled back")
+}
+
+func TestParallel_empty(t *testing.T) {
+	require.NoError(t, Parallel(context.Background(), testdb.Open(t, inMemory), nil))
+}
+
+func TestGo(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	rolledBack := make(chan struct{})
+
+	var result <-chan error
+
+	require.ErrorIs(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		outer := Get(ctx).ID()
+
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.NoError(t, err)
+
+		result = Go(ctx, db, nil, func(ctx context.Context) error {
+			<-rolledBack
+
+			assert.NotEqual(t, outer, Get(ctx).ID(), "outer transaction should not be shared")
+
+			_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+
+			return err
+		})
+
+		return errFailed
+	}), errFailed)
+
+	close(rolledBack)
+
+	require.NoError(t, <-result)
+	assert.Equal(t, []string{"Alice", "Bob", "Dave"}, names(t, context.Background(), db))
+
+	_, open := <-result
+	assert.False(t, open, "result should be closed")
+}
+
+func TestGo_noTransaction(t *testing.T) {
+	tx, err := testdb.Open(t, inMemory).BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+
+	defer tx.Rollback() //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(Set(context.Background(), tx, nil))
+	cancel()
+
+	require.NoError(t, <-Go(ctx, nil, nil, func(ctx context.Context) error {
+		assert.False(t, IsInTx(ctx))
+
+		return ctx.Err()
+	}), "context should not be canceled with its parent")
+}
+
+func TestGo_panic(t *testing.T) {
+	err := <-Go(context.Background(), testdb.Open(t, inMemory), nil, func(context.Context) error {
+		panic("boom")
+	})
+
+	require.ErrorIs(t, err, ErrPanic)
+}
@@ -0,0 +1,175 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+var (
+	// ErrTransactionRequired is returned with PropagationMandatory when no transaction matching
+	// the options is current. It wraps ErrNoTransaction.
+	ErrTransactionRequired = fmt.Errorf("txx: transaction required: %w", ErrNoTransaction)
+	// ErrTransactionNotAllowed is returned with PropagationNever when a transaction is current.
+	ErrTransactionNotAllowed = errors.New("txx: transaction not allowed")
+)
+
+// Propagation decides how EnsureWith runs a function depending on the current transaction.
+type Propagation int
+
+// Propagation modes.
+const (
+	// PropagationRequired reuses the current transaction matching the options,
+	// begins a new one otherwise, like Ensure.
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew always begins a new transaction, like Wrap.
+	PropagationRequiresNew
+	// PropagationMandatory reuses the current transaction matching the options,
+	// returning ErrTransactionRequired otherwise.
+	PropagationMandatory
+	// PropagationNever runs without transaction, returning ErrTransactionNotAllowed if one is current.
+	PropagationNever
+	// PropagationSupports reuses the current transaction if any, whatever its options,
+	// running without transaction otherwise.
+	PropagationSupports
+	// PropagationNotSupported runs without transaction, the current one, if any,
+	// being hidden from the function.
+	PropagationNotSupported
+)
+
+func (p Propagation) String() string {
+	switch p {
+	case PropagationRequired:
+		return "required"
+	case PropagationRequiresNew:
+		return "requires_new"
+	case PropagationMandatory:
+		return "mandatory"
+	case PropagationNever:
+		return "never"
+	case PropagationSupports:
+		return "supports"
+	case PropagationNotSupported:
+		return "not_supported"
+	}
+
+	return fmt.Sprintf("Propagation(%d)", int(p))
+}
+
+// Require returns the current transaction of ctx, or ErrTransactionRequired if none is valid,
+// for code that must not run without transaction, like repository methods whose writes would
+// otherwise be committed statement by statement. See PropagationMandatory to also match options.
+func Require(ctx context.Context) (Current, error) {
+	current := Get(ctx)
+	if !current.IsValid() {
+		return Current{}, ErrTransactionRequired
+	}
+
+	return current, nil
+}
+
+// MustRequire is Require panicking without transaction, for internal invariants:
+// the panic value is an error wrapping ErrTransactionRequired, naming the location of the caller.
+func MustRequire(ctx context.Context) Current {
+	current, err := Require(ctx)
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+
+		panic(fmt.Errorf("%w, called at %s:%d", err, file, line))
+	}
+
+	return current
+}
+
+// EnsureWith runs function f according to given propagation mode,
+// given options applying to the transaction it begins or reuses, if any.
+func EnsureWith(
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	p Propagation,
+	f func(ctx context.Context) error,
+) error {
+	return propagate(ctx, p, transactionRequired(ctx, opts, sql.LevelDefault, nil), f, func(ctx context.Context) error {
+		return Wrap(ctx, db, opts, f)
+	}, func(ctx context.Context) error {
+		return runReused(nil, clock.Real{}, opts, Get(ctx).s.getLabels(), func() error {
+			return reusing(f)(ctx)
+		})
+	})
+}
+
+// EnsureWith runs function f according to given propagation mode.
+//
+// See the package-level EnsureWith function.
+// PropagationRequiresNew is not subject to WithNestedWrapPolicy, being explicit.
+func (m *Manager) EnsureWith(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	p Propagation,
+	f func(ctx context.Context) error,
+) error {
+	if p == PropagationRequired {
+		return m.Ensure(ctx, opts, f)
+	}
+
+	opts = m.options(opts)
+
+	return propagate(ctx, p, m.transactionRequired(ctx, opts), f, func(ctx context.Context) error {
+		return m.wrap(ctx, opts, f)
+	}, func(ctx context.Context) error {
+		return m.reuseTx(ctx, opts, reusing(f))
+	})
+}
+
+// propagate runs function f according to given propagation mode,
+// wrap running it in a new transaction, reuse in the current one,
+// and newRequired telling if the current transaction doesn't match.
+func propagate(
+	ctx context.Context,
+	p Propagation,
+	newRequired bool,
+	f func(ctx context.Context) error,
+	wrap func(ctx context.Context) error,
+	reuse func(ctx context.Context) error,
+) error {
+	inTx := IsInTx(ctx)
+
+	switch p {
+	case PropagationRequired:
+		if newRequired {
+			return wrap(ctx)
+		}
+	case PropagationRequiresNew:
+		return wrap(ctx)
+	case PropagationMandatory:
+		if !inTx {
+			return ErrTransactionRequired
+		}
+
+		if newRequired {
+			return fmt.Errorf("%w: current transaction doesn't match options", ErrTransactionRequired)
+		}
+	case PropagationNever:
+		if inTx {
+			return ErrTransactionNotAllowed
+		}
+	case PropagationSupports:
+	case PropagationNotSupported:
+		if inTx {
+			return f(Detach(ctx))
+		}
+	default:
+		return fmt.Errorf("txx: unknown %v", p) //nolint:goerr113
+	}
+
+	if inTx {
+		return reuse(ctx)
+	}
+
+	return f(ctx)
+}
@@ -0,0 +1,138 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureWith(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	outer := &sql.Tx{}
+
+	const (
+		none  = "none"
+		fresh = "fresh"
+		reuse = "reuse"
+	)
+
+	tests := []struct {
+		propagation Propagation
+		opts        *sql.TxOptions
+		// want is the transaction of the function without and with a current transaction, if run.
+		want    [2]string
+		wantErr [2]error
+	}{
+		{propagation: PropagationRequired, want: [2]string{fresh, reuse}},
+		{propagation: PropagationRequired, opts: ReadOnly(), want: [2]string{fresh, fresh}},
+		{propagation: PropagationRequiresNew, want: [2]string{fresh, fresh}},
+		{propagation: PropagationMandatory, wantErr: [2]error{ErrTransactionRequired, nil}, want: [2]string{"", reuse}},
+		{
+			propagation: PropagationMandatory,
+			opts:        ReadOnly(),
+			wantErr:     [2]error{ErrTransactionRequired, ErrTransactionRequired},
+		},
+		{propagation: PropagationNever, wantErr: [2]error{nil, ErrTransactionNotAllowed}, want: [2]string{none, ""}},
+		{propagation: PropagationSupports, want: [2]string{none, reuse}},
+		{propagation: PropagationSupports, opts: ReadOnly(), want: [2]string{none, reuse}},
+		{propagation: PropagationNotSupported, want: [2]string{none, none}},
+	}
+
+	for _, tt := range tests {
+		for i, ctx := range []context.Context{context.Background(), Set(context.Background(), outer, nil)} {
+			t.Run(tt.propagation.String(), func(t *testing.T) {
+				got := ""
+
+				err := EnsureWith(ctx, db, tt.opts, tt.propagation, func(ctx context.Context) error {
+					switch current := Get(ctx); {
+					case !current.IsValid():
+						got = none
+					case current.Tx == outer:
+						got = reuse
+					default:
+						got = fresh
+					}
+
+					return nil
+				})
+
+				if tt.wantErr[i] != nil {
+					require.ErrorIs(t, err, tt.wantErr[i])
+				} else {
+					require.NoError(t, err)
+				}
+
+				assert.Equal(t, tt.want[i], got, "in transaction: %v", i == 1)
+			})
+		}
+	}
+}
+
+func TestEnsureWith_error(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+
+	require.ErrorIs(t, EnsureWith(context.Background(), db, nil, PropagationMandatory, checkTxExists), ErrNoTransaction)
+	require.Error(t, EnsureWith(context.Background(), db, nil, Propagation(-1), checkTxExists))
+}
+
+func TestManager_EnsureWith(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithNestedWrapPolicy(NestedWrapDeny))
+
+	require.ErrorIs(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		outer := Get(ctx).ID()
+
+		require.NoError(t, m.EnsureWith(ctx, nil, PropagationRequired, func(ctx context.Context) error {
+			assert.Equal(t, outer, Get(ctx).ID())
+
+			return nil
+		}))
+
+		require.NoError(t, m.EnsureWith(ctx, nil, PropagationRequiresNew, func(ctx context.Context) error {
+			assert.NotEqual(t, outer, Get(ctx).ID())
+
+			return nil
+		}))
+
+		require.NoError(t, m.EnsureWith(ctx, nil, PropagationNotSupported, func(ctx context.Context) error {
+			assert.False(t, IsInTx(ctx))
+
+			return nil
+		}))
+
+		return m.EnsureWith(ctx, nil, PropagationNever, checkTxExists)
+	}), ErrTransactionNotAllowed)
+
+	assert.Equal(t, []string{"begin", "begin", "commit", "rollback"}, backend.calls)
+}
+
+func TestRequire(t *testing.T) {
+	_, err := Require(context.Background())
+	require.ErrorIs(t, err, ErrTransactionRequired)
+	require.ErrorIs(t, err, ErrNoTransaction)
+
+	require.NoError(t, Wrap(context.Background(), testdb.Open(t, inMemory), nil, func(ctx context.Context) error {
+		current, err := Require(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Get(ctx), current)
+		assert.Equal(t, Get(ctx), MustRequire(ctx))
+
+		return nil
+	}))
+}
+
+func TestMustRequire(t *testing.T) {
+	defer func() {
+		err, ok := recover().(error)
+		require.True(t, ok)
+		require.ErrorIs(t, err, ErrTransactionRequired)
+		assert.Contains(t, err.Error(), "propagation_test.go:")
+	}()
+
+	MustRequire(context.Background())
+	t.Fatal("MustRequire should panic")
+}
@@ -0,0 +1,135 @@
+package txx
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrReadOnlyViolation is returned when a write is executed through the Current methods of a read-only transaction,
+// and so through the helpers of the package, see WithoutReadOnlyEnforcement.
+var ErrReadOnlyViolation = errors.New("txx: write in a read-only transaction")
+
+//nolint:gochecknoglobals
+var (
+	// writeKeywords start the statements rejected in read-only transactions.
+	writeKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT", "CREATE", "ALTER", "DROP"}
+	// cteWriteKeywords are the writes of common table expressions, like WITH ... INSERT.
+	cteWriteKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE"}
+)
+
+// SkipTxForReadOnly makes Manager.Ensure run read-only work outside of any transaction
+// when none is current, saving the BEGIN and COMMIT round trips of single-statement reads.
+//
+// Function f then sees no transaction, IsInTx reporting false, and helpers like Select
+// run on the database. Statements are not isolated from each other anymore:
+// work needing multi-statement consistency must not use this mode.
+// A current transaction is still reused as usual.
+func SkipTxForReadOnly() Option {
+	return func(m *Manager) {
+		m.skipReadOnly = true
+	}
+}
+
+// WithoutReadOnlyEnforcement lets writes run in read-only transactions, leaving their rejection to the driver.
+//
+// By default, statements of read-only transactions executed through Current, or the helpers of the package,
+// fail with ErrReadOnlyViolation before reaching the driver if they start with INSERT, UPDATE, DELETE, MERGE,
+// REPLACE, UPSERT, CREATE, ALTER or DROP, leading comments aside, or if they are common table expressions
+// with an INSERT, UPDATE, DELETE or MERGE, as some drivers like SQLite ones don't enforce read-only transactions.
+// Creating a temporary table is allowed. Statements executed directly on Get(ctx).Tx are not checked.
+func WithoutReadOnlyEnforcement() Option {
+	return func(m *Manager) {
+		m.r.readOnlyWrites = true
+	}
+}
+
+// checkReadOnly returns an error if given query is a write while the transaction is read-only.
+func (s *scope) checkReadOnly(query string) error {
+	if s == nil || !s.readOnly {
+		return nil
+	}
+
+	if keyword, write := isWrite(query); write {
+		return fmt.Errorf("%w: %s", ErrReadOnlyViolation, keyword)
+	}
+
+	return nil
+}
+
+// isWrite conservatively reports if given query is a write, with its write keyword.
+func isWrite(query string) (string, bool) {
+	words := keywords(query)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	if words[0] == "WITH" {
+		for _, word := range words[1:] {
+			if slices.Contains(cteWriteKeywords, word) {
+				return word, true
+			}
+		}
+
+		return "", false
+	}
+
+	if !slices.Contains(writeKeywords, words[0]) {
+		return "", false
+	}
+
+	if words[0] == "CREATE" && len(words) > 1 && (words[1] == "TEMP" || words[1] == "TEMPORARY") {
+		return "", false
+	}
+
+	return words[0], true
+}
+
+// keywords returns the words of given query in upper case, skipping comments, literals and quoted identifiers.
+func keywords(query string) []string {
+	var (
+		words []string
+		word  strings.Builder
+	)
+
+	end := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || word.Len() > 0 && c >= '0' && c <= '9':
+			word.WriteByte(c)
+
+			continue
+		case strings.HasPrefix(query[i:], "--"):
+			i = skipTo(query, i+2, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipTo(query, i+2, "*/")
+		case c == '\'' || c == '"' || c == '`':
+			i = skipTo(query, i+1, string(c))
+		}
+
+		end()
+	}
+
+	end()
+
+	return words
+}
+
+// skipTo returns the index of the last byte of the first given delimiter of query from index i,
+// the last index of query if not found.
+func skipTo(query string, i int, delimiter string) int {
+	if j := strings.Index(query[i:], delimiter); j >= 0 {
+		return i + j + len(delimiter) - 1
+	}
+
+	return len(query) - 1
+}
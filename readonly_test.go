@@ -0,0 +1,167 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipTxForReadOnly(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, SkipTxForReadOnly())
+
+	require.NoError(t, m.Ensure(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		assert.False(t, IsInTx(ctx))
+
+		return nil
+	}))
+	assert.Empty(t, backend.calls, "no transaction should be begun")
+
+	require.NoError(t, m.Ensure(context.Background(), nil, noop))
+	assert.Equal(t, []string{"begin", "commit"}, backend.calls, "read-write work still needs a transaction")
+}
+
+func TestSkipTxForReadOnly_reuse(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, SkipTxForReadOnly())
+
+	err := m.Wrap(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		outer := Get(ctx)
+
+		return m.Ensure(ctx, ReadOnly(), checkTxEquals(outer.Tx))
+	})
+
+	require.NoError(t, err)
+}
+
+func TestSkipTxForReadOnly_helpers(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db, SkipTxForReadOnly())
+
+	err := m.Ensure(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		got, err := Select[string](ctx, db, "SELECT name FROM person ORDER BY id")
+
+		assert.Equal(t, []string{"Alice", "Bob"}, got)
+
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), m.Stats().Committed)
+}
+
+func TestIsInTx(t *testing.T) {
+	assert.False(t, IsInTx(context.Background()))
+	assert.True(t, IsInTx(Set(context.Background(), &sql.Tx{}, nil)))
+}
+
+func TestIsWrite(t *testing.T) {
+	tests := []struct {
+		query   string
+		keyword string
+	}{
+		{query: "INSERT INTO person (name) VALUES ('Carol')", keyword: "INSERT"},
+		{query: "  update person SET name = 'Carol'", keyword: "UPDATE"},
+		{query: "-- rename\nUPDATE person SET name = 'Carol'", keyword: "UPDATE"},
+		{query: "/* purge */ DELETE FROM person", keyword: "DELETE"},
+		{query: "/* a */ -- b\n /* c */ DROP TABLE person", keyword: "DROP"},
+		{query: "WITH old AS (SELECT id FROM person) DELETE FROM person WHERE id IN (SELECT id FROM old)", keyword: "DELETE"},
+		{query: "with new (name) AS (VALUES ('Carol')) insert INTO person (name) SELECT name FROM new", keyword: "INSERT"},
+		{query: "REPLACE INTO person (id, name) VALUES (1, 'Carol')", keyword: "REPLACE"},
+		{query: "UPSERT INTO person (id, name) VALUES (1, 'Carol')", keyword: "UPSERT"},
+		{query: "MERGE INTO person USING other ON person.id = other.id WHEN MATCHED THEN DELETE", keyword: "MERGE"},
+		{query: "CREATE TABLE other (id INTEGER)", keyword: "CREATE"},
+		{query: "CREATE TEMP TABLE other (id INTEGER)"},
+		{query: "SELECT name FROM person"},
+		{query: "-- DELETE FROM person\nSELECT name FROM person"},
+		{query: "SELECT 'DELETE' FROM person"},
+		{query: "SELECT REPLACE(name, 'a', 'b') FROM person"},
+		{query: "WITH named AS (SELECT \"delete\" FROM person) SELECT * FROM named"},
+		{query: ""},
+		{query: "/* unterminated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			keyword, write := isWrite(tt.query)
+
+			assert.Equal(t, tt.keyword, keyword)
+			assert.Equal(t, tt.keyword != "", write)
+		})
+	}
+}
+
+func TestReadOnlyViolation(t *testing.T) {
+	writes := []string{
+		"INSERT INTO person (name) VALUES ('Carol')",
+		"  update person SET name = 'Carol'",
+		"DELETE FROM person",
+		"CREATE TABLE other (id INTEGER)",
+		"ALTER TABLE person ADD COLUMN age INTEGER",
+		"DROP TABLE person",
+	}
+
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	for _, query := range writes {
+		t.Run(query, func(t *testing.T) {
+			err := Wrap(context.Background(), db, ReadOnly(), func(ctx context.Context) error {
+				_, err := ExecContext(ctx, db, query)
+				require.ErrorIs(t, err, ErrReadOnlyViolation)
+
+				_, err = PrepareContext(ctx, db, query)
+				require.ErrorIs(t, err, ErrReadOnlyViolation)
+
+				_, err = QueryContext(ctx, db, query)
+				require.ErrorIs(t, err, ErrReadOnlyViolation)
+
+				require.ErrorIs(t, QueryRowContext(ctx, db, query).Err(), ErrReadOnlyViolation)
+				require.ErrorIs(t, ExecBuffered(ctx, query), ErrReadOnlyViolation)
+
+				return nil
+			})
+
+			require.NoError(t, err)
+		})
+	}
+
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(db)))
+}
+
+func TestReadOnlyViolation_reads(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, Wrap(context.Background(), db, ReadOnly(), func(ctx context.Context) error {
+		assert.Equal(t, []string{"Alice", "Bob"}, names(t, ctx, NewDB(db)))
+
+		_, err := TempTable(ctx, "(id INTEGER)")
+
+		return err
+	}))
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := ExecContext(ctx, db, "INSERT INTO person (name) VALUES ('Carol')")
+
+		return err
+	}), "read-write transactions should not be checked")
+}
+
+func TestWithoutReadOnlyEnforcement(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, New(db, WithoutReadOnlyEnforcement()).Wrap(
+		context.Background(),
+		ReadOnly(),
+		func(ctx context.Context) error {
+			_, err := ExecContext(ctx, db, "INSERT INTO person (name) VALUES ('Carol')")
+
+			return err
+		},
+	), "SQLite accepts writes in read-only transactions")
+
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, context.Background(), NewDB(db)))
+}
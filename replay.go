@@ -0,0 +1,123 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// ErrCommitAmbiguous is returned when the connection failed at commit time,
+// so that the transaction may or may not have been committed.
+var ErrCommitAmbiguous = errors.New("txx: commit outcome unknown")
+
+const defaultReplayAttempts = 3
+
+// Replay configures the replay of transactions whose connection failed, see WithReplay.
+type Replay struct {
+	// MaxAttempts is the maximum number of attempts, including the first one, 3 if not positive.
+	MaxAttempts int
+	// ConnectionFailure classifies errors caused by a lost connection,
+	// driver.ErrBadConn and sql.ErrConnDone if nil.
+	ConnectionFailure func(err error) bool
+	// Backoff configures the sleeps between attempts, none by default, see RetryPolicy.
+	// Its MaxAttempts and Retryable are ignored, and its Clock defaults to the Clock of the Manager.
+	Backoff RetryPolicy
+}
+
+// WithReplay re-runs the function of a transaction in a new transaction when its connection fails,
+// like during a database failover.
+//
+// A transaction is replayed if a connection failure, as classified by the Replay config,
+// happens before commit, or at commit when the driver reports driver.ErrBadConn,
+// which drivers only do when certain the commit was not performed.
+// Any other connection failure at commit fails with ErrCommitAmbiguous, wrapping the commit error.
+// Replays stop as soon as the context is done, like retries of WrapRetry.
+//
+// The function must be idempotent across replays: only its database work is undone between attempts.
+func WithReplay(cfg Replay) Option {
+	return func(m *Manager) {
+		if cfg.MaxAttempts <= 0 {
+			cfg.MaxAttempts = defaultReplayAttempts
+		}
+
+		if cfg.ConnectionFailure == nil {
+			cfg.ConnectionFailure = isConnectionFailure
+		}
+
+		m.r.replay = &cfg
+	}
+}
+
+func isConnectionFailure(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+// attempts runs function f in a new transaction, replaying it according to the replay config if any.
+func (r runner) attempts(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	f func(ctx context.Context, tx transaction) error,
+) error {
+	if r.replay == nil {
+		return r.run(ctx, opts, f)
+	}
+
+	var (
+		attempt    int
+		replayable bool
+		policy     = r.replay.Backoff
+	)
+
+	policy.MaxAttempts = r.replay.MaxAttempts
+	policy.Retryable = func(error) bool {
+		return replayable
+	}
+
+	if policy.Clock == nil {
+		policy.Clock = r.getClock()
+	}
+
+	return policy.retry(ctx, func(ctx context.Context) error {
+		var (
+			last completion
+			once = r
+		)
+
+		attempt++
+		once.finish = func(c *completion) {
+			last = *c
+			replayable = attempt < r.replay.MaxAttempts && ctx.Err() == nil && r.replayable(c)
+			c.retrying = replayable && c.rolledBack
+
+			r.done(c)
+		}
+
+		err := once.run(ctx, opts, f)
+
+		// A failed commit is neither committed nor rolled back.
+		if last.tx != nil && !last.committed && !last.rolledBack {
+			err = last.err
+
+			// driver.ErrBadConn tells the commit was not sent: any other connection failure leaves it unknown
+			if !replayable && r.replay.ConnectionFailure(err) && !errors.Is(err, driver.ErrBadConn) {
+				err = fmt.Errorf("%w: %w", ErrCommitAmbiguous, err)
+			}
+		}
+
+		return err
+	})
+}
+
+// replayable reports if the transaction described by c can be replayed.
+func (r runner) replayable(c *completion) bool {
+	switch {
+	case c.committed || c.panicked || !r.replay.ConnectionFailure(c.err):
+		return false
+	case c.rolledBack:
+		return true
+	}
+
+	return errors.Is(c.err, driver.ErrBadConn)
+}
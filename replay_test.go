@@ -0,0 +1,161 @@
+package txx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReplay(t *testing.T) {
+	// The connection drops at the third statement of the first attempt.
+	backend := &fakeBackend{execs: []error{nil, nil, driver.ErrBadConn}}
+
+	var infos []Info
+
+	m := newManager(backend, WithReplay(Replay{}), WithFinally(func(info Info) {
+		infos = append(infos, info)
+	}))
+
+	var attempts int
+
+	require.NoError(t, m.r.attempts(context.Background(), nil, func(ctx context.Context, tx transaction) error {
+		attempts++
+
+		for _, query := range []string{"a", "b", "c"} {
+			if err := tx.Exec(ctx, query); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"begin", "a", "b", "c", "rollback", "begin", "a", "b", "c", "commit"}, backend.calls)
+	require.Len(t, infos, 2)
+	assert.Equal(t, CauseRetry, infos[0].Cause)
+	assert.True(t, infos[1].Committed)
+}
+
+func TestWithReplay_commit(t *testing.T) {
+	errOther := errors.New("other")            //nolint:goerr113
+	errReset := errors.New("connection reset") //nolint:goerr113
+	replay := Replay{ConnectionFailure: func(err error) bool {
+		return errors.Is(err, errReset) || errors.Is(err, driver.ErrBadConn)
+	}}
+
+	tests := []struct {
+		name         string
+		commits      []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{name: "not performed", commits: []error{driver.ErrBadConn}, wantAttempts: 2},
+		{name: "ambiguous", commits: []error{errReset}, wantErr: ErrCommitAmbiguous, wantAttempts: 1},
+		{name: "other", commits: []error{errOther}, wantErr: errOther, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{commits: tt.commits}
+			m := newManager(backend, WithReplay(replay))
+
+			var attempts int
+
+			err := m.Wrap(context.Background(), nil, func(_ context.Context) error {
+				attempts++
+
+				return nil
+			})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantAttempts, attempts)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.commits[0])
+			}
+		})
+	}
+}
+
+func TestWithReplay_bounded(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend, WithReplay(Replay{MaxAttempts: 2}))
+
+	var attempts int
+
+	err := m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		attempts++
+
+		return driver.ErrBadConn
+	})
+
+	require.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestWithReplay_exhausted(t *testing.T) {
+	backend := &fakeBackend{commits: []error{driver.ErrBadConn, driver.ErrBadConn}}
+	m := newManager(backend, WithReplay(Replay{MaxAttempts: 2}))
+
+	err := m.Wrap(context.Background(), nil, noop)
+
+	require.ErrorIs(t, err, driver.ErrBadConn)
+	assert.NotErrorIs(t, err, ErrCommitAmbiguous, "a commit not sent is not ambiguous")
+}
+
+func TestWithReplay_backoff(t *testing.T) {
+	backend := &fakeBackend{commits: []error{driver.ErrBadConn, driver.ErrBadConn}}
+
+	var sleeps []time.Duration
+
+	m := newManager(backend, WithReplay(Replay{Backoff: RetryPolicy{
+		InitialBackoff: time.Microsecond,
+		OnRetry: func(_ int, err error, sleep time.Duration) {
+			require.ErrorIs(t, err, driver.ErrBadConn)
+
+			sleeps = append(sleeps, sleep)
+		},
+	}}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	assert.Equal(t, []time.Duration{time.Microsecond, 2 * time.Microsecond}, sleeps)
+}
+
+func TestWithReplay_notReplayed(t *testing.T) {
+	errOther := errors.New("other") //nolint:goerr113
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{name: "other error", ctx: context.Background(), err: errOther},
+		{name: "canceled", ctx: ctx, err: driver.ErrBadConn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(&fakeBackend{}, WithReplay(Replay{}))
+
+			var attempts int
+
+			err := m.Wrap(tt.ctx, nil, func(_ context.Context) error {
+				attempts++
+				cancel()
+
+				return tt.err
+			})
+
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, 1, attempts)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryMultiplier = 2
+)
+
+// RetryPolicy configures the retries of WrapRetry.
+//
+// Attempts are retried right away unless InitialBackoff is set: the backoff is then multiplied
+// after every attempt, bounded by MaxBackoff, and randomized by Jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one, 3 if not positive.
+	MaxAttempts int
+	// Retryable classifies the errors worth retrying in a new transaction, IsSerializationFailure if nil.
+	Retryable func(err error) bool
+	// InitialBackoff is the sleep before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff bounds the backoff before jitter, unbounded if not positive.
+	MaxBackoff time.Duration
+	// Multiplier of the backoff after every attempt, 2 if less than 1.
+	Multiplier float64
+	// Jitter randomizes backoffs by up to this fraction, in both directions, like 0.2 for ±20%.
+	Jitter float64
+	// Rand returns pseudo-random numbers in [0, 1) for the jitter, math/rand/v2 Float64 if nil.
+	Rand func() float64
+	// OnRetry, if not nil, is called with the number and error of every failed attempt to be retried,
+	// and the sleep before the next one.
+	OnRetry func(attempt int, err error, sleep time.Duration)
+	// Clock sleeps between attempts, the Clock of the Manager or the time package if nil.
+	Clock Clock
+}
+
+// IsSerializationFailure reports if given error is a serialization failure or a deadlock,
+// the transaction being aborted to be retried.
+//
+// Drivers are not imported: PostgreSQL and CockroachDB errors are recognized by their SQLSTATE,
+// serialization_failure and deadlock_detected, and MySQL ones by their message.
+func IsSerializationFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var state interface{ SQLState() string }
+
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+
+	return strings.Contains(strings.ToLower(err.Error()), "deadlock found when trying to get lock") // MySQL 1213
+}
+
+// WrapRetry runs function f like Wrap, retrying it in a new transaction with given options
+// as long as it fails with a retryable error, according to given policy.
+//
+// Function f must be idempotent per attempt: only its database work is undone between attempts.
+// Retries stop as soon as the context is done, the error of the last attempt being returned,
+// wrapped with the context error if done while sleeping.
+func WrapRetry(
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+	policy RetryPolicy,
+) error {
+	return policy.retry(ctx, func(ctx context.Context) error {
+		return Wrap(ctx, db, opts, f)
+	})
+}
+
+// WrapRetry runs function f like Wrap, retrying it according to given policy.
+//
+// See the package-level WrapRetry function.
+func (m *Manager) WrapRetry(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+	policy RetryPolicy,
+) error {
+	if policy.Clock == nil {
+		policy.Clock = m.clock
+	}
+
+	return policy.retry(ctx, func(ctx context.Context) error {
+		return m.Wrap(ctx, opts, f)
+	})
+}
+
+// retry given attempt according to the policy.
+func (p RetryPolicy) retry(ctx context.Context, attempt func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryAttempts
+	}
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsSerializationFailure
+	}
+
+	for n := 1; ; n++ {
+		err := attempt(ctx)
+		if err == nil || n >= maxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		sleep := p.backoff(n)
+
+		if p.OnRetry != nil {
+			p.OnRetry(n, err, sleep)
+		}
+
+		if err = p.sleep(ctx, sleep, err); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the sleep after given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	backoff := float64(p.InitialBackoff)
+
+	for i := 1; i < attempt; i++ {
+		backoff *= multiplier
+
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		random := p.Rand
+		if random == nil {
+			random = rand.Float64 //nolint:gosec
+		}
+
+		backoff *= 1 + p.Jitter*(2*random()-1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// sleep for given duration, returning the context error wrapped with the error of the last attempt
+// if the context is done first.
+func (p RetryPolicy) sleep(ctx context.Context, d time.Duration, last error) error {
+	if d <= 0 {
+		return nil
+	}
+
+	c := p.Clock
+	if c == nil {
+		c = clock.Real{}
+	}
+
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("txx: retry interrupted: %w, last attempt: %w", ctx.Err(), last)
+	}
+}
@@ -0,0 +1,236 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyDB returns a database whose commits fail with a serialization failure until failures is exhausted.
+func flakyDB(t *testing.T, failures int32) (*sql.DB, *fakedb.Driver) {
+	t.Helper()
+
+	var left atomic.Int32
+
+	left.Store(failures)
+
+	d := &fakedb.Driver{
+		Commit: func() error {
+			if left.Add(-1) >= 0 {
+				return sqlStateError("40001")
+			}
+
+			return nil
+		},
+	}
+
+	return fakedb.Open(t, d), d
+}
+
+// countCalls returns the number of calls of given method made to d.
+func countCalls(d *fakedb.Driver, method string) int32 {
+	var n int32
+
+	for _, c := range d.Calls() {
+		if c.Method == method {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestWrapRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		policy       RetryPolicy
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{name: "success", failures: 0, wantAttempts: 1},
+		{name: "retried", failures: 2, wantAttempts: 3},
+		{name: "exhausted", failures: 3, wantErr: true, wantAttempts: 3},
+		{name: "max attempts", failures: 4, policy: RetryPolicy{MaxAttempts: 5}, wantAttempts: 5},
+		{
+			name:         "not retryable",
+			failures:     1,
+			policy:       RetryPolicy{Retryable: func(_ error) bool { return false }},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, d := flakyDB(t, tt.failures)
+
+			var attempts atomic.Int32
+
+			err := WrapRetry(context.Background(), db, nil, func(ctx context.Context) error {
+				attempts.Add(1)
+
+				return checkTxExists(ctx)
+			}, tt.policy)
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrCommitFailed)
+				assert.True(t, IsSerializationFailure(err))
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+			assert.Equal(t, tt.wantAttempts, countCalls(d, "commit"))
+		})
+	}
+}
+
+func TestWrapRetry_canceled(t *testing.T) {
+	db, _ := flakyDB(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var attempts int
+
+	err := WrapRetry(ctx, db, nil, func(_ context.Context) error {
+		attempts++
+
+		cancel()
+
+		return sqlStateError("40001")
+	}, RetryPolicy{MaxAttempts: 10})
+
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts, "retries should stop once the context is done")
+}
+
+func TestWrapRetry_backoff(t *testing.T) {
+	db, _ := flakyDB(t, 4)
+
+	var sleeps []time.Duration
+
+	policy := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Microsecond,
+		MaxBackoff:     5 * time.Microsecond,
+		Multiplier:     3,
+		Jitter:         0.2,
+		Rand:           func() float64 { return 0.75 },
+		OnRetry: func(attempt int, err error, sleep time.Duration) {
+			assert.Equal(t, len(sleeps)+1, attempt)
+			require.ErrorIs(t, err, ErrCommitFailed)
+
+			sleeps = append(sleeps, sleep)
+		},
+	}
+
+	require.NoError(t, WrapRetry(context.Background(), db, nil, checkTxExists, policy))
+	assert.Equal(t, []time.Duration{1100, 3300, 5500, 5500}, sleeps)
+}
+
+func TestWrapRetry_backoffCanceled(t *testing.T) {
+	db, d := flakyDB(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	policy := RetryPolicy{
+		InitialBackoff: time.Hour,
+		OnRetry: func(_ int, _ error, _ time.Duration) {
+			cancel()
+		},
+	}
+
+	err := WrapRetry(ctx, db, nil, checkTxExists, policy)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, err, ErrCommitFailed, "last transaction error should be wrapped")
+	assert.Equal(t, int32(1), countCalls(d, "commit"))
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration
+	}{
+		{name: "none", policy: RetryPolicy{}, want: []time.Duration{0, 0, 0}},
+		{
+			name:   "default multiplier",
+			policy: RetryPolicy{InitialBackoff: time.Second},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:   "bounded",
+			policy: RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 1.5},
+			want:   []time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond, 3 * time.Second},
+		},
+		{
+			name: "jitter",
+			policy: RetryPolicy{
+				InitialBackoff: time.Second,
+				Multiplier:     1,
+				Jitter:         0.5,
+				Rand:           sequence(0, 0.5, 0.99),
+			},
+			want: []time.Duration{500 * time.Millisecond, time.Second, 1490 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]time.Duration, len(tt.want))
+			for i := range got {
+				got[i] = tt.policy.backoff(i + 1)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// sequence returns a random source returning given numbers in order.
+func sequence(numbers ...float64) func() float64 {
+	return func() float64 {
+		n := numbers[0]
+		numbers = numbers[1:]
+
+		return n
+	}
+}
+
+func TestManager_WrapRetry(t *testing.T) {
+	db, d := flakyDB(t, 1)
+
+	require.NoError(t, New(db).WrapRetry(context.Background(), nil, checkTxExists, RetryPolicy{}))
+	assert.Equal(t, int32(2), countCalls(d, "commit"))
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil},
+		{err: sqlStateError("40001"), want: true},
+		{err: fmt.Errorf("wrapped: %w", sqlStateError("40P01")), want: true},
+		{err: sqlStateError("23505")},
+		{
+			err:  errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction"), //nolint:goerr113,lll
+			want: true,
+		},
+		{err: ErrStaleVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			assert.Equal(t, tt.want, IsSerializationFailure(tt.err))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+// ReadOnlyReuse decides if read-only work may reuse a current read-write transaction.
+type ReadOnlyReuse int
+
+const (
+	// ReuseStrict begins a new read-only transaction for read-only work in a read-write transaction.
+	//
+	// That new transaction doesn't see the uncommitted writes of the current one, and may wait for its locks.
+	ReuseStrict ReadOnlyReuse = iota
+	// ReuseReadWrite lets read-only work reuse a current read-write transaction, seeing its uncommitted writes.
+	//
+	// The reused transaction stays read-write: writes of the read-only work are not rejected.
+	ReuseReadWrite
+)
+
+var defaultReadOnlyReuse atomic.Int32 //nolint:gochecknoglobals
+
+// SetDefaultReadOnlyReuse sets the policy used when none is set in the context, ReuseStrict by default.
+func SetDefaultReadOnlyReuse(p ReadOnlyReuse) {
+	defaultReadOnlyReuse.Store(int32(p)) //nolint:gosec
+}
+
+type readOnlyReuseKey struct{}
+
+// SetReadOnlyReuse returns a copy of ctx whose read-only work follows given policy,
+// overriding the default one, see SetDefaultReadOnlyReuse.
+func SetReadOnlyReuse(ctx context.Context, p ReadOnlyReuse) context.Context {
+	return context.WithValue(ctx, readOnlyReuseKey{}, p)
+}
+
+// GetReadOnlyReuse returns the policy of read-only work in ctx, the default one if not set, see SetReadOnlyReuse.
+func GetReadOnlyReuse(ctx context.Context) ReadOnlyReuse {
+	if p, found := ctx.Value(readOnlyReuseKey{}).(ReadOnlyReuse); found {
+		return p
+	}
+
+	return ReadOnlyReuse(defaultReadOnlyReuse.Load())
+}
+
+// transactionRequired returns if a new transaction is required to match given options in given context,
+// LevelDefault resolving to given level.
+//
+// The Compatibility of the context if any, otherwise given one if not nil, decides if a current transaction
+// is reused, the ReadOnlyReuse policy of the context being followed otherwise.
+func transactionRequired(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	level sql.IsolationLevel,
+	compatible Compatibility,
+) bool {
+	current := Get(ctx)
+
+	if mode, found := lockingMode(ctx); found && (opts == nil || !opts.ReadOnly) &&
+		current.IsValid() && mode > current.s.getLocking() {
+		return true
+	}
+
+	if f, found := ctx.Value(compatibilityKey{}).(Compatibility); found {
+		compatible = f
+	}
+
+	if compatible != nil && current.IsValid() {
+		return !compatible(current, opts)
+	}
+
+	return current.newTransactionRequired(opts, level, GetReadOnlyReuse(ctx))
+}
+
+// Compatibility reports if the current transaction, always valid, can be reused for given requested options.
+type Compatibility func(current Current, requested *sql.TxOptions) bool
+
+// DefaultCompatibility is the Compatibility of transactions without WithCompatibility,
+// the opposite of Current.NewTransactionRequired.
+func DefaultCompatibility(current Current, requested *sql.TxOptions) bool {
+	return !current.NewTransactionRequired(requested)
+}
+
+// WithCompatibility overrides how the Manager decides whether Ensure and the like reuse a current transaction.
+//
+// Function f is only called when a transaction is current, given options merged with WithDefaultOptions.
+// It may delegate to DefaultCompatibility, which ignores the ReadOnlyReuse policy of the context.
+func WithCompatibility(f Compatibility) Option {
+	return func(m *Manager) {
+		m.compatible = f
+	}
+}
+
+type compatibilityKey struct{}
+
+// SetCompatibility returns a copy of ctx whose Ensure calls and the like decide whether to reuse
+// the current transaction with f, overriding WithCompatibility.
+func SetCompatibility(ctx context.Context, f Compatibility) context.Context {
+	return context.WithValue(ctx, compatibilityKey{}, f)
+}
+
+// transactionRequired returns if a new transaction is required to match given options in given context,
+// according to the Compatibility of the Manager if any.
+func (m *Manager) transactionRequired(ctx context.Context, opts *sql.TxOptions) bool {
+	return transactionRequired(ctx, opts, m.r.isolation, m.compatible)
+}
@@ -0,0 +1,125 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetReadOnlyReuse(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.Error(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		outer := Get(ctx)
+
+		_, err := ExecContext(ctx, db, "INSERT INTO person (id, name) VALUES (3, 'Carol')")
+		require.NoError(t, err)
+
+		require.NoError(t, Ensure(SetReadOnlyReuse(ctx, ReuseReadWrite), db, ReadOnly(), func(ctx context.Context) error {
+			assert.Same(t, outer.Tx, Get(ctx).Tx, "read-write transaction should be reused")
+
+			got, err := Select[string](ctx, db, "SELECT name FROM person ORDER BY id")
+			assert.Equal(t, []string{"Alice", "Bob", "Carol"}, got, "uncommitted insert should be seen")
+
+			return err
+		}))
+
+		require.NoError(t, Ensure(ctx, db, ReadOnly(), func(ctx context.Context) error {
+			assert.NotSame(t, outer.Tx, Get(ctx).Tx, "strict policy should begin a new transaction")
+
+			return nil
+		}))
+
+		return fail(ctx)
+	}))
+}
+
+func TestSetDefaultReadOnlyReuse(t *testing.T) {
+	SetDefaultReadOnlyReuse(ReuseReadWrite)
+	t.Cleanup(func() {
+		SetDefaultReadOnlyReuse(ReuseStrict)
+	})
+
+	readWrite := Current{Tx: &sql.Tx{}}
+	readOnly := Current{Tx: &sql.Tx{}, Opts: ReadOnly()}
+	ctx := Set(context.Background(), readWrite.Tx, nil)
+
+	assert.False(t, readWrite.NewTransactionRequired(ReadOnly()))
+	assert.False(t, transactionRequired(ctx, ReadOnly(), sql.LevelDefault, nil))
+	assert.True(t, readWrite.NewTransactionRequired(SerializableReadOnly()), "isolation should still be checked")
+	assert.True(t, readOnly.NewTransactionRequired(nil), "read-only transaction should not be reused for writes")
+	assert.True(
+		t,
+		transactionRequired(SetReadOnlyReuse(ctx, ReuseStrict), ReadOnly(), sql.LevelDefault, nil),
+		"context policy should override the default one",
+	)
+}
+
+func TestWithCompatibility(t *testing.T) {
+	always := func(_ Current, _ *sql.TxOptions) bool { return true }
+	never := func(_ Current, _ *sql.TxOptions) bool { return false }
+
+	tests := []struct {
+		name       string
+		compatible Compatibility
+		opts       *sql.TxOptions
+		want       []string
+	}{
+		{name: "default", compatible: DefaultCompatibility, opts: SerializableReadOnly(), want: []string{"begin", "begin"}},
+		{name: "default reuse", compatible: DefaultCompatibility, want: []string{"begin"}},
+		{name: "always", compatible: always, opts: SerializableReadOnly(), want: []string{"begin"}},
+		{name: "never", compatible: never, want: []string{"begin", "begin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+			m := newManager(backend, WithCompatibility(func(current Current, requested *sql.TxOptions) bool {
+				assert.True(t, current.IsValid(), "compatibility should only be checked in a transaction")
+
+				return tt.compatible(current, requested)
+			}))
+
+			require.NoError(t, m.Ensure(context.Background(), nil, func(ctx context.Context) error {
+				return m.Ensure(ctx, tt.opts, noop)
+			}))
+
+			var begins []string
+
+			for _, call := range backend.calls {
+				if call == "begin" {
+					begins = append(begins, call)
+				}
+			}
+
+			assert.Equal(t, tt.want, begins)
+		})
+	}
+}
+
+func TestSetCompatibility(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		outer := Get(ctx)
+		always := SetCompatibility(ctx, func(_ Current, _ *sql.TxOptions) bool { return true })
+
+		return Ensure(always, db, SerializableReadOnly(), checkTxEquals(outer.Tx))
+	}))
+
+	m := newManager(&fakeBackend{}, WithCompatibility(func(_ Current, _ *sql.TxOptions) bool { return true }))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		never := SetCompatibility(ctx, func(_ Current, _ *sql.TxOptions) bool { return false })
+
+		return m.Ensure(never, nil, func(inner context.Context) error {
+			assert.NotEqual(t, Get(ctx).ID(), Get(inner).ID(), "context should override the Manager")
+
+			return nil
+		})
+	}))
+}
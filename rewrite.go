@@ -0,0 +1,48 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRewrite wraps errors returned by the SQL rewrite hook, see WithRewriteSQL.
+var ErrRewrite = errors.New("txx: rewriting SQL")
+
+// WithRewriteSQL registers a hook rewriting the SQL of every statement executed or prepared
+// through the Current methods and the DB helpers in transactions of the Manager.
+//
+// The hook is the last step before the driver: it sees the query as given by the caller,
+// and the statement context records the rewritten query.
+// If the hook fails, the statement is not executed and fails with ErrRewrite.
+// Since a *sql.Row can't carry such an error, QueryRowContext then fails with context.Canceled on Scan.
+func WithRewriteSQL(f func(ctx context.Context, query string) (string, error)) Option {
+	return func(m *Manager) {
+		m.r.rewrite = f
+	}
+}
+
+type skipRewriteKey struct{}
+
+// SkipRewrite returns a copy of ctx whose statements are not rewritten, see WithRewriteSQL.
+func SkipRewrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipRewriteKey{}, true)
+}
+
+// rewriteSQL applies the rewrite hook of the transaction, if any, to given query.
+func (s *scope) rewriteSQL(ctx context.Context, query string) (string, error) {
+	if s == nil || s.rewrite == nil {
+		return query, nil
+	}
+
+	if skip, _ := ctx.Value(skipRewriteKey{}).(bool); skip {
+		return query, nil
+	}
+
+	result, err := s.rewrite(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrRewrite, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rewriting(t *testing.T) *Manager {
+	t.Helper()
+
+	errForbidden := errors.New("forbidden") //nolint:goerr113
+
+	db := testdb.Open(t, inMemory)
+
+	return New(db, WithStatementContext(), WithRewriteSQL(func(_ context.Context, query string) (string, error) {
+		if strings.Contains(query, "forbidden") {
+			return "", errForbidden
+		}
+
+		return strings.ReplaceAll(query, "{n}", "42"), nil
+	}))
+}
+
+func TestWithRewriteSQL(t *testing.T) {
+	m := rewriting(t)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		var n int
+
+		require.NoError(t, Get(ctx).QueryRowContext(ctx, "SELECT {n}").Scan(&n))
+		assert.Equal(t, 42, n)
+
+		rows, err := Get(ctx).QueryContext(ctx, "SELECT {n}")
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+
+		_, err = Get(ctx).ExecContext(ctx, "CREATE TABLE t{n} (id INTEGER)")
+		require.NoError(t, err)
+
+		stmt, err := NewDB(nil).PrepareContext(ctx, "SELECT id FROM t{n}")
+		require.NoError(t, err)
+
+		return stmt.Close()
+	}))
+}
+
+func TestWithRewriteSQL_recorded(t *testing.T) {
+	m := rewriting(t)
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "SELECT * FROM unknown_{n}")
+
+		return err
+	})
+
+	var sc *StatementContext
+
+	require.ErrorAs(t, err, &sc)
+	assert.Equal(t, "SELECT * FROM unknown_42", sc.Query)
+}
+
+func TestWithRewriteSQL_errors(t *testing.T) {
+	m := rewriting(t)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		_, err := current.ExecContext(ctx, "SELECT 'forbidden'")
+		require.ErrorIs(t, err, ErrRewrite)
+		assert.ErrorContains(t, err, "forbidden")
+
+		_, err = current.QueryContext(ctx, "SELECT 'forbidden'")
+		require.ErrorIs(t, err, ErrRewrite)
+
+		_, err = NewDB(nil).PrepareContext(ctx, "SELECT 'forbidden'")
+		require.ErrorIs(t, err, ErrRewrite)
+
+		var s string
+
+		require.ErrorIs(t, current.QueryRowContext(ctx, "SELECT 'forbidden'").Scan(&s), ErrRewrite)
+
+		return nil
+	}))
+}
+
+func TestSkipRewrite(t *testing.T) {
+	m := rewriting(t)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		var s string
+
+		require.NoError(t, Get(ctx).QueryRowContext(SkipRewrite(ctx), "SELECT 'forbidden {n}'").Scan(&s))
+		assert.Equal(t, "forbidden {n}", s)
+
+		return nil
+	}))
+}
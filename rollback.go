@@ -0,0 +1,82 @@
+package txx
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrRollback is returned by a transaction function, directly or wrapped, to roll back its transaction
+// while reporting success, like when there is nothing to do and provisional rows must be discarded.
+//
+// Wrap then rolls back and returns nil, its Info having Cause CauseRollbackOnly. A function run by Ensure
+// in a reused transaction marks it rollback-only instead, leaving its completion to the outer Wrap:
+// Ensure returns nil and the outer transaction is rolled back, even if committed by Commit.
+// A transaction stored by Set can't be marked: Ensure returns the error as is.
+var ErrRollback = errors.New("txx: rollback requested")
+
+// ErrRollbackOnly is returned by Wrap when its transaction was marked by SetRollbackOnly, see WithQuietRollbackOnly.
+var ErrRollbackOnly = errors.New("txx: transaction marked rollback-only")
+
+// SetRollbackOnly marks the transaction of ctx rollback-only: whatever else happens, it is rolled back
+// once the function of the Wrap owning it returns, even without error, and Commit rolls it back too.
+//
+// Any participant may mark it, like a function run by Ensure deep in a call stack, the mark being shared
+// by every function reusing the transaction. Wrap then returns ErrRollbackOnly, or nil with WithQuietRollbackOnly,
+// unless another error occurred. It returns ErrNoTransaction if the transaction was not begun by txx, like with Set.
+func SetRollbackOnly(ctx context.Context) error {
+	s := Get(ctx).s
+	if s == nil {
+		return ErrNoTransaction
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rollbackOnly == nil {
+		s.rollbackOnly = ErrRollbackOnly
+	}
+
+	return nil
+}
+
+// RollbackOnly reports if the transaction is marked rollback-only, by SetRollbackOnly, ErrRollback
+// or WithMaxStatements, so that it will be rolled back.
+func (c Current) RollbackOnly() bool {
+	return c.s != nil && c.s.getRollbackOnly() != nil
+}
+
+// WithQuietRollbackOnly makes Wrap return nil rather than ErrRollbackOnly for transactions
+// marked by SetRollbackOnly, reporting their rollback like the one of ErrRollback.
+func WithQuietRollbackOnly() Option {
+	return func(m *Manager) {
+		m.r.quietRollbackOnly = true
+	}
+}
+
+// markRollback marks the transaction rollback-only if err is ErrRollback, returning nil, err otherwise.
+func (s *scope) markRollback(err error) error {
+	if s == nil || !errors.Is(err, ErrRollback) {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rollbackOnly == nil {
+		s.rollbackOnly = err
+	}
+
+	return nil
+}
+
+// reusing adapts function f to run in a reused transaction, one frame deeper,
+// an ErrRollback marking it rollback-only, unless the transaction is aborted.
+func reusing(f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := Get(ctx).s.checkAborted(); err != nil {
+			return err
+		}
+
+		return Get(ctx).s.markRollback(f(deeper(ctx)))
+	}
+}
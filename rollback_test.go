@@ -0,0 +1,217 @@
+package txx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "direct", err: ErrRollback},
+		{name: "wrapped", err: fmt.Errorf("nothing to do: %w", ErrRollback)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+			var info Info
+
+			m := New(db, WithFinally(func(i Info) { info = i }))
+
+			require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				if err := insertPerson(ctx, db, "Carol"); err != nil {
+					return err
+				}
+
+				return tt.err
+			}))
+
+			assert.Equal(t, 2, countPeople(t, db), "transaction should be rolled back")
+			assert.False(t, info.Committed)
+			assert.Equal(t, CauseRollbackOnly, info.Cause)
+			require.NoError(t, info.Err)
+		})
+	}
+}
+
+func TestErrRollback_otherError(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		if err := insertPerson(ctx, db, "Carol"); err != nil {
+			return err
+		}
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrRollback)
+	assert.Equal(t, 2, countPeople(t, db))
+}
+
+func TestErrRollback_value(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	result, err := WrapValue(context.Background(), db, nil, func(_ context.Context) (int, error) {
+		return 42, ErrRollback
+	})
+
+	require.NoError(t, err)
+	assert.Zero(t, result, "value of a transaction not committed")
+}
+
+func TestErrRollback_reused(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	m := New(db)
+
+	ensures := map[string]func(ctx context.Context, f func(ctx context.Context) error) error{
+		"Ensure": func(ctx context.Context, f func(ctx context.Context) error) error {
+			return Ensure(ctx, db, nil, f)
+		},
+		"Manager.Ensure": func(ctx context.Context, f func(ctx context.Context) error) error {
+			return m.Ensure(ctx, nil, f)
+		},
+		"EnsureWith": func(ctx context.Context, f func(ctx context.Context) error) error {
+			return EnsureWith(ctx, db, nil, PropagationMandatory, f)
+		},
+	}
+
+	for name, ensure := range ensures {
+		t.Run(name, func(t *testing.T) {
+			err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				if err := insertPerson(ctx, db, "Carol"); err != nil {
+					return err
+				}
+
+				require.NoError(t, ensure(ctx, func(ctx context.Context) error {
+					if err := insertPerson(ctx, db, "Dave"); err != nil {
+						return err
+					}
+
+					return ErrRollback
+				}))
+
+				assert.True(t, Get(ctx).IsValid(), "outer transaction should not be rolled back yet")
+
+				return insertPerson(ctx, db, "Eve")
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, 2, countPeople(t, db), "outer transaction should be rolled back")
+		})
+	}
+}
+
+func TestErrRollback_reusedCommit(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, Ensure(ctx, db, nil, func(ctx context.Context) error {
+			if err := insertPerson(ctx, db, "Carol"); err != nil {
+				return err
+			}
+
+			return ErrRollback
+		}))
+
+		return Get(ctx).Commit(ctx)
+	}))
+
+	assert.Equal(t, 2, countPeople(t, db))
+}
+
+func TestSetRollbackOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "default", wantErr: ErrRollbackOnly},
+		{name: "quiet", opts: []Option{WithQuietRollbackOnly()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+			var info Info
+
+			m := New(db, append(tt.opts, WithFinally(func(i Info) { info = i }))...)
+
+			// ensure runs f three Ensure levels deep, the innermost one marking the transaction
+			var ensure func(ctx context.Context, depth int) error
+
+			ensure = func(ctx context.Context, depth int) error {
+				return m.Ensure(ctx, nil, func(ctx context.Context) error {
+					if err := insertPerson(ctx, db, fmt.Sprint("Level ", depth)); err != nil {
+						return err
+					}
+
+					if depth == 3 {
+						return SetRollbackOnly(ctx)
+					}
+
+					if err := ensure(ctx, depth+1); err != nil {
+						return err
+					}
+
+					assert.True(t, Get(ctx).RollbackOnly(), "mark should be visible at level %d", depth)
+
+					return nil
+				})
+			}
+
+			err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				assert.False(t, Get(ctx).RollbackOnly())
+
+				if err := ensure(ctx, 1); err != nil {
+					return err
+				}
+
+				assert.True(t, Get(ctx).RollbackOnly())
+
+				return nil
+			})
+
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+
+			assert.Equal(t, 2, countPeople(t, db), "transaction should be rolled back")
+			assert.False(t, info.Committed)
+			assert.Equal(t, CauseRollbackOnly, info.Cause)
+		})
+	}
+}
+
+func TestSetRollbackOnly_commit(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+		require.NoError(t, SetRollbackOnly(ctx))
+		require.ErrorIs(t, Get(ctx).Commit(ctx), ErrRollbackOnly)
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, ErrRollbackOnly)
+	assert.Equal(t, 2, countPeople(t, db))
+}
+
+func TestSetRollbackOnly_noTransaction(t *testing.T) {
+	require.ErrorIs(t, SetRollbackOnly(context.Background()), ErrNoTransaction)
+	assert.False(t, Current{}.RollbackOnly())
+}
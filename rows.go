@@ -0,0 +1,57 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+// Rows runs a query in the current transaction if any, or on db otherwise,
+// and lazily yields every row scanned into a T, see Select.
+//
+// The underlying rows are closed when the loop ends, including on early break, error or context cancellation.
+// An error is yielded at most once, as the last element.
+func Rows[T any](ctx context.Context, db *sql.DB, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := QuerierFor(ctx, db).QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+
+			return
+		}
+
+		defer rows.Close()
+
+		p, dest, err := planFor[T](rows)
+		if err != nil {
+			yield(zero, err)
+
+			return
+		}
+
+		for rows.Next() {
+			if err = ctx.Err(); err != nil {
+				yield(zero, err)
+
+				return
+			}
+
+			value, err := p.scan(rows, dest)
+			if err != nil {
+				yield(zero, err)
+
+				return
+			}
+
+			if !yield(value.Interface().(T), nil) { //nolint:forcetypeassert
+				return
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
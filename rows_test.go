@@ -0,0 +1,146 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numbersSchema creates the number table, of numbers and a string.
+const numbersSchema = `
+	CREATE TABLE number (n);
+	INSERT INTO number VALUES (1), (2), (3), ('four'), (5);
+`
+
+func assertNoLeak(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	assert.Equal(t, 0, db.Stats().InUse, "connection should be released")
+}
+
+func TestRows(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+
+	var got []int64
+
+	for n, err := range Rows[int64](context.Background(), db, "SELECT n FROM number WHERE n < 4 ORDER BY n") {
+		require.NoError(t, err)
+
+		got = append(got, n)
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, got)
+	assertNoLeak(t, db)
+}
+
+func TestRows_break(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+
+	var got []int64
+
+	for n, err := range Rows[int64](context.Background(), db, "SELECT n FROM number") {
+		require.NoError(t, err)
+
+		got = append(got, n)
+
+		if n == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []int64{1, 2}, got)
+	assertNoLeak(t, db)
+}
+
+func TestRows_scanError(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+
+	var (
+		got  []int64
+		errs []error
+	)
+
+	for n, err := range Rows[int64](context.Background(), db, "SELECT n FROM number") {
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		got = append(got, n)
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, got)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "scanning into int64")
+	assertNoLeak(t, db)
+}
+
+func TestRows_queryError(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+
+	for _, err := range Rows[int64](context.Background(), db, "SELECT * FROM missing") {
+		require.Error(t, err)
+	}
+
+	for _, err := range Rows[int64](context.Background(), db, "SELECT n, n FROM number") {
+		require.Error(t, err)
+	}
+
+	assertNoLeak(t, db)
+}
+
+func TestRows_canceled(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		got     []int64
+		lastErr error
+	)
+
+	for n, err := range Rows[int64](ctx, db, "SELECT n FROM number WHERE n <> 'four'") {
+		if err != nil {
+			lastErr = err
+
+			break
+		}
+
+		got = append(got, n)
+
+		cancel()
+	}
+
+	assert.Equal(t, []int64{1}, got)
+	require.ErrorIs(t, lastErr, context.Canceled)
+	assertNoLeak(t, db)
+}
+
+func TestRows_transaction(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(numbersSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "DELETE FROM number WHERE n <> 1")
+		require.NoError(t, err)
+
+		var got []int64
+
+		for n, err := range Rows[int64](ctx, db, "SELECT n FROM number") {
+			require.NoError(t, err)
+
+			got = append(got, n)
+		}
+
+		assert.Equal(t, []int64{1}, got)
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	assertNoLeak(t, db)
+}
@@ -0,0 +1,144 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+// Nested runs function f like Ensure, except that a reused transaction is protected by a savepoint:
+// if f returns an error, only its work is rolled back and the transaction remains usable,
+// otherwise the savepoint is released.
+//
+// Savepoints are named after the nesting depth, see Current.Nesting, like sp_1.
+// A panic of f is not recovered, aborting the whole transaction.
+func Nested(ctx context.Context, db Beginner, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	if transactionRequired(ctx, opts, sql.LevelDefault, nil) {
+		return Wrap(ctx, db, opts, f)
+	}
+
+	return runReused(nil, clock.Real{}, opts, Get(ctx).s.getLabels(), func() error {
+		return nested(ctx, f)
+	})
+}
+
+// Nested runs function f like Ensure, protecting a reused transaction by a savepoint.
+//
+// See the package-level Nested function.
+func (m *Manager) Nested(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	opts = m.options(opts)
+
+	if m.transactionRequired(ctx, opts) {
+		if err := m.checkIncompatible(ctx, opts); err != nil {
+			return err
+		}
+
+		return m.wrap(ctx, opts, f)
+	}
+
+	return m.reuseTx(ctx, opts, func(ctx context.Context) error {
+		return nested(ctx, f)
+	})
+}
+
+// nested runs function f in a savepoint of the current transaction.
+func nested(ctx context.Context, f func(ctx context.Context) error) error {
+	current := Get(ctx)
+	current.Nesting++
+	current.Depth++
+
+	name := fmt.Sprintf("sp_%d", current.Nesting)
+
+	var tx transaction = sqlTransaction{tx: current.Tx, strategy: TxStrategy{}}
+	if current.s != nil {
+		tx = current.s.tx
+	}
+
+	if err := current.s.flush(ctx); err != nil {
+		return err
+	}
+
+	if err := tx.Savepoint(ctx, name); err != nil {
+		return fmt.Errorf("txx: creating savepoint %s: %w", name, err)
+	}
+
+	mark := current.s.mark()
+
+	if err := f(context.WithValue(ctx, ctxKey, current)); err != nil {
+		current.s.discard()
+		current.s.invalidate()
+		current.s.rollbackTo(ctx, mark, err)
+
+		if rollbackErr := tx.RollbackTo(ctx, name); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: to savepoint %s: %w", ErrRollbackFailed, name, rollbackErr))
+		}
+
+		_ = tx.Release(ctx, name)
+
+		return err
+	}
+
+	if err := current.s.flush(ctx); err != nil {
+		return err
+	}
+
+	if err := tx.Release(ctx, name); err != nil {
+		return fmt.Errorf("txx: releasing savepoint %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// savepointMark is the number of functions registered by onComplete, OnCommit and OnRollback
+// when a savepoint is created.
+type savepointMark struct {
+	cleanups   int
+	onCommit   int
+	onRollback int
+}
+
+func (s *scope) mark() savepointMark {
+	if s == nil {
+		return savepointMark{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return savepointMark{cleanups: len(s.cleanups), onCommit: len(s.onCommit), onRollback: len(s.onRollback)}
+}
+
+// rollbackTo unregisters the functions registered by onComplete, OnCommit and OnRollback since given mark,
+// their work being rolled back to its savepoint, calling the OnRollback ones with given cause.
+func (s *scope) rollbackTo(ctx context.Context, mark savepointMark, cause error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+
+	if mark.cleanups < len(s.cleanups) {
+		s.cleanups = s.cleanups[:mark.cleanups]
+	}
+
+	if mark.onCommit < len(s.onCommit) {
+		s.onCommit = s.onCommit[:mark.onCommit]
+	}
+
+	var onRollback []func(ctx context.Context, cause error)
+
+	if mark.onRollback < len(s.onRollback) {
+		onRollback = append(onRollback, s.onRollback[mark.onRollback:]...)
+		s.onRollback = s.onRollback[:mark.onRollback]
+	}
+	s.mu.Unlock()
+
+	errs := rollbackCallbacks(ctx, onRollback, cause)
+
+	s.mu.Lock()
+	s.callbackErrs = append(s.callbackErrs, errs...)
+	s.mu.Unlock()
+}
@@ -0,0 +1,130 @@
+package txx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNested(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+
+		err := Nested(ctx, db, nil, func(ctx context.Context) error {
+			require.NoError(t, insertPerson(ctx, db, "Dave"))
+
+			return fail(ctx)
+		})
+		require.Error(t, err)
+
+		require.NoError(t, Nested(ctx, db, nil, func(ctx context.Context) error {
+			return insertPerson(ctx, db, "Eve")
+		}))
+
+		return insertPerson(ctx, db, "Frank")
+	}))
+
+	names, err := Select[string](context.Background(), db, "SELECT name FROM person ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol", "Eve", "Frank"}, names)
+}
+
+func TestNested_noTransaction(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, Nested(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, checkTxExists(ctx))
+		assert.Zero(t, Get(ctx).Nesting)
+
+		return insertPerson(ctx, db, "Carol")
+	}))
+
+	require.Error(t, Nested(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Dave"))
+
+		return fail(ctx)
+	}))
+
+	assert.Equal(t, 3, countPeople(t, db))
+}
+
+func TestManager_Nested(t *testing.T) {
+	backend := &fakeBackend{}
+	m := newManager(backend)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		assert.Zero(t, Get(ctx).Nesting)
+
+		require.NoError(t, m.Nested(ctx, nil, func(ctx context.Context) error {
+			assert.Equal(t, 1, Get(ctx).Nesting)
+
+			require.Error(t, m.Nested(ctx, nil, func(ctx context.Context) error {
+				assert.Equal(t, 2, Get(ctx).Nesting)
+
+				return fail(ctx)
+			}))
+
+			return m.Nested(ctx, nil, func(ctx context.Context) error {
+				assert.Equal(t, 2, Get(ctx).Nesting)
+
+				return nil
+			})
+		}))
+
+		assert.Zero(t, Get(ctx).Nesting)
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{
+		"begin",
+		"savepoint sp_1",
+		"savepoint sp_2", "rollback to sp_2", "release sp_2",
+		"savepoint sp_2", "release sp_2",
+		"release sp_1",
+		"commit",
+	}, backend.calls)
+}
+
+func TestNested_set(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+
+	ctx := Set(context.Background(), tx, nil)
+
+	require.Error(t, Nested(ctx, db, nil, func(ctx context.Context) error {
+		require.NoError(t, insertPerson(ctx, db, "Carol"))
+
+		return fail(ctx)
+	}))
+	require.NoError(t, insertPerson(ctx, db, "Dave"))
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, 3, countPeople(t, db))
+}
+
+func TestNested_tempTable(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.Error(t, Nested(ctx, db, nil, func(ctx context.Context) error {
+			if _, err := TempTable(ctx, "(id INTEGER)"); err != nil {
+				return err
+			}
+
+			return fail(ctx)
+		}))
+
+		return nil
+	}), "temporary table rolled back to the savepoint should not be dropped")
+
+	assert.Zero(t, countTempTables(t, db))
+}
@@ -0,0 +1,195 @@
+package txx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// plan scans rows into values of a type T.
+type plan struct {
+	typ    reflect.Type // T
+	elem   reflect.Type // T, or the type pointed to if T is a pointer
+	fields [][]int      // struct field index by column, nil for scalars
+}
+
+// newPlan returns a plan scanning given columns into values of type typ.
+//
+// Flat structs are mapped by db tag, or case-insensitive field name;
+// any other type is scanned from a single column.
+func newPlan(typ reflect.Type, columns []string) (*plan, error) {
+	p := &plan{typ: typ, elem: typ}
+
+	if typ.Kind() == reflect.Pointer {
+		p.elem = typ.Elem()
+	}
+
+	if !isStruct(p.elem) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("txx: scanning %d columns into %v, expected 1", len(columns), typ) //nolint:goerr113
+		}
+
+		return p, nil
+	}
+
+	p.fields = make([][]int, len(columns))
+
+	for i, column := range columns {
+		index, found := findField(p.elem, column)
+		if !found {
+			return nil, fmt.Errorf("txx: column %q has no matching field in %v", column, typ) //nolint:goerr113
+		}
+
+		p.fields[i] = index
+	}
+
+	return p, nil
+}
+
+func isStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+
+	return !reflect.PointerTo(typ).Implements(scannerType)
+}
+
+func findField(typ reflect.Type, column string) ([]int, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, tagged := field.Tag.Lookup("db")
+		if name == "-" {
+			continue
+		}
+
+		if tagged && name != "" {
+			if name == column {
+				return field.Index, true
+			}
+		} else if strings.EqualFold(field.Name, column) {
+			return field.Index, true
+		}
+	}
+
+	return nil, false
+}
+
+// scan the current row into a new value.
+func (p *plan) scan(rows *sql.Rows, dest []any) (reflect.Value, error) {
+	if p.fields == nil {
+		value := reflect.New(p.typ)
+		dest[0] = value.Interface()
+
+		if err := rows.Scan(dest...); err != nil {
+			return reflect.Value{}, fmt.Errorf("txx: scanning into %v: %w", p.typ, err)
+		}
+
+		return value.Elem(), nil
+	}
+
+	elem := reflect.New(p.elem)
+
+	for i, index := range p.fields {
+		dest[i] = elem.Elem().FieldByIndex(index).Addr().Interface()
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return reflect.Value{}, fmt.Errorf("txx: scanning into %v: %w", p.typ, err)
+	}
+
+	if p.typ.Kind() == reflect.Pointer {
+		return elem, nil
+	}
+
+	return elem.Elem(), nil
+}
+
+// planFor returns a plan scanning given rows into values of type T.
+func planFor[T any](rows *sql.Rows) (*plan, []any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := newPlan(reflect.TypeOf((*T)(nil)).Elem(), columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, make([]any, len(columns)), nil
+}
+
+// scanAll scans and closes given rows into a slice of T.
+func scanAll[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	p, dest, err := planFor[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []T
+
+	for rows.Next() {
+		value, err := p.scan(rows, dest)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, value.Interface().(T)) //nolint:forcetypeassert
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, rows.Close()
+}
+
+// scanOne scans and closes given rows into a single T.
+//
+// It returns ErrNotFound if there is no row, and ErrTooManyRows if there is more than one.
+func scanOne[T any](rows *sql.Rows) (T, error) {
+	defer rows.Close()
+
+	var zero T
+
+	p, dest, err := planFor[T](rows)
+	if err != nil {
+		return zero, err
+	}
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return zero, err
+		}
+
+		return zero, ErrNotFound
+	}
+
+	value, err := p.scan(rows, dest)
+	if err != nil {
+		return zero, err
+	}
+
+	if rows.Next() {
+		return zero, ErrTooManyRows
+	}
+
+	if err = rows.Err(); err != nil {
+		return zero, err
+	}
+
+	return value.Interface().(T), rows.Close() //nolint:forcetypeassert
+}
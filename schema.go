@@ -0,0 +1,51 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrInvalidSchema is returned when the schema of a transaction is not a valid identifier, see WithSchema.
+var ErrInvalidSchema = errors.New("txx: invalid schema")
+
+var schemaPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`) //nolint:gochecknoglobals
+
+// WithSchema sets the PostgreSQL search_path of every transaction to the schema returned by resolve,
+// for schema-per-tenant databases.
+//
+// The search_path is set with SET LOCAL right after begin, so it is scoped to the transaction
+// and can't change while nested Ensure calls reuse it. The schema is reported in Info.
+//
+// The transaction fails if resolve fails or returns an identifier other than letters, digits
+// and underscores not starting with a digit, in which case ErrInvalidSchema is returned.
+func WithSchema(resolve func(ctx context.Context) (string, error)) Option {
+	return func(m *Manager) {
+		m.r.setups = append(m.r.setups, setup{
+			apply: func(ctx context.Context, tx transaction, s *scope) error {
+				schema, err := resolve(ctx)
+				if err != nil {
+					return fmt.Errorf("txx: resolving schema: %w", err)
+				}
+
+				if !schemaPattern.MatchString(schema) {
+					return fmt.Errorf("%w: %q", ErrInvalidSchema, schema)
+				}
+
+				if err = tx.Exec(ctx, setSearchPath(schema)); err != nil {
+					return err
+				}
+
+				s.schema = schema
+
+				return nil
+			},
+		})
+	}
+}
+
+// setSearchPath returns the statement setting the search path to given valid schema.
+func setSearchPath(schema string) string {
+	return `SET LOCAL search_path TO "` + schema + `"`
+}
@@ -0,0 +1,91 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaKey struct{}
+
+func resolveSchema(ctx context.Context) (string, error) {
+	schema, ok := ctx.Value(schemaKey{}).(string)
+	if !ok {
+		return "", errors.New("no tenant") //nolint:goerr113
+	}
+
+	return schema, nil
+}
+
+func TestWithSchema(t *testing.T) {
+	backend := &fakeBackend{}
+
+	var infos []Info
+
+	m := newManager(backend, WithSchema(resolveSchema), WithFinally(func(info Info) {
+		infos = append(infos, info)
+	}))
+	ctx := context.WithValue(context.Background(), schemaKey{}, "tenant_1")
+
+	require.NoError(t, m.Wrap(ctx, nil, func(ctx context.Context) error {
+		return m.Ensure(context.WithValue(ctx, schemaKey{}, "tenant_2"), nil, noop)
+	}))
+
+	assert.Equal(t, []string{"begin", `SET LOCAL search_path TO "tenant_1"`, "commit"}, backend.calls)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "tenant_1", infos[0].Schema)
+}
+
+func TestWithSchema_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema any
+		want   error
+	}{
+		{name: "unresolved"},
+		{name: "empty", schema: "", want: ErrInvalidSchema},
+		{name: "digit", schema: "1tenant", want: ErrInvalidSchema},
+		{name: "quote", schema: `tenant"; DROP TABLE users; --`, want: ErrInvalidSchema},
+		{name: "dot", schema: "public.tenant", want: ErrInvalidSchema},
+		{name: "space", schema: "tenant ", want: ErrInvalidSchema},
+		{name: "unicode", schema: "tenanté", want: ErrInvalidSchema},
+		{name: "long", schema: "t123456789012345678901234567890123456789012345678901234567890123", want: ErrInvalidSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+			m := newManager(backend, WithSchema(resolveSchema))
+			ctx := context.Background()
+
+			if tt.schema != nil {
+				ctx = context.WithValue(ctx, schemaKey{}, tt.schema)
+			}
+
+			err := m.Wrap(ctx, nil, noop)
+
+			require.Error(t, err)
+
+			if tt.want != nil {
+				require.ErrorIs(t, err, tt.want)
+			}
+
+			assert.Equal(t, []string{"begin", "rollback"}, backend.calls)
+		})
+	}
+}
+
+func TestWithSchema_valid(t *testing.T) {
+	for _, schema := range []string{"t", "_t", "Tenant_42", "t12345678901234567890123456789012345678901234567890123456789012"} {
+		t.Run(schema, func(t *testing.T) {
+			backend := &fakeBackend{}
+			m := newManager(backend, WithSchema(resolveSchema))
+
+			require.NoError(t, m.Wrap(context.WithValue(context.Background(), schemaKey{}, schema), nil, noop))
+			assert.Equal(t, []string{"begin", setSearchPath(schema), "commit"}, backend.calls)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// scope is the mutable state shared by every frame of a transaction.
+type scope struct {
+	// id identifies the transaction.
+	id uint64
+	// startedAt is when the transaction was begun, according to clock, see Current.Duration.
+	startedAt time.Time
+	clock     Clock
+	// tx is the transaction, nil for snapshots.
+	tx         transaction
+	statements bool
+	// redact statement arguments, redactType if nil.
+	redact func(i int, v any) any
+	// snapshot rejects writes, the transaction being shared by a SnapshotPool.
+	snapshot bool
+	// readOnly rejects writes, see ErrReadOnlyViolation.
+	readOnly bool
+	// dryRun rolls back instead of committing, see DryRun.
+	dryRun bool
+	// quiet reports a rollback-only transaction marked by SetRollbackOnly as a success, see WithQuietRollbackOnly.
+	quiet bool
+	// rewrite, if not nil, rewrites the SQL of statements.
+	rewrite func(ctx context.Context, query string) (string, error)
+	// explain, if not nil, captures the plans of slow statements.
+	explain *Explain
+	// schema set as search path by WithSchema, if any.
+	schema string
+	// dialect of the database, see WithDialect.
+	dialect Dialect
+	// asOf is the AS OF SYSTEM TIME expression set by WithAsOfSystemTime, if any.
+	asOf string
+	// labels extracted by WithLabelsFromContext, if any.
+	labels map[string]string
+	// bufferLimit is the maximum number of parameters of statements flushed by ExecBuffered, see maxParams.
+	bufferLimit int
+	// memoLimit is the maximum number of memoized rows, memoizing being disabled if not positive.
+	memoLimit int
+	// maxStatements executed through Current, unlimited if not positive, see WithMaxStatements.
+	maxStatements int
+	// strict rejects concurrent statements, see WithStrictConcurrency.
+	strict bool
+	// locking is the locking mode the transaction was begun with, see WithLockingMode.
+	locking LockingMode
+	// isolation is the level LevelDefault resolves to, see WithDefaultIsolation.
+	isolation sql.IsolationLevel
+	// owner is the goroutine that began the transaction, if strict.
+	owner owner
+
+	mu    sync.Mutex
+	count int
+	last  *StatementContext
+	stmts map[*sql.Stmt]*sql.Stmt // bound by StmtFor
+	// prepared are the statements cached by Prepare, by query.
+	prepared map[string]*sql.Stmt
+	// memo holds memoized query results, see WithMemoizedSelects.
+	memo     map[memoKey]any
+	memoSize int
+	// values stored by SetValue, cleared by close.
+	values map[any]any
+	// cleanups run right before completion, see onComplete.
+	cleanups []func(ctx context.Context) error
+	// rows returned by queries and maybe not closed yet, if strict.
+	rows []openRows
+	// onCommit are the callbacks registered by OnCommit.
+	onCommit []func(ctx context.Context)
+	// onRollback are the callbacks registered by OnRollback.
+	onRollback []func(ctx context.Context, cause error)
+	// calledBack is set once the callbacks are called, with the cause of a rollback, see OnRollback.
+	calledBack bool
+	cause      error
+	// callbackErrs are the panics of OnRollback callbacks of rolled back savepoints, see Nested.
+	callbackErrs []error
+	// pending rows buffered by ExecBuffered, if any, and the number of rows buffered so far.
+	pending      *execBuffer
+	bufferedRows int
+	// rollbackOnly is why the transaction must be rolled back, if it must.
+	rollbackOnly error
+	// aborted rejects statements, see MarkAborted.
+	aborted bool
+	// finishing is set once end begins.
+	finishing bool
+	// txDone is set once a statement fails with sql.ErrTxDone, the transaction being finished outside of txx.
+	txDone bool
+	// ending is set once the transaction is committed or rolled back, after flush and cleanups, see end.
+	ending *ending
+	closed bool
+	// done is closed by close, see Done.
+	done    chan struct{}
+	outcome Info
+}
+
+// record a statement about to be executed in the transaction,
+// returning an error if it must not be executed, see WithMaxStatements.
+func (s *scope) record(query string, args []any) error {
+	if s == nil || (!s.statements && s.maxStatements <= 0) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.countStatement(); err != nil {
+		return err
+	}
+
+	if !s.statements {
+		return nil
+	}
+
+	redact := s.redact
+	if redact == nil {
+		redact = redactType
+	}
+
+	var redacted []any
+
+	if len(args) > 0 {
+		redacted = make([]any, len(args))
+		for i, arg := range args {
+			redacted[i] = redact(i, arg)
+		}
+	}
+
+	s.last = &StatementContext{
+		Index:   s.count,
+		Query:   query,
+		NumArgs: len(args),
+		Args:    redacted,
+	}
+
+	return nil
+}
+
+// getDialect returns the dialect of the transaction, DialectGeneric if not begun by txx.
+func (s *scope) getDialect() Dialect {
+	if s == nil {
+		return DialectGeneric
+	}
+
+	return s.dialect
+}
+
+// now returns the current time according to the clock of the transaction, the time package if not begun by txx.
+func (s *scope) now() time.Time {
+	if s == nil || s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
+
+// checkWrite returns an error if writes are not allowed in the transaction.
+func (s *scope) checkWrite() error {
+	if s != nil && s.snapshot {
+		return ErrSnapshotWrite
+	}
+
+	return nil
+}
+
+// checkWriteQuery returns an error if given query is a write while writes are not allowed in the transaction,
+// see isWrite.
+func (s *scope) checkWriteQuery(query string) error {
+	if _, write := isWrite(query); write {
+		return s.checkWrite()
+	}
+
+	return nil
+}
+
+// wrap given error with the last recorded statement, if any.
+func (s *scope) wrap(err error) error {
+	if err == nil || s == nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		return err
+	}
+
+	result := *s.last
+	result.Err = err
+
+	return &result
+}
+
+// stmt returns given statement bound to tx, caching it.
+func (s *scope) stmt(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt) *sql.Stmt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bound, found := s.stmts[stmt]; found {
+		return bound
+	}
+
+	if s.stmts == nil {
+		s.stmts = make(map[*sql.Stmt]*sql.Stmt)
+	}
+
+	bound := tx.StmtContext(ctx, stmt)
+	s.stmts[stmt] = bound
+
+	return bound
+}
+
+// prepare returns the statement of given query prepared on current, caching it.
+func (s *scope) prepare(ctx context.Context, current Current, query string) (*sql.Stmt, error) {
+	s.mu.Lock()
+	prepared, found := s.prepared[query]
+	s.mu.Unlock()
+
+	if found {
+		return prepared, nil
+	}
+
+	// prepared unlocked, PrepareContext flushing buffered rows through the scope
+	prepared, err := current.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prepared == nil {
+		s.prepared = make(map[string]*sql.Stmt)
+	}
+
+	s.prepared[query] = prepared
+
+	return prepared, nil
+}
+
+// close releases the resources of a completed transaction, given its outcome.
+func (s *scope) close(outcome Info) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bound := range s.stmts {
+		_ = bound.Close()
+	}
+
+	for _, prepared := range s.prepared {
+		_ = prepared.Close()
+	}
+
+	s.stmts = nil
+	s.prepared = nil
+	s.values = nil
+	s.closed = true
+	s.outcome = outcome
+
+	if s.done != nil {
+		close(s.done)
+	}
+}
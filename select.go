@@ -0,0 +1,80 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when a query expected to return a row returns none.
+	//
+	// It wraps sql.ErrNoRows.
+	ErrNotFound = fmt.Errorf("txx: not found: %w", sql.ErrNoRows)
+	// ErrTooManyRows is returned when a query expected to return a single row returns more.
+	ErrTooManyRows = errors.New("txx: too many rows")
+)
+
+// Select runs a query in the current transaction if any, or on db otherwise,
+// and scans every row into a T.
+//
+// T is either a flat struct whose fields are matched to columns by db tag or case-insensitive name,
+// or any other type scanned from a single column, like int64 or sql.NullString.
+func Select[T any](ctx context.Context, db *sql.DB, query string, args ...any) ([]T, error) {
+	return memoized(ctx, false, query, args, func() ([]T, error) {
+		rows, err := QuerierFor(ctx, db).QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		return scanAll[T](rows)
+	})
+}
+
+// QueryOne runs a query in the current transaction if any, or on db otherwise,
+// and scans its single row into a T, see Select.
+//
+// It returns ErrNotFound if there is no row, and ErrTooManyRows if there is more than one.
+func QueryOne[T any](ctx context.Context, db *sql.DB, query string, args ...any) (T, error) {
+	result, err := memoized(ctx, true, query, args, func() ([]T, error) {
+		rows, err := QuerierFor(ctx, db).QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := scanOne[T](rows)
+
+		return []T{value}, err
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return result[0], nil
+}
+
+// ExecReturning executes a statement with a RETURNING clause in the current transaction if any,
+// or on db otherwise, and scans its single returned row into a T, see Select.
+//
+// It returns ErrNotFound if the statement returned nothing.
+func ExecReturning[T any](ctx context.Context, db *sql.DB, query string, args ...any) (T, error) {
+	if err := Get(ctx).s.checkWrite(); err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	Get(ctx).s.invalidate()
+
+	rows, err := QuerierFor(ctx, db).QueryContext(ctx, query, args...)
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return scanOne[T](rows)
+}
@@ -0,0 +1,215 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type person struct {
+	ID       int64  `db:"id"`
+	Name     string `db:"name"`
+	Nickname *string
+	Email    sql.NullString `db:"email"`
+	Ignored  string         `db:"-"`
+	private  string
+}
+
+// peopleSchema creates the person table, of Alice and Bob.
+const peopleSchema = `
+	CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL, nickname TEXT, email TEXT);
+	INSERT INTO person VALUES (1, 'Alice', 'Al', 'alice@example.com'), (2, 'Bob', NULL, NULL);
+`
+
+func TestSelect_struct(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	got, err := Select[person](context.Background(), db, "SELECT id, name, nickname, email FROM person ORDER BY id")
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	assert.Equal(t, int64(1), got[0].ID)
+	assert.Equal(t, "Alice", got[0].Name)
+	require.NotNil(t, got[0].Nickname)
+	assert.Equal(t, "Al", *got[0].Nickname)
+	assert.Equal(t, sql.NullString{String: "alice@example.com", Valid: true}, got[0].Email)
+
+	assert.Nil(t, got[1].Nickname)
+	assert.False(t, got[1].Email.Valid)
+	assert.Empty(t, got[1].Ignored)
+	assert.Empty(t, got[1].private)
+}
+
+func TestSelect_pointer(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	got, err := Select[*person](context.Background(), db, "SELECT name FROM person WHERE id = ?", 2)
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "Bob", got[0].Name)
+}
+
+func TestSelect_scalar(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	ids, err := Select[int64](context.Background(), db, "SELECT id FROM person ORDER BY id")
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	nicknames, err := Select[*string](context.Background(), db, "SELECT nickname FROM person ORDER BY id")
+
+	require.NoError(t, err)
+	require.Len(t, nicknames, 2)
+	assert.Equal(t, "Al", *nicknames[0])
+	assert.Nil(t, nicknames[1])
+
+	emails, err := Select[sql.NullString](context.Background(), db, "SELECT email FROM person ORDER BY id")
+
+	require.NoError(t, err)
+	assert.Equal(t, []sql.NullString{{String: "alice@example.com", Valid: true}, {}}, emails)
+
+	none, err := Select[int64](context.Background(), db, "SELECT id FROM person WHERE id < 0")
+
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestSelect_errors(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	_, err := Select[person](ctx, db, "SELECT id, name AS unknown FROM person")
+	require.ErrorContains(t, err, `column "unknown"`)
+
+	_, err = Select[int64](ctx, db, "SELECT id, name FROM person")
+	require.ErrorContains(t, err, "scanning 2 columns")
+
+	_, err = Select[int64](ctx, db, "SELECT name FROM person")
+	require.ErrorContains(t, err, `name "name"`)
+
+	_, err = Select[string](ctx, db, "SELECT nickname FROM person")
+	require.Error(t, err, "NULL into a non nullable type")
+
+	_, err = Select[int64](ctx, db, "SELECT * FROM missing")
+	require.Error(t, err)
+}
+
+func TestSelect_transaction(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (id, name) VALUES (3, 'Carol')")
+		require.NoError(t, err)
+
+		inside, err := Select[string](ctx, db, "SELECT name FROM person ORDER BY id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob", "Carol"}, inside)
+
+		outside, err := Select[string](context.Background(), db, "SELECT name FROM person ORDER BY id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob"}, outside)
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+}
+
+func TestQueryOne(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	got, err := QueryOne[person](ctx, db, "SELECT id, name FROM person WHERE id = ?", 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, person{ID: 1, Name: "Alice"}, got)
+
+	_, err = QueryOne[person](ctx, db, "SELECT id, name FROM person WHERE id = ?", 42)
+
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = QueryOne[int64](ctx, db, "SELECT id FROM person")
+
+	require.ErrorIs(t, err, ErrTooManyRows)
+
+	_, err = QueryOne[int64](ctx, db, "SELECT name FROM person WHERE id = 1")
+
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrNotFound)
+
+	_, err = QueryOne[int64](ctx, db, "SELECT * FROM missing")
+
+	require.Error(t, err)
+}
+
+func TestQueryOne_transaction(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (id, name) VALUES (3, 'Carol')")
+		require.NoError(t, err)
+
+		name, err := QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = 3")
+		require.NoError(t, err)
+		assert.Equal(t, "Carol", name)
+
+		_, err = QueryOne[string](context.Background(), db, "SELECT name FROM person WHERE id = 3")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = QueryOne[string](ctx, db, "SELECT name FROM person")
+		require.ErrorIs(t, err, ErrTooManyRows)
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+}
+
+func TestExecReturning(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	id, err := ExecReturning[int64](ctx, db, "INSERT INTO person (name) VALUES (?) RETURNING id", "Carol")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+
+	got, err := ExecReturning[person](ctx, db, "UPDATE person SET name = 'Caroline' WHERE id = ? RETURNING id, name", id)
+
+	require.NoError(t, err)
+	assert.Equal(t, person{ID: 3, Name: "Caroline"}, got)
+
+	_, err = ExecReturning[int64](ctx, db, "DELETE FROM person WHERE id = 42 RETURNING id")
+
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestExecReturning_rollback(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		id, err := ExecReturning[int64](ctx, db, "INSERT INTO person (name) VALUES (?) RETURNING id", "Carol")
+		require.NoError(t, err)
+
+		name, err := QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", id)
+		require.NoError(t, err)
+		assert.Equal(t, "Carol", name)
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+
+	names, err := Select[string](context.Background(), db, "SELECT name FROM person ORDER BY id")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, names)
+}
@@ -0,0 +1,86 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Slow configures the detection of slow transactions, see WithSlowTransactions.
+type Slow struct {
+	// Threshold is the total duration above which a transaction is slow, disabling the detection if not positive.
+	Threshold time.Duration
+	// Open detects slow transactions while still open, by a timer firing once the threshold is exceeded,
+	// instead of once completed.
+	Open bool
+	// Handle is called once per slow transaction.
+	Handle func(ctx context.Context, tx SlowTransaction)
+}
+
+// SlowTransaction describes a slow transaction, see WithSlowTransactions.
+type SlowTransaction struct {
+	// ID identifies the transaction, like Info.ID.
+	ID   uint64
+	Opts *sql.TxOptions
+	// Duration of the transaction so far, if Open, otherwise up to its commit or rollback.
+	Duration time.Duration
+	// Open reports if the transaction is still open.
+	Open bool
+}
+
+// WithSlowTransactions calls a handler for every transaction of the Manager lasting longer than a threshold,
+// from begin to its commit or rollback, see SetSlowTransactions to override it.
+//
+// With Slow.Open, the handler is called from the goroutine of a timer while the transaction is still open,
+// concurrently with its function. The timer is stopped once the transaction is committed or rolled back,
+// before OnCommit and OnRollback callbacks: Wrap waits for a running handler before returning,
+// so that no goroutine is left behind. The handler is not called again on completion.
+func WithSlowTransactions(cfg Slow) Option {
+	return func(m *Manager) {
+		m.r.slow = &cfg
+	}
+}
+
+type slowKey struct{}
+
+// SetSlowTransactions returns a copy of ctx whose transactions are detected as slow according to cfg,
+// overriding the configuration of the Manager, see WithSlowTransactions.
+//
+// A zero Slow disables the detection.
+func SetSlowTransactions(ctx context.Context, cfg Slow) context.Context {
+	return context.WithValue(ctx, slowKey{}, cfg)
+}
+
+// watchSlow detects if the transaction begun at given time is slow,
+// returning the function to call with its duration once it is committed or rolled back.
+func (r runner) watchSlow(ctx context.Context, id uint64, opts *sql.TxOptions, start time.Time) func(d time.Duration) {
+	cfg, found := ctx.Value(slowKey{}).(Slow)
+	if !found && r.slow != nil {
+		cfg = *r.slow
+	}
+
+	if cfg.Threshold <= 0 || cfg.Handle == nil {
+		return func(time.Duration) {}
+	}
+
+	if !cfg.Open {
+		return func(d time.Duration) {
+			if d > cfg.Threshold {
+				cfg.Handle(ctx, SlowTransaction{ID: id, Opts: opts, Duration: d})
+			}
+		}
+	}
+
+	fired := make(chan struct{})
+	timer := r.getClock().AfterFunc(cfg.Threshold-r.now().Sub(start), func() {
+		defer close(fired)
+
+		cfg.Handle(ctx, SlowTransaction{ID: id, Opts: opts, Duration: r.now().Sub(start), Open: true})
+	})
+
+	return func(time.Duration) {
+		if !timer.Stop() {
+			<-fired
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package txx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowRecorder records the slow transactions handled.
+type slowRecorder struct {
+	mu   sync.Mutex
+	txs  []SlowTransaction
+	done chan struct{}
+}
+
+func newSlowRecorder() *slowRecorder {
+	return &slowRecorder{done: make(chan struct{}, 1)}
+}
+
+func (r *slowRecorder) handle(_ context.Context, tx SlowTransaction) {
+	r.mu.Lock()
+	r.txs = append(r.txs, tx)
+	r.mu.Unlock()
+
+	select {
+	case r.done <- struct{}{}:
+	default:
+	}
+}
+
+func (r *slowRecorder) recorded() []SlowTransaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SlowTransaction(nil), r.txs...)
+}
+
+// advance returns a transaction function advancing given clock by duration d.
+func advance(c *clock.Fake, d time.Duration) func(ctx context.Context) error {
+	return func(_ context.Context) error {
+		c.Advance(d)
+
+		return nil
+	}
+}
+
+func TestWithSlowTransactions(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	rec := newSlowRecorder()
+	m := New(
+		testdb.Open(t, inMemory),
+		WithClock(c),
+		WithSlowTransactions(Slow{Threshold: 20 * time.Millisecond, Handle: rec.handle}),
+	)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+	assert.Empty(t, rec.recorded())
+
+	var id uint64
+
+	require.NoError(t, m.Wrap(context.Background(), ReadOnly(), func(ctx context.Context) error {
+		id = Get(ctx).ID()
+
+		return advance(c, 40*time.Millisecond)(ctx)
+	}))
+
+	txs := rec.recorded()
+	require.Len(t, txs, 1)
+
+	assert.Equal(t, id, txs[0].ID)
+	assert.Equal(t, ReadOnly(), txs[0].Opts)
+	assert.False(t, txs[0].Open)
+	assert.Equal(t, 40*time.Millisecond, txs[0].Duration)
+}
+
+func TestWithSlowTransactions_open(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	rec := newSlowRecorder()
+	m := New(
+		testdb.Open(t, inMemory),
+		WithClock(c),
+		WithSlowTransactions(Slow{Threshold: 20 * time.Millisecond, Open: true, Handle: rec.handle}),
+	)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		c.Advance(20 * time.Millisecond)
+
+		select {
+		case <-rec.done:
+		default:
+			t.Error("slow transaction should be handled while open")
+		}
+
+		return nil
+	}))
+
+	c.Advance(40 * time.Millisecond)
+
+	txs := rec.recorded()
+	require.Len(t, txs, 1, "slow transaction should be handled once")
+
+	assert.True(t, txs[0].Open)
+	assert.Equal(t, 20*time.Millisecond, txs[0].Duration)
+}
+
+func TestWithSlowTransactions_stopped(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	rec := newSlowRecorder()
+	m := New(
+		testdb.Open(t, inMemory),
+		WithClock(c),
+		WithSlowTransactions(Slow{Threshold: time.Second, Open: true, Handle: rec.handle}),
+	)
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(_ context.Context) error {
+		assert.Equal(t, 1, c.Timers())
+
+		return nil
+	}))
+
+	assert.Zero(t, c.Timers(), "timer should be stopped on completion")
+
+	c.Advance(time.Minute)
+	assert.Empty(t, rec.recorded())
+}
+
+func TestSetSlowTransactions(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	rec, override := newSlowRecorder(), newSlowRecorder()
+	m := New(
+		testdb.Open(t, inMemory),
+		WithClock(c),
+		WithSlowTransactions(Slow{Threshold: time.Hour, Handle: rec.handle}),
+	)
+
+	// any transaction on the time package is slower than a nanosecond
+	ctx := SetSlowTransactions(context.Background(), Slow{Threshold: time.Nanosecond, Handle: override.handle})
+	require.NoError(t, m.Wrap(ctx, nil, advance(c, 10*time.Millisecond)))
+
+	require.NoError(t, Wrap(ctx, testdb.Open(t, inMemory), nil, noop))
+
+	ctx = SetSlowTransactions(context.Background(), Slow{})
+	m = New(
+		testdb.Open(t, inMemory),
+		WithClock(c),
+		WithSlowTransactions(Slow{Threshold: time.Millisecond, Handle: rec.handle}),
+	)
+	require.NoError(t, m.Wrap(ctx, nil, advance(c, 10*time.Millisecond)))
+
+	assert.Empty(t, rec.recorded())
+	assert.Len(t, override.recorded(), 2, "package-level Wrap should follow the context")
+}
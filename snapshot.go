@@ -0,0 +1,156 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSnapshotWrite is returned when a write is executed through a shared snapshot transaction.
+var ErrSnapshotWrite = errors.New("txx: write in a snapshot transaction")
+
+// SnapshotPool shares a long-lived read-only transaction between requests,
+// so that many independent reads get a consistent view without beginning a transaction each.
+//
+// The shared transaction is replaced every refresh interval.
+// A replaced transaction is only rolled back once every request using it is done,
+// so that data seen through a snapshot is at most one interval plus the request duration old.
+//
+// A SnapshotPool is safe for concurrent use.
+type SnapshotPool struct {
+	m        *Manager
+	interval time.Duration
+	opts     *sql.TxOptions
+
+	mu      sync.Mutex
+	current *snapshot
+	timer   Timer
+	closed  bool
+}
+
+type snapshot struct {
+	tx   transaction
+	s    *scope
+	refs int
+	// retired reports if the snapshot has been replaced, to be rolled back once unused.
+	retired bool
+}
+
+// NewSnapshotPool begins a shared snapshot transaction on the database of given Manager,
+// replaced every interval according to the Manager Clock.
+//
+// Snapshot transactions are repeatable read and read-only. Given context only carries values to begin the first one:
+// its cancellation doesn't roll it back.
+func NewSnapshotPool(ctx context.Context, m *Manager, interval time.Duration) (*SnapshotPool, error) {
+	p := &SnapshotPool{
+		m:        m,
+		interval: interval,
+		opts:     &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+	}
+
+	// the snapshot outlives ctx, which would roll it back once done
+	tx, err := m.r.b.begin(context.WithoutCancel(ctx), p.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.current = newSnapshot(tx)
+	p.timer = m.clock.AfterFunc(interval, p.refresh)
+
+	return p, nil
+}
+
+// WithSnapshot returns a copy of ctx carrying the current shared snapshot transaction.
+//
+// The snapshot is held until ctx is done, so ctx must be canceled once the request completes.
+// Writes through the Current methods of a snapshot fail with ErrSnapshotWrite.
+// Once the pool is closed, ctx is returned as is.
+func (p *SnapshotPool) WithSnapshot(ctx context.Context) context.Context {
+	p.mu.Lock()
+	snap := p.current
+
+	if snap == nil {
+		p.mu.Unlock()
+
+		return ctx
+	}
+
+	snap.refs++
+	p.mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		p.release(snap)
+	})
+
+	return snap.tx.bind(ctx, p.opts, snap.s)
+}
+
+// Close stops refreshing and rolls back the shared snapshot transaction once unused.
+func (p *SnapshotPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	p.closed = true
+	p.timer.Stop()
+	p.retire(p.current)
+	p.current = nil
+}
+
+// refresh replaces the current snapshot, keeping it if a new one can't be begun.
+func (p *SnapshotPool) refresh() {
+	tx, err := p.m.r.b.begin(context.Background(), p.opts)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		if err == nil {
+			_ = tx.Rollback(context.Background())
+		}
+
+		return
+	}
+
+	p.timer = p.m.clock.AfterFunc(p.interval, p.refresh)
+
+	if err != nil {
+		return
+	}
+
+	p.retire(p.current)
+	p.current = newSnapshot(tx)
+}
+
+func (p *SnapshotPool) release(snap *snapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap.refs--
+
+	if snap.retired && snap.refs == 0 {
+		snap.rollback()
+	}
+}
+
+func (p *SnapshotPool) retire(snap *snapshot) {
+	snap.retired = true
+
+	if snap.refs == 0 {
+		snap.rollback()
+	}
+}
+
+func newSnapshot(tx transaction) *snapshot {
+	return &snapshot{tx: tx, s: &scope{snapshot: true}}
+}
+
+func (snap *snapshot) rollback() {
+	snap.s.close(completion{tx: snap.tx, rolledBack: true}.info())
+	_ = snap.tx.Rollback(context.Background())
+}
@@ -0,0 +1,228 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemSchema creates the item table.
+const itemSchema = "CREATE TABLE item (id INTEGER)"
+
+func items(t *testing.T, ctx context.Context) int {
+	t.Helper()
+
+	var n int
+
+	require.NoError(t, Get(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM item").Scan(&n))
+
+	return n
+}
+
+func insertItem(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	_, err := db.Exec("INSERT INTO item (id) VALUES (1)")
+	require.NoError(t, err)
+}
+
+func snapshotPool(t *testing.T, db *sql.DB) (*SnapshotPool, *clock.Fake) {
+	t.Helper()
+
+	c := clock.NewFake(time.Unix(0, 0))
+
+	p, err := NewSnapshotPool(context.Background(), New(db, WithClock(c)), time.Minute)
+	require.NoError(t, err)
+
+	t.Cleanup(p.Close)
+
+	return p, c
+}
+
+func TestSnapshotPool_refresh(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(itemSchema))
+	p, c := snapshotPool(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snap := p.WithSnapshot(ctx)
+
+	require.True(t, IsInTx(snap))
+	assert.Equal(t, 0, items(t, snap))
+
+	insertItem(t, db)
+
+	c.Advance(30 * time.Second)
+
+	assert.Equal(t, 0, items(t, p.WithSnapshot(ctx)), "snapshot should not be refreshed yet")
+
+	c.Advance(30 * time.Second)
+
+	assert.Equal(t, 1, items(t, p.WithSnapshot(ctx)), "snapshot should be refreshed")
+	assert.Equal(t, 0, items(t, snap), "held snapshot should stay consistent")
+	assert.Equal(t, 1, c.Timers())
+}
+
+func TestSnapshotPool_refs(t *testing.T) {
+	p, c := snapshotPool(t, testdb.Open(t, onDisk, testdb.WithSchema(itemSchema)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	snap := p.WithSnapshot(ctx)
+	tx := Get(snap).Tx
+
+	c.Advance(time.Minute)
+
+	assert.NotEqual(t, tx, Get(p.WithSnapshot(context.Background())).Tx)
+	assert.Equal(t, 0, items(t, snap), "retired snapshot should wait for its readers")
+
+	cancel()
+
+	require.Eventually(t, func() bool {
+		return tx.Rollback() != nil
+	}, time.Second, time.Millisecond, "retired snapshot should be rolled back once unused")
+}
+
+func TestSnapshotPool_write(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(itemSchema))
+	p, _ := snapshotPool(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snap := p.WithSnapshot(ctx)
+
+	_, err := Get(snap).ExecContext(snap, "INSERT INTO item (id) VALUES (1)")
+	require.ErrorIs(t, err, ErrSnapshotWrite)
+
+	_, err = NewDB(db).ExecContext(snap, "INSERT INTO item (id) VALUES (1)")
+	require.ErrorIs(t, err, ErrSnapshotWrite)
+
+	require.NoError(t, New(db).Ensure(snap, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO item (id) VALUES (1)")
+
+		return err
+	}), "a read-write transaction should be begun")
+}
+
+func TestSnapshotPool_Close(t *testing.T) {
+	p, c := snapshotPool(t, testdb.Open(t, onDisk, testdb.WithSchema(itemSchema)))
+
+	tx := Get(p.WithSnapshot(context.Background())).Tx
+
+	p.Close()
+	p.Close()
+
+	assert.Equal(t, 0, c.Timers())
+	assert.False(t, IsInTx(p.WithSnapshot(context.Background())))
+	assert.NoError(t, tx.Rollback(), "snapshot in use should not be rolled back")
+}
+
+func TestNewSnapshotPool_canceled(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(itemSchema))
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := &watchedContext{Context: parent}
+
+	p, err := NewSnapshotPool(ctx, New(db), time.Minute)
+	require.NoError(t, err)
+
+	t.Cleanup(p.Close)
+
+	// database/sql rolls back asynchronously once the context it watches is done
+	assert.False(t, ctx.watched.Load(), "the cancellation of ctx should not be watched")
+	cancel()
+
+	snap, done := context.WithCancel(context.Background())
+	defer done()
+
+	assert.Equal(t, 0, items(t, p.WithSnapshot(snap)), "snapshot should outlive the context of the pool")
+}
+
+// watchedContext records whether its cancellation is watched.
+type watchedContext struct {
+	context.Context
+	watched atomic.Bool
+}
+
+func (c *watchedContext) Done() <-chan struct{} {
+	c.watched.Store(true)
+
+	return c.Context.Done()
+}
+
+func TestSnapshotPool_writeMethods(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(itemSchema))
+	p, _ := snapshotPool(t, db)
+
+	const insert = "INSERT INTO item (id) VALUES (1) RETURNING id"
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{
+			name: "exec",
+			run: func(ctx context.Context) error {
+				_, err := Get(ctx).ExecContext(ctx, insert)
+
+				return err
+			},
+		},
+		{
+			name: "query",
+			run: func(ctx context.Context) error {
+				rows, err := Get(ctx).QueryContext(ctx, insert)
+				if err == nil {
+					_ = rows.Close()
+				}
+
+				return err
+			},
+		},
+		{
+			name: "query row",
+			run: func(ctx context.Context) error {
+				var id int
+
+				return Get(ctx).QueryRowContext(ctx, insert).Scan(&id)
+			},
+		},
+		{
+			name: "prepare",
+			run: func(ctx context.Context) error {
+				_, err := Get(ctx).PrepareContext(ctx, insert)
+
+				return err
+			},
+		},
+		{
+			name: "exec returning",
+			run: func(ctx context.Context) error {
+				_, err := ExecReturning[int](ctx, db, insert)
+
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			require.ErrorIs(t, tt.run(p.WithSnapshot(ctx)), ErrSnapshotWrite)
+		})
+	}
+
+	var n int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM item").Scan(&n))
+	assert.Zero(t, n, "nothing should be written")
+}
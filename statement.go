@@ -0,0 +1,327 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// StatementContext is the error returned when statement context is enabled,
+// carrying the last statement executed in the failed transaction.
+//
+// Use errors.As to extract it.
+type StatementContext struct {
+	// Index of the statement in the transaction, starting at 1.
+	Index int
+	// Query is the SQL text of the statement, without its arguments.
+	Query string
+	// NumArgs is the number of arguments of the statement.
+	NumArgs int
+	// Args of the statement, redacted as placeholders of their type unless configured otherwise,
+	// see WithUnsafeArgLogging and WithArgRedactor.
+	Args []any
+	// Err is the transaction error.
+	Err error
+}
+
+func (e *StatementContext) Error() string {
+	if len(e.Args) > 0 {
+		return fmt.Sprintf("%v (statement #%d: %s, args: %v)", e.Err, e.Index, e.Query, e.Args)
+	}
+
+	return fmt.Sprintf("%v (statement #%d: %s)", e.Err, e.Index, e.Query)
+}
+
+func (e *StatementContext) Unwrap() error {
+	return e.Err
+}
+
+// WithStatementContext enables the capture of statements executed through Current methods,
+// so that callback and commit errors are wrapped in a StatementContext.
+//
+// SQL text may be sensitive. Arguments are redacted: only their type is captured,
+// unless WithUnsafeArgLogging or WithArgRedactor is used.
+func WithStatementContext() Option {
+	return func(m *Manager) {
+		m.r.statements = true
+	}
+}
+
+// WithUnsafeArgLogging captures the values of statement arguments as is.
+//
+// Arguments may hold personal data or secrets: it is meant for development only.
+func WithUnsafeArgLogging() Option {
+	return WithArgRedactor(func(_ int, v any) any {
+		return v
+	})
+}
+
+// WithArgRedactor captures statement arguments transformed by given function,
+// called with the index and value of every argument, like to hash an email rather than drop it.
+func WithArgRedactor(redact func(i int, v any) any) Option {
+	return func(m *Manager) {
+		m.r.redact = redact
+	}
+}
+
+// redactType is the default redactor, replacing arguments by a placeholder of their type.
+func redactType(_ int, v any) any {
+	if v == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("<%T>", v)
+}
+
+// ExecContext executes a query without returning any rows in the current transaction.
+func (c Current) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := c.s.checkFinished(); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkWrite(); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	query, err := c.s.rewriteSQL(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.s.invalidate()
+
+	if err = c.s.record(query, args); err != nil {
+		return nil, err
+	}
+
+	if err = c.s.use(); err != nil {
+		return nil, err
+	}
+
+	start := c.s.now()
+
+	result, err := c.Tx.ExecContext(ctx, query, args...)
+	if err == nil {
+		c.s.explainSlow(ctx, c.Tx, query, args, start)
+	}
+
+	return result, c.s.finished(err)
+}
+
+// QueryContext executes a query returning rows in the current transaction.
+func (c Current) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := c.s.checkFinished(); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkWriteQuery(query); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	query, err := c.s.rewriteSQL(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.s.record(query, args); err != nil {
+		return nil, err
+	}
+
+	if err = c.s.use(); err != nil {
+		return nil, err
+	}
+
+	start := c.s.now()
+
+	rows, err := c.Tx.QueryContext(ctx, query, args...)
+	if err == nil {
+		c.s.explainSlow(ctx, c.Tx, query, args, start)
+		c.s.opened(rows)
+	}
+
+	return rows, c.s.finished(err)
+}
+
+// QueryRowContext executes a query returning at most one row in the current transaction.
+func (c Current) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	err := c.s.checkFinished()
+	if err == nil {
+		err = c.s.checkWriteQuery(query)
+	}
+
+	if err == nil {
+		err = c.s.checkReadOnly(query)
+	}
+
+	if err == nil {
+		err = c.s.flush(ctx)
+	}
+
+	if err == nil {
+		query, err = c.s.rewriteSQL(ctx, query)
+	}
+
+	if err == nil {
+		err = c.s.record(query, args)
+	}
+
+	if err == nil {
+		err = c.s.use()
+	}
+
+	if err != nil {
+		return errRow(err)
+	}
+
+	start := c.s.now()
+	row := c.Tx.QueryRowContext(ctx, query, args...)
+
+	if err := row.Err(); err == nil {
+		c.s.explainSlow(ctx, c.Tx, query, args, start)
+	} else {
+		_ = c.s.finished(err)
+	}
+
+	return row
+}
+
+// errRow returns a row whose error is given error, a *sql.Row being only built by database/sql.
+func errRow(err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err: err})
+	defer db.Close()
+
+	return db.QueryRowContext(context.Background(), "")
+}
+
+// errConnector connects to a database whose queries fail with err, see errRow.
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return errConn(c), nil
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c errConnector) Open(_ string) (driver.Conn, error) {
+	return errConn(c), nil
+}
+
+type errConn struct {
+	err error
+}
+
+func (c errConn) QueryContext(_ context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	return nil, c.err
+}
+
+func (c errConn) Prepare(_ string) (driver.Stmt, error) {
+	return nil, c.err
+}
+
+func (c errConn) Close() error {
+	return nil
+}
+
+func (c errConn) Begin() (driver.Tx, error) {
+	return nil, c.err
+}
+
+// PrepareContext creates a prepared statement bound to the current transaction.
+//
+// The statement is closed when the transaction completes.
+func (c Current) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := c.s.checkFinished(); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkWriteQuery(query); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+
+	if err := c.s.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	query, err := c.s.rewriteSQL(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.s.use(); err != nil {
+		return nil, err
+	}
+
+	stmt, err := c.Tx.PrepareContext(ctx, query)
+
+	return stmt, c.s.finished(err)
+}
+
+// Executor returns the current transaction as a Querier if valid, nil otherwise, see QuerierFor.
+func (c Current) Executor() Querier {
+	if !c.IsValid() {
+		return nil
+	}
+
+	return c
+}
+
+// StmtFor returns given statement bound to the current transaction if any, the statement itself otherwise.
+//
+// Inside a transaction begun by Wrap, the bound statement is cached so that repeated calls don't rebind it,
+// and it is closed when the transaction completes.
+func StmtFor(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	current := Get(ctx)
+	if !current.IsValid() {
+		return stmt
+	}
+
+	if current.s == nil {
+		return current.Tx.StmtContext(ctx, stmt)
+	}
+
+	return current.s.stmt(ctx, current.Tx, stmt)
+}
+
+// Prepare returns a statement of given query, prepared once per transaction.
+//
+// Inside a transaction begun by Wrap, the statement is prepared on first call, through PrepareContext,
+// and cached by query text, so that loops don't prepare it again: it is closed when the transaction completes,
+// and must not be closed by the caller. Outside of any transaction, it is prepared on db and owned by the caller,
+// who must close it.
+func Prepare(ctx context.Context, db Querier, query string) (*sql.Stmt, error) {
+	current := Get(ctx)
+	if !current.IsValid() {
+		return db.PrepareContext(ctx, query)
+	}
+
+	if current.s == nil {
+		return current.PrepareContext(ctx, query)
+	}
+
+	return current.s.prepare(ctx, current, query)
+}
@@ -0,0 +1,306 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batch(ctx context.Context) error {
+	current := Get(ctx)
+
+	for _, query := range []string{
+		"CREATE TABLE item (id INTEGER PRIMARY KEY)",
+		"INSERT INTO item (id) VALUES (1)",
+		"INSERT INTO item (id) VALUES (1)",
+		"INSERT INTO item (id) VALUES (2)",
+	} {
+		if _, err := current.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestWithStatementContext(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithStatementContext())
+
+	err := m.Wrap(context.Background(), nil, batch)
+
+	var sc *StatementContext
+
+	require.ErrorAs(t, err, &sc)
+	assert.Equal(t, 3, sc.Index)
+	assert.Equal(t, "INSERT INTO item (id) VALUES (1)", sc.Query)
+	assert.Equal(t, 0, sc.NumArgs)
+	assert.Contains(t, err.Error(), "statement #3")
+	assert.Equal(t, errors.Unwrap(err), sc.Err)
+}
+
+func TestWithStatementContext_disabled(t *testing.T) {
+	m := New(testdb.Open(t, inMemory))
+
+	err := m.Wrap(context.Background(), nil, batch)
+
+	var sc *StatementContext
+
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &sc))
+}
+
+func TestWithStatementContext_noStatement(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithStatementContext())
+
+	err := m.Wrap(context.Background(), nil, fail)
+
+	var sc *StatementContext
+
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &sc))
+}
+
+func TestWithStatementContext_args(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []any
+	}{
+		{
+			name: "redacted",
+			want: []any{"<int64>", "<string>", "<nil>"},
+		},
+		{
+			name: "unsafe",
+			opts: []Option{WithUnsafeArgLogging()},
+			want: []any{int64(1), "alice@example.com", nil},
+		},
+		{
+			name: "redactor",
+			opts: []Option{WithArgRedactor(func(i int, v any) any {
+				if i == 1 {
+					return "hash:" + strings.ToUpper(v.(string)) //nolint:forcetypeassert
+				}
+
+				return v
+			})},
+			want: []any{int64(1), "hash:ALICE@EXAMPLE.COM", nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(testdb.Open(t, inMemory), append([]Option{WithStatementContext()}, tt.opts...)...)
+
+			err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				_, err := Get(ctx).ExecContext(ctx, "INSERT INTO missing VALUES (?, ?, ?)", int64(1), "alice@example.com", nil)
+
+				return err
+			})
+
+			var sc *StatementContext
+
+			require.ErrorAs(t, err, &sc)
+			assert.Equal(t, 3, sc.NumArgs)
+			assert.Equal(t, tt.want, sc.Args)
+
+			if len(tt.opts) == 0 {
+				assert.NotContains(t, err.Error(), "alice")
+				assert.Contains(t, err.Error(), "args: [<int64> <string> <nil>]")
+			}
+		})
+	}
+}
+
+func TestCurrent_QueryContext(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithStatementContext())
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		var n int
+
+		current := Get(ctx)
+
+		rows, err := current.QueryContext(ctx, "SELECT ?", 1)
+		if err != nil {
+			return err
+		}
+
+		_ = rows.Close()
+
+		if err = current.QueryRowContext(ctx, "SELECT 1").Scan(&n); err != nil {
+			return err
+		}
+
+		_, err = current.QueryContext(ctx, "SELECT * FROM missing WHERE id = ?", 1)
+
+		return err
+	})
+
+	var sc *StatementContext
+
+	require.ErrorAs(t, err, &sc)
+	assert.Equal(t, 3, sc.Index)
+	assert.Equal(t, 1, sc.NumArgs)
+}
+
+func TestStmtFor(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO person (name) VALUES (?)")
+	require.NoError(t, err)
+
+	defer stmt.Close()
+
+	assert.Same(t, stmt, StmtFor(ctx, stmt))
+
+	var bound *sql.Stmt
+
+	err = Wrap(ctx, db, nil, func(ctx context.Context) error {
+		bound = StmtFor(ctx, stmt)
+
+		assert.NotSame(t, stmt, bound)
+		assert.Same(t, bound, StmtFor(ctx, stmt), "binding should be cached")
+
+		_, err := bound.ExecContext(ctx, "Carol")
+		require.NoError(t, err)
+
+		return fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, ctx, db))
+
+	_, err = bound.ExecContext(ctx, "Dave")
+	require.Error(t, err, "bound statement should be closed")
+
+	_, err = stmt.ExecContext(ctx, "Dave")
+	require.NoError(t, err, "original statement should still be usable")
+}
+
+func TestStmtFor_set(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	stmt, err := db.PrepareContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+
+	defer stmt.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	defer tx.Rollback() //nolint:errcheck
+
+	assert.NotSame(t, stmt, StmtFor(Set(ctx, tx, nil), stmt))
+}
+
+func TestPrepare(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	var stmt *sql.Stmt
+
+	require.NoError(t, Wrap(ctx, db, nil, func(ctx context.Context) error {
+		for _, name := range []string{"Carol", "Dave"} {
+			got, err := Prepare(ctx, db, "INSERT INTO person (name) VALUES (?)")
+			require.NoError(t, err)
+
+			if stmt != nil {
+				assert.Same(t, stmt, got, "statement should be cached")
+			}
+
+			stmt = got
+
+			_, err = stmt.ExecContext(ctx, name)
+			require.NoError(t, err)
+		}
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"Alice", "Bob", "Carol", "Dave"}, names(t, ctx, db))
+
+	_, err := stmt.ExecContext(ctx, "Eve")
+	require.Error(t, err, "statement should be closed")
+	require.NoError(t, stmt.Close(), "closing again should be harmless")
+}
+
+func TestPrepare_noTransaction(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	first, err := Prepare(ctx, db, "SELECT 1")
+	require.NoError(t, err)
+
+	defer first.Close()
+
+	second, err := Prepare(ctx, db, "SELECT 1")
+	require.NoError(t, err)
+
+	defer second.Close()
+
+	assert.NotSame(t, first, second)
+}
+
+func BenchmarkPrepare(b *testing.B) {
+	const rows = 1000
+
+	db, err := sql.Open("sqlite", filepath.Join(b.TempDir(), "bench.db"))
+	require.NoError(b, err)
+
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(b, err)
+
+	ctx := context.Background()
+
+	b.Run("uncached", func(b *testing.B) {
+		for range b.N {
+			require.NoError(b, Wrap(ctx, db, nil, func(ctx context.Context) error {
+				for range rows {
+					stmt, err := Get(ctx).PrepareContext(ctx, "INSERT INTO person (name) VALUES (?)")
+					if err != nil {
+						return err
+					}
+
+					_, err = stmt.ExecContext(ctx, "Carol")
+					_ = stmt.Close()
+
+					if err != nil {
+						return err
+					}
+				}
+
+				return nil
+			}))
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for range b.N {
+			require.NoError(b, Wrap(ctx, db, nil, func(ctx context.Context) error {
+				for range rows {
+					stmt, err := Prepare(ctx, db, "INSERT INTO person (name) VALUES (?)")
+					if err != nil {
+						return err
+					}
+
+					if _, err = stmt.ExecContext(ctx, "Carol"); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			}))
+		}
+	})
+}
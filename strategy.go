@@ -0,0 +1,117 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Strategy begins and completes the transactions of a Manager, see WithStrategy.
+type Strategy interface {
+	// Begin a transaction on db with given options.
+	//
+	// A nil transaction without error runs the work without transaction, see NoTxStrategy.
+	Begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error)
+	// Commit given transaction.
+	Commit(ctx context.Context, tx *sql.Tx) error
+	// Rollback given transaction.
+	Rollback(ctx context.Context, tx *sql.Tx) error
+}
+
+// WithStrategy overrides the Strategy of a Manager, TxStrategy by default.
+func WithStrategy(s Strategy) Option {
+	return func(m *Manager) {
+		if b, ok := m.r.b.(sqlBeginner); ok {
+			b.strategy = s
+			m.r.b = b
+		}
+	}
+}
+
+// TxStrategy is the default Strategy, using database/sql transactions.
+type TxStrategy struct{}
+
+// Begin a transaction on db with given options.
+func (TxStrategy) Begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.BeginTx(ctx, opts)
+}
+
+// Commit given transaction.
+func (TxStrategy) Commit(_ context.Context, tx *sql.Tx) error {
+	return tx.Commit()
+}
+
+// Rollback given transaction.
+func (TxStrategy) Rollback(_ context.Context, tx *sql.Tx) error {
+	return tx.Rollback()
+}
+
+// NoTxStrategy is a Strategy for engines without real transactions, like ClickHouse:
+// BEGIN is skipped and work runs on the database.
+//
+// Function f then sees no transaction, IsInTx reporting false, and helpers like Select
+// run on the database. Commit and rollback do nothing, the Info being marked NonTransactional,
+// so that nothing is undone when f fails. Setups like WithSchema and savepoints are no-ops too.
+//
+// It must be used as a pointer.
+type NoTxStrategy struct {
+	// Logger, if not nil, is warned on first use.
+	Logger *slog.Logger
+
+	warned atomic.Bool
+}
+
+// Begin returns no transaction.
+func (s *NoTxStrategy) Begin(ctx context.Context, _ *sql.DB, _ *sql.TxOptions) (*sql.Tx, error) {
+	if s.Logger != nil && s.warned.CompareAndSwap(false, true) {
+		s.Logger.WarnContext(ctx, "txx: running without transactions, work is not atomic")
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+// Commit does nothing.
+func (*NoTxStrategy) Commit(_ context.Context, _ *sql.Tx) error {
+	return nil
+}
+
+// Rollback does nothing.
+func (*NoTxStrategy) Rollback(_ context.Context, _ *sql.Tx) error {
+	return nil
+}
+
+// noTransaction is the transaction of work run without transaction by a Strategy.
+//
+// Its pointer identifies the work, like among the active transactions of a Manager.
+type noTransaction struct {
+	_ byte // distinct allocations, unlike zero-size ones
+}
+
+func (*noTransaction) bind(ctx context.Context, _ *sql.TxOptions, _ *scope) context.Context {
+	return ctx
+}
+
+func (*noTransaction) Commit(_ context.Context) error {
+	return nil
+}
+
+func (*noTransaction) Rollback(_ context.Context) error {
+	return nil
+}
+
+func (*noTransaction) Savepoint(_ context.Context, _ string) error {
+	return nil
+}
+
+func (*noTransaction) RollbackTo(_ context.Context, _ string) error {
+	return nil
+}
+
+func (*noTransaction) Release(_ context.Context, _ string) error {
+	return nil
+}
+
+func (*noTransaction) Exec(_ context.Context, _ string) error {
+	return nil
+}
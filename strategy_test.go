@@ -0,0 +1,149 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStrategy is a TxStrategy counting commits and rollbacks.
+type countingStrategy struct {
+	TxStrategy
+
+	commits, rollbacks int
+}
+
+func (s *countingStrategy) Commit(ctx context.Context, tx *sql.Tx) error {
+	s.commits++
+
+	return s.TxStrategy.Commit(ctx, tx)
+}
+
+func (s *countingStrategy) Rollback(ctx context.Context, tx *sql.Tx) error {
+	s.rollbacks++
+
+	return s.TxStrategy.Rollback(ctx, tx)
+}
+
+func countPeople(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var result int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM person").Scan(&result))
+
+	return result
+}
+
+func insertPerson(ctx context.Context, db *sql.DB, name string) error {
+	_, err := QuerierFor(ctx, db).ExecContext(ctx, "INSERT INTO person (name) VALUES (?)", name)
+
+	return err
+}
+
+func TestWithStrategy(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	tests := []struct {
+		name     string
+		strategy Strategy
+		inTx     bool
+		// undone reports if failed work is rolled back.
+		undone bool
+	}{
+		{name: "tx", strategy: TxStrategy{}, inTx: true, undone: true},
+		{name: "noTx", strategy: &NoTxStrategy{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+			var infos []Info
+
+			m := New(db, WithStrategy(tt.strategy), WithFinally(func(info Info) { infos = append(infos, info) }))
+
+			require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				assert.Equal(t, tt.inTx, IsInTx(ctx))
+
+				return m.Ensure(ctx, nil, func(ctx context.Context) error {
+					return insertPerson(ctx, db, "Carol")
+				})
+			}))
+			assert.Equal(t, 3, countPeople(t, db))
+
+			err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+				require.NoError(t, insertPerson(ctx, db, "Dave"))
+
+				return errFailed
+			})
+			require.ErrorIs(t, err, errFailed)
+
+			if tt.undone {
+				assert.Equal(t, 3, countPeople(t, db))
+			} else {
+				assert.Equal(t, 4, countPeople(t, db))
+			}
+
+			got, err := Select[string](context.Background(), db, "SELECT name FROM person WHERE id = 1")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Alice"}, got)
+
+			require.NotEmpty(t, infos)
+			assert.True(t, infos[0].Committed)
+
+			last := infos[len(infos)-1]
+			assert.False(t, last.Committed)
+			assert.Equal(t, CauseError, last.Cause)
+
+			for _, info := range infos {
+				assert.Equal(t, !tt.inTx, info.NonTransactional)
+			}
+		})
+	}
+}
+
+func TestWithStrategy_custom(t *testing.T) {
+	strategy := &countingStrategy{}
+	m := New(testdb.Open(t, inMemory), WithStrategy(strategy))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+
+	assert.Equal(t, 1, strategy.commits)
+	assert.Equal(t, 1, strategy.rollbacks)
+}
+
+// unhashableStrategy is a TxStrategy value which can't be a map key.
+type unhashableStrategy struct {
+	TxStrategy
+
+	hooks []func()
+}
+
+func TestWithStrategy_unhashable(t *testing.T) {
+	m := New(testdb.Open(t, inMemory), WithStrategy(unhashableStrategy{}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, checkTxExists))
+	require.Error(t, m.Wrap(context.Background(), nil, fail))
+
+	assert.Equal(t, uint64(1), m.Stats().Committed)
+	assert.Zero(t, m.Stats().Active)
+	require.NoError(t, m.Shutdown(context.Background()))
+}
+
+func TestNoTxStrategy_warning(t *testing.T) {
+	rec := &recorder{}
+	m := New(testdb.Open(t, inMemory), WithStrategy(&NoTxStrategy{Logger: slog.New(rec)}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+
+	require.Len(t, rec.records, 1)
+	assert.Equal(t, slog.LevelWarn, rec.records[0].Level)
+}
@@ -0,0 +1,155 @@
+package txx
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrConcurrentUse is returned when a transaction is used by several goroutines, see WithStrictConcurrency.
+var ErrConcurrentUse = errors.New("txx: concurrent use of transaction")
+
+// strictEnv enables WithStrictConcurrency for every Manager when true, see strconv.ParseBool.
+const strictEnv = "TXX_STRICT"
+
+// WithStrictConcurrency makes the Current methods of the transactions of a Manager fail with ErrConcurrentUse
+// when they are misused the way a sql.Tx, not being safe for concurrent use, doesn't detect:
+//   - a statement is executed by another goroutine than the one that began the transaction,
+//     even after the owner is done with it, the transaction having to be used by a single goroutine;
+//   - a statement is executed while the Rows of a query are not closed, which most drivers reject
+//     or corrupt, SQLite excepted.
+//
+// The error names the call sites of both statements, or of the statement and of the Wrap owning the transaction.
+// It is also enabled by the TXX_STRICT environment variable set to true when the Manager is created,
+// like in tests. Each statement then reads the header of the stack of its goroutine, to identify it:
+// meant to find bugs, not to prevent them.
+func WithStrictConcurrency() Option {
+	return func(m *Manager) {
+		m.r.strict = true
+	}
+}
+
+// strictFromEnv reports if TXX_STRICT enables WithStrictConcurrency.
+func strictFromEnv() bool {
+	strict, _ := strconv.ParseBool(os.Getenv(strictEnv))
+
+	return strict
+}
+
+// owner is the goroutine owning a transaction if strict, see WithStrictConcurrency.
+type owner struct {
+	goroutine uint64
+	site      string
+}
+
+// openRows are rows returned by a query of the transaction, maybe not closed yet.
+type openRows struct {
+	rows *sql.Rows
+	site string
+}
+
+// own makes the calling goroutine the owner of the transaction, if strict.
+func (s *scope) own() {
+	if s.strict {
+		s.owner = owner{goroutine: goroutineID(), site: callSite()}
+	}
+}
+
+// use returns an error naming both call sites if the transaction is misused by a statement
+// called by the first caller outside of txx.
+func (s *scope) use() error {
+	if s == nil || !s.strict {
+		return nil
+	}
+
+	site := callSite()
+
+	if g := goroutineID(); g != s.owner.goroutine {
+		return fmt.Errorf(
+			"%w: statement at %s on goroutine %d, transaction begun at %s on goroutine %d",
+			ErrConcurrentUse, site, g, s.owner.site, s.owner.goroutine,
+		)
+	}
+
+	if other := s.openRows(); other != "" {
+		return fmt.Errorf("%w: statement at %s while rows of statement at %s are open", ErrConcurrentUse, site, other)
+	}
+
+	return nil
+}
+
+// opened records rows returned by a query, until they are closed, if strict.
+func (s *scope) opened(rows *sql.Rows) {
+	if s == nil || !s.strict || rows == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rows = append(s.rows, openRows{rows: rows, site: callSite()})
+}
+
+// openRows returns the call site of the query whose rows are still open, if any, forgetting the closed ones.
+func (s *scope) openRows() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := s.rows[:0]
+
+	for _, r := range s.rows {
+		// Columns only fails once the rows are closed, by Close or by the end of Next
+		if _, err := r.rows.Columns(); err == nil {
+			open = append(open, r)
+		}
+	}
+
+	clear(s.rows[len(open):])
+	s.rows = open
+
+	if len(open) == 0 {
+		return ""
+	}
+
+	return open[0].site
+}
+
+// callSite returns the function and location of the first caller outside of this package, tests excepted.
+func callSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, "github.com/MartyHub/txx.") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// goroutineID returns the identifier of the calling goroutine, read from the header of its stack,
+// like "goroutine 42 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+
+	header := buf[:runtime.Stack(buf[:], false)]
+	header, _ = bytes.CutPrefix(header, []byte("goroutine "))
+
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+
+	return id
+}
@@ -0,0 +1,158 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingDB returns a database whose "block" statements run until release is closed, signaling entered.
+func blockingDB(t *testing.T) (db *sql.DB, entered, release chan struct{}) {
+	t.Helper()
+
+	entered, release = make(chan struct{}), make(chan struct{})
+	db = fakedb.Open(t, &fakedb.Driver{
+		Exec: func(_ context.Context, query string, _ []any) (fakedb.Result, error) {
+			if query == "block" {
+				close(entered)
+				<-release
+			}
+
+			return fakedb.Result{}, nil
+		},
+	})
+
+	return db, entered, release
+}
+
+func TestWithStrictConcurrency(t *testing.T) {
+	db, entered, release := blockingDB(t)
+
+	err := New(db, WithStrictConcurrency()).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		done := make(chan error, 1)
+
+		go func() {
+			<-entered
+
+			_, err := Get(ctx).ExecContext(ctx, "INSERT")
+			done <- err
+
+			close(release)
+		}()
+
+		if _, err := Get(ctx).ExecContext(ctx, "block"); err != nil {
+			return err
+		}
+
+		return <-done
+	})
+
+	require.ErrorIs(t, err, ErrConcurrentUse)
+	assert.Regexp(t,
+		`statement at \S+TestWithStrictConcurrency\.func1\.1 \(\S+strict_test\.go:\d+\) on goroutine \d+, `+
+			`transaction begun at \S+TestWithStrictConcurrency \(\S+strict_test\.go:\d+\) on goroutine \d+`,
+		err.Error(),
+	)
+}
+
+func TestWithStrictConcurrency_otherGoroutine(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	err := New(db, WithStrictConcurrency()).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		if err := insertPerson(ctx, db, "Carol"); err != nil {
+			return err
+		}
+
+		// the statement runs once the owner is done with the transaction
+		done := make(chan error)
+
+		go func() {
+			done <- insertPerson(ctx, db, "Dave")
+		}()
+
+		return <-done
+	})
+
+	require.ErrorIs(t, err, ErrConcurrentUse)
+	assert.Regexp(t,
+		`statement at \S+insertPerson \(\S+strategy_test\.go:\d+\) on goroutine \d+, `+
+			`transaction begun at \S+TestWithStrictConcurrency_otherGoroutine \(\S+strict_test\.go:\d+\)`,
+		err.Error(),
+	)
+	assert.Equal(t, 2, countPeople(t, db))
+}
+
+func TestWithStrictConcurrency_openRows(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, New(db, WithStrictConcurrency()).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		rows, err := Get(ctx).QueryContext(ctx, "SELECT name FROM person")
+		require.NoError(t, err)
+
+		_, err = Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+		require.ErrorIs(t, err, ErrConcurrentUse)
+		assert.Regexp(t,
+			`statement at \S+TestWithStrictConcurrency_openRows\.func1 \(\S+strict_test\.go:\d+\) `+
+				`while rows of statement at \S+TestWithStrictConcurrency_openRows\.func1 \(\S+strict_test\.go:\d+\) are open`,
+			err.Error(),
+		)
+
+		require.NoError(t, rows.Close())
+
+		if _, err = Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')"); err != nil {
+			return err
+		}
+
+		// rows iterated to the end are closed
+		rows, err = Get(ctx).QueryContext(ctx, "SELECT name FROM person")
+		require.NoError(t, err)
+
+		var n int
+
+		for rows.Next() {
+			n++
+		}
+
+		require.NoError(t, rows.Err())
+		assert.Equal(t, 3, n)
+
+		_, err = Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Dave')")
+
+		return err
+	}))
+
+	assert.Equal(t, 4, countPeople(t, db))
+}
+
+func TestWithStrictConcurrency_sequential(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	require.NoError(t, New(db, WithStrictConcurrency()).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		for range 3 {
+			var n int
+
+			if err := Get(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM person").Scan(&n); err != nil {
+				return err
+			}
+
+			if _, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+}
+
+func TestWithStrictConcurrency_env(t *testing.T) {
+	t.Setenv(strictEnv, "true")
+	assert.True(t, New(testdb.Open(t, inMemory)).r.strict)
+
+	t.Setenv(strictEnv, "")
+	assert.False(t, New(testdb.Open(t, inMemory)).r.strict)
+}
@@ -0,0 +1,67 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+//nolint:gochecknoglobals
+var tempTables atomic.Uint64
+
+// TempTable creates a temporary table living as long as the transaction of the context and returns its name.
+//
+// The table is created with CREATE TEMPORARY TABLE followed by a unique name and given definition,
+// like "(id INTEGER, payload TEXT)". As temporary tables of engines like SQLite or MySQL belong to the
+// connection rather than the transaction, the table is dropped right before the transaction completes,
+// which makes ON COMMIT DROP unnecessary where available. A failure to drop it rolls the transaction back.
+//
+// Names are unique across transactions and nested calls. TempTable returns ErrNoTransaction if there is
+// no transaction begun by txx in the context.
+func TempTable(ctx context.Context, ddl string) (string, error) {
+	current := Get(ctx)
+	if current.s == nil {
+		return "", ErrNoTransaction
+	}
+
+	name := fmt.Sprintf("txx_tmp_%d", tempTables.Add(1))
+
+	if _, err := current.ExecContext(ctx, "CREATE TEMPORARY TABLE "+name+" "+ddl); err != nil {
+		return "", fmt.Errorf("txx: creating temporary table: %w", err)
+	}
+
+	current.s.onComplete(func(ctx context.Context) error {
+		if _, err := current.Tx.ExecContext(ctx, "DROP TABLE "+name); err != nil {
+			return fmt.Errorf("txx: dropping temporary table %s: %w", name, err)
+		}
+
+		return nil
+	})
+
+	return name, nil
+}
+
+// onComplete registers f to run in the transaction right before it completes.
+func (s *scope) onComplete(f func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanups = append(s.cleanups, f)
+}
+
+// cleanup runs the functions registered by onComplete in reverse order and joins their errors.
+func (s *scope) cleanup(ctx context.Context) error {
+	s.mu.Lock()
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+
+	var errs []error
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		errs = append(errs, cleanups[i](ctx))
+	}
+
+	return errors.Join(errs...)
+}
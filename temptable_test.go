@@ -0,0 +1,131 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countTempTables returns the number of temporary tables of the single connection of db.
+func countTempTables(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var result int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_temp_master WHERE type = 'table'").Scan(&result))
+
+	return result
+}
+
+func stage(name *string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var err error
+
+		*name, err = TempTable(ctx, "(id INTEGER, payload TEXT)")
+		if err != nil {
+			return err
+		}
+
+		current := Get(ctx)
+
+		if _, err = current.ExecContext(ctx, "INSERT INTO "+*name+" VALUES (1, 'a'), (2, 'b')"); err != nil {
+			return err
+		}
+
+		var n int
+
+		if err = current.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+*name).Scan(&n); err != nil {
+			return err
+		}
+
+		if n != 2 {
+			return errors.New("unexpected row count") //nolint:goerr113
+		}
+
+		return nil
+	}
+}
+
+func TestTempTable(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	var name string
+
+	require.NoError(t, Wrap(context.Background(), db, nil, stage(&name)))
+	assert.NotEmpty(t, name)
+	assert.Zero(t, countTempTables(t, db))
+}
+
+func TestTempTable_rollback(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	var name string
+
+	errFailed := errors.New("failed") //nolint:goerr113
+	err := Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		require.NoError(t, stage(&name)(ctx))
+
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	assert.NotEmpty(t, name)
+	assert.Zero(t, countTempTables(t, db))
+}
+
+func TestTempTable_nested(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	var outer, inner string
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		if err := stage(&outer)(ctx); err != nil {
+			return err
+		}
+
+		return Ensure(ctx, db, nil, stage(&inner))
+	}))
+
+	assert.NotEqual(t, outer, inner)
+	assert.Zero(t, countTempTables(t, db))
+}
+
+func TestTempTable_dropFailure(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	var info Info
+
+	m := New(db, WithFinally(func(i Info) { info = i }))
+
+	err := m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		name, err := TempTable(ctx, "(id INTEGER)")
+		if err != nil {
+			return err
+		}
+
+		_, err = Get(ctx).ExecContext(ctx, "DROP TABLE "+name)
+
+		return err
+	})
+
+	require.ErrorContains(t, err, "dropping temporary table")
+	assert.False(t, info.Committed)
+	assert.ErrorContains(t, info.Err, "dropping temporary table")
+}
+
+func TestTempTable_noTransaction(t *testing.T) {
+	_, err := TempTable(context.Background(), "(id INTEGER)")
+	require.ErrorIs(t, err, ErrNoTransaction)
+
+	_, err = TempTable(Set(context.Background(), &sql.Tx{}, nil), "(id INTEGER)")
+	require.ErrorIs(t, err, ErrNoTransaction)
+}
@@ -0,0 +1,297 @@
+package txx
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+// ErrTenantMismatch is returned when a transaction of a tenant is requested
+// while a transaction of another tenant is current.
+var ErrTenantMismatch = errors.New("txx: tenant mismatch")
+
+// TenantOption configures a TenantManager.
+type TenantOption func(tm *TenantManager)
+
+// WithMaxTenants limits the number of cached tenant databases, unlimited if not positive.
+//
+// Least recently used idle databases are closed first.
+func WithMaxTenants(n int) TenantOption {
+	return func(tm *TenantManager) {
+		tm.maxTenants = n
+	}
+}
+
+// WithTenantTTL closes tenant databases idle for longer than given duration, never if not positive.
+//
+// There is no background sweep: expired databases are closed once a tenant database is acquired or released.
+func WithTenantTTL(d time.Duration) TenantOption {
+	return func(tm *TenantManager) {
+		tm.ttl = d
+	}
+}
+
+// WithTenantOptions configures the Manager of every tenant.
+func WithTenantOptions(opts ...Option) TenantOption {
+	return func(tm *TenantManager) {
+		tm.opts = append(tm.opts, opts...)
+	}
+}
+
+// WithTenantClock overrides the Clock used for the tenant TTL, the time package by default.
+func WithTenantClock(c Clock) TenantOption {
+	return func(tm *TenantManager) {
+		tm.clock = c
+	}
+}
+
+// TenantManager routes transactions to a database per tenant, resolved from the context.
+//
+// Tenant databases are opened lazily and cached, idle ones being closed according to
+// WithMaxTenants and WithTenantTTL. A database is never closed while it has active transactions.
+//
+// A TenantManager is safe for concurrent use.
+type TenantManager struct {
+	resolve    func(ctx context.Context) (string, error)
+	open       func(tenant string) (*sql.DB, error)
+	opts       []Option
+	maxTenants int
+	ttl        time.Duration
+	clock      Clock
+
+	mu      sync.Mutex
+	tenants map[string]*list.Element
+	lru     *list.List // of *tenantDB, most recently used first
+}
+
+type tenantDB struct {
+	tenant string
+	// ready is closed once db is opened, or err set.
+	ready    chan struct{}
+	db       *sql.DB
+	m        *Manager
+	err      error
+	refs     int
+	lastUsed time.Time
+}
+
+// NewTenantManager returns a TenantManager resolving the tenant from the context with resolve,
+// and opening its database with open.
+func NewTenantManager(
+	resolve func(ctx context.Context) (string, error),
+	open func(tenant string) (*sql.DB, error),
+	opts ...TenantOption,
+) *TenantManager {
+	tm := &TenantManager{
+		resolve: resolve,
+		open:    open,
+		clock:   clock.Real{},
+		tenants: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(tm)
+	}
+
+	return tm
+}
+
+// Ensure function f run in a transaction of the context tenant with given options.
+//
+// A current transaction is only reused if it belongs to the same tenant,
+// otherwise ErrTenantMismatch is returned.
+func (tm *TenantManager) Ensure(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	return tm.do(ctx, func(tenant string, m *Manager) error {
+		opts := m.options(opts)
+		if m.transactionRequired(ctx, opts) {
+			if err := m.checkIncompatible(ctx, opts); err != nil {
+				return err
+			}
+
+			return tm.wrap(ctx, tenant, m.wrap, opts, f)
+		}
+
+		return m.reuseTx(ctx, opts, reusing(f))
+	})
+}
+
+// Wrap function f in a new transaction of the context tenant with given options.
+//
+// If a transaction of another tenant is current, ErrTenantMismatch is returned.
+func (tm *TenantManager) Wrap(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	return tm.do(ctx, func(tenant string, m *Manager) error {
+		return tm.wrap(ctx, tenant, m.Wrap, opts, f)
+	})
+}
+
+// Close closes every cached tenant database.
+//
+// It should only be called once no transaction is active anymore.
+func (tm *TenantManager) Close() error {
+	tm.mu.Lock()
+
+	var evicted []*tenantDB
+
+	for e := tm.lru.Front(); e != nil; e = tm.lru.Front() {
+		evicted = append(evicted, tm.evict(e))
+	}
+	tm.mu.Unlock()
+
+	return closeTenants(evicted)
+}
+
+func (tm *TenantManager) do(ctx context.Context, f func(tenant string, m *Manager) error) error {
+	tenant, err := tm.resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("txx: resolving tenant: %w", err)
+	}
+
+	if current := Get(ctx); current.IsValid() && current.Tenant != tenant {
+		return fmt.Errorf("%w: %q requested in a transaction of %q", ErrTenantMismatch, tenant, current.Tenant)
+	}
+
+	entry, err := tm.acquire(tenant)
+	if err != nil {
+		return err
+	}
+
+	defer tm.release(entry)
+
+	return f(tenant, entry.m)
+}
+
+func (tm *TenantManager) wrap(
+	ctx context.Context,
+	tenant string,
+	wrap func(ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context) error) error,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) error,
+) error {
+	return wrap(ctx, opts, func(ctx context.Context) error {
+		current := Get(ctx)
+		current.Tenant = tenant
+
+		return f(context.WithValue(ctx, ctxKey, current))
+	})
+}
+
+// acquire the database of given tenant, opened once by the first caller without the lock, the others waiting.
+func (tm *TenantManager) acquire(tenant string) (*tenantDB, error) {
+	tm.mu.Lock()
+
+	now := tm.clock.Now()
+
+	e, found := tm.tenants[tenant]
+	if found {
+		tm.lru.MoveToFront(e)
+	} else {
+		e = tm.lru.PushFront(&tenantDB{tenant: tenant, ready: make(chan struct{})})
+		tm.tenants[tenant] = e
+	}
+
+	entry, _ := e.Value.(*tenantDB)
+	entry.refs++
+	entry.lastUsed = now
+	evicted := tm.sweep(now)
+	tm.mu.Unlock()
+
+	_ = closeTenants(evicted)
+
+	if !found {
+		tm.openTenant(e, entry)
+	}
+
+	<-entry.ready
+
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	return entry, nil
+}
+
+// openTenant opens the database of given entry, removing it on failure.
+func (tm *TenantManager) openTenant(e *list.Element, entry *tenantDB) {
+	defer close(entry.ready)
+
+	db, err := tm.open(entry.tenant)
+	if err == nil {
+		entry.db, entry.m = db, New(db, tm.opts...)
+
+		return
+	}
+
+	entry.err = fmt.Errorf("txx: opening database of tenant %q: %w", entry.tenant, err)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	// never evicted while opening, being referenced, unless by Close
+	tm.lru.Remove(e)
+
+	if tm.tenants[entry.tenant] == e {
+		delete(tm.tenants, entry.tenant)
+	}
+}
+
+func (tm *TenantManager) release(entry *tenantDB) {
+	tm.mu.Lock()
+
+	entry.refs--
+	entry.lastUsed = tm.clock.Now()
+	evicted := tm.sweep(entry.lastUsed)
+	tm.mu.Unlock()
+
+	_ = closeTenants(evicted)
+}
+
+// sweep removes idle databases beyond the limits, returning them to be closed without the lock.
+func (tm *TenantManager) sweep(now time.Time) []*tenantDB {
+	var evicted []*tenantDB
+
+	for e := tm.lru.Back(); e != nil; {
+		prev := e.Prev()
+		entry, _ := e.Value.(*tenantDB)
+
+		if entry.refs == 0 {
+			expired := tm.ttl > 0 && now.Sub(entry.lastUsed) >= tm.ttl
+			exceeding := tm.maxTenants > 0 && tm.lru.Len() > tm.maxTenants
+
+			if expired || exceeding {
+				evicted = append(evicted, tm.evict(e))
+			}
+		}
+
+		e = prev
+	}
+
+	return evicted
+}
+
+// evict removes given element, whose database must be closed by closeTenants.
+func (tm *TenantManager) evict(e *list.Element) *tenantDB {
+	entry, _ := tm.lru.Remove(e).(*tenantDB)
+	delete(tm.tenants, entry.tenant)
+
+	return entry
+}
+
+// closeTenants closes the databases of given evicted entries, joining their errors.
+func closeTenants(entries []*tenantDB) error {
+	var errs []error
+
+	for _, entry := range entries {
+		if entry.db != nil {
+			errs = append(errs, entry.db.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,249 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func resolveTenant(ctx context.Context) (string, error) {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant, nil
+	}
+
+	return "", errors.New("no tenant") //nolint:goerr113
+}
+
+type tenantDBs struct {
+	t      *testing.T
+	dir    string
+	opened map[string][]*sql.DB
+}
+
+func newTenantDBs(t *testing.T) *tenantDBs {
+	t.Helper()
+
+	return &tenantDBs{t: t, dir: t.TempDir(), opened: make(map[string][]*sql.DB)}
+}
+
+func (dbs *tenantDBs) open(tenant string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", filepath.Join(dbs.dir, tenant+".db"))
+	if err != nil {
+		return nil, err
+	}
+
+	dbs.opened[tenant] = append(dbs.opened[tenant], db)
+
+	dbs.t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db, nil
+}
+
+func (dbs *tenantDBs) closed(tenant string) bool {
+	opened := dbs.opened[tenant]
+
+	return opened[len(opened)-1].Ping() != nil
+}
+
+func insertTenant(ctx context.Context) error {
+	current := Get(ctx)
+
+	if _, err := current.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS owner (name TEXT)"); err != nil {
+		return err
+	}
+
+	_, err := current.ExecContext(ctx, "INSERT INTO owner (name) VALUES (?)", current.Tenant)
+
+	return err
+}
+
+func TestTenantManager_routing(t *testing.T) {
+	dbs := newTenantDBs(t)
+	tm := NewTenantManager(resolveTenant, dbs.open)
+
+	t.Cleanup(func() {
+		_ = tm.Close()
+	})
+
+	for _, tenant := range []string{"a", "b", "a"} {
+		require.NoError(t, tm.Wrap(withTenant(context.Background(), tenant), nil, insertTenant))
+	}
+
+	for tenant, want := range map[string]int{"a": 2, "b": 1} {
+		var n int
+
+		require.NoError(t, tm.Ensure(withTenant(context.Background(), tenant), ReadOnly(), func(ctx context.Context) error {
+			return Get(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM owner WHERE name = ?", tenant).Scan(&n)
+		}))
+		assert.Equal(t, want, n, tenant)
+		assert.Len(t, dbs.opened[tenant], 1, "database should be cached")
+	}
+}
+
+func TestTenantManager_Ensure(t *testing.T) {
+	tm := NewTenantManager(resolveTenant, newTenantDBs(t).open)
+	ctx := withTenant(context.Background(), "a")
+
+	err := tm.Wrap(ctx, nil, func(ctx context.Context) error {
+		outer := Get(ctx)
+
+		assert.Equal(t, "a", outer.Tenant)
+
+		return tm.Ensure(ctx, nil, checkTxEquals(outer.Tx))
+	})
+
+	require.NoError(t, err)
+}
+
+func TestTenantManager_mismatch(t *testing.T) {
+	tm := NewTenantManager(resolveTenant, newTenantDBs(t).open)
+
+	err := tm.Wrap(withTenant(context.Background(), "a"), nil, func(ctx context.Context) error {
+		ctx = withTenant(ctx, "b")
+
+		require.ErrorIs(t, tm.Ensure(ctx, nil, checkTxExists), ErrTenantMismatch)
+
+		return tm.Wrap(ctx, nil, checkTxExists)
+	})
+
+	require.ErrorIs(t, err, ErrTenantMismatch)
+	require.ErrorIs(t, tm.Ensure(Set(withTenant(context.Background(), "a"), &sql.Tx{}, nil), nil, checkTxExists), ErrTenantMismatch)
+}
+
+func TestTenantManager_errors(t *testing.T) {
+	errOpen := errors.New("open") //nolint:goerr113
+	tm := NewTenantManager(resolveTenant, func(_ string) (*sql.DB, error) {
+		return nil, errOpen
+	})
+
+	require.Error(t, tm.Wrap(context.Background(), nil, checkTxExists))
+	require.ErrorIs(t, tm.Wrap(withTenant(context.Background(), "a"), nil, checkTxExists), errOpen)
+}
+
+func TestTenantManager_opening(t *testing.T) {
+	dbs := newTenantDBs(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+
+	var opens atomic.Int32
+
+	tm := NewTenantManager(resolveTenant, func(tenant string) (*sql.DB, error) {
+		if tenant == "slow" && opens.Add(1) == 1 {
+			close(entered)
+			<-release
+		}
+
+		return dbs.open(tenant)
+	})
+
+	t.Cleanup(func() {
+		_ = tm.Close()
+	})
+
+	errs := make(chan error, 2)
+
+	for range 2 {
+		go func() {
+			errs <- tm.Wrap(withTenant(context.Background(), "slow"), nil, noop)
+		}()
+	}
+
+	<-entered
+	require.NoError(t, tm.Wrap(withTenant(context.Background(), "fast"), nil, insertTenant),
+		"other tenants should not wait for an opening database")
+	close(release)
+
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+	assert.Equal(t, int32(1), opens.Load(), "database should be opened once")
+}
+
+func TestTenantManager_openFailed(t *testing.T) {
+	errOpen := errors.New("open") //nolint:goerr113
+	dbs := newTenantDBs(t)
+	fail := true
+	tm := NewTenantManager(resolveTenant, func(tenant string) (*sql.DB, error) {
+		if fail {
+			return nil, errOpen
+		}
+
+		return dbs.open(tenant)
+	})
+
+	t.Cleanup(func() {
+		_ = tm.Close()
+	})
+
+	ctx := withTenant(context.Background(), "a")
+
+	require.ErrorIs(t, tm.Wrap(ctx, nil, insertTenant), errOpen)
+
+	fail = false
+
+	require.NoError(t, tm.Wrap(ctx, nil, insertTenant), "failed open should not be cached")
+}
+
+func TestTenantManager_maxTenants(t *testing.T) {
+	dbs := newTenantDBs(t)
+	tm := NewTenantManager(resolveTenant, dbs.open, WithMaxTenants(1))
+	a := withTenant(context.Background(), "a")
+	b := withTenant(context.Background(), "b")
+
+	require.NoError(t, tm.Wrap(a, nil, checkTxExists))
+	require.NoError(t, tm.Wrap(b, nil, checkTxExists))
+
+	assert.True(t, dbs.closed("a"), "least recently used tenant should be closed")
+	assert.False(t, dbs.closed("b"))
+
+	// an active database is not closed
+	require.NoError(t, tm.Wrap(a, nil, func(_ context.Context) error {
+		require.NoError(t, tm.Wrap(b, nil, checkTxExists))
+
+		assert.False(t, dbs.closed("a"))
+		assert.True(t, dbs.closed("b"), "idle tenant should be closed after release")
+
+		return nil
+	}))
+
+	assert.Len(t, dbs.opened["a"], 2)
+	assert.Len(t, dbs.opened["b"], 2)
+	assert.False(t, dbs.closed("a"))
+}
+
+func TestTenantManager_ttl(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	dbs := newTenantDBs(t)
+	tm := NewTenantManager(resolveTenant, dbs.open, WithTenantTTL(time.Minute), WithTenantClock(c))
+	a := withTenant(context.Background(), "a")
+	b := withTenant(context.Background(), "b")
+
+	require.NoError(t, tm.Wrap(a, nil, checkTxExists))
+
+	c.Advance(30 * time.Second)
+
+	require.NoError(t, tm.Wrap(b, nil, checkTxExists))
+	assert.False(t, dbs.closed("a"))
+
+	c.Advance(30 * time.Second)
+
+	require.NoError(t, tm.Wrap(b, nil, checkTxExists))
+	assert.True(t, dbs.closed("a"))
+	assert.False(t, dbs.closed("b"))
+	require.NoError(t, tm.Close())
+	assert.True(t, dbs.closed("b"))
+}
@@ -0,0 +1,82 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrBeginTimeout is returned when beginning a transaction takes longer than the begin timeout,
+	// see WithBeginTimeout.
+	ErrBeginTimeout = errors.New("txx: begin timeout")
+	// ErrTimeout is returned when a transaction takes longer than its timeout, see WrapWithTimeout.
+	ErrTimeout = errors.New("txx: transaction timeout")
+)
+
+// WithBeginTimeout bounds the time to acquire a connection and begin a transaction,
+// failing with ErrBeginTimeout past given duration.
+//
+// Once begun, the transaction and its function run under the context given to Wrap,
+// so that pool starvation can be told apart from slow work.
+func WithBeginTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.r.beginTimeout = d
+	}
+}
+
+// WrapWithTimeout is Wrap bounding the whole transaction, from begin to commit, by given timeout,
+// independently of the deadline of ctx.
+//
+// The transaction and its function run under a context expiring after timeout: once expired,
+// the transaction is rolled back and the returned error matches both ErrTimeout and context.DeadlineExceeded.
+// A transaction committed before is not affected.
+func WrapWithTimeout(
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	timeout time.Duration,
+	f func(ctx context.Context) error,
+) error {
+	return withTimeout(ctx, timeout, func(ctx context.Context) error {
+		return Wrap(ctx, db, opts, f)
+	})
+}
+
+// WrapWithTimeout is Wrap bounding the whole transaction by given timeout.
+//
+// See the package-level WrapWithTimeout function.
+func (m *Manager) WrapWithTimeout(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	timeout time.Duration,
+	f func(ctx context.Context) error,
+) error {
+	return withTimeout(ctx, timeout, func(ctx context.Context) error {
+		return m.Wrap(ctx, opts, f)
+	})
+}
+
+// withTimeout runs wrap with a context expiring after timeout, wrapping its error with ErrTimeout once expired.
+func withTimeout(ctx context.Context, timeout time.Duration, wrap func(ctx context.Context) error) error {
+	tctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+	defer cancel()
+
+	err := wrap(withDeadlineCause(tctx, ErrTimeout))
+	if err == nil {
+		return nil
+	}
+
+	// once the function context expired, tctx is done too, see settle
+	if !errors.Is(context.Cause(tctx), ErrTimeout) {
+		return err
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+
+	return fmt.Errorf("%w after %v: %w", ErrTimeout, timeout, err)
+}
@@ -0,0 +1,100 @@
+package txx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBeginTimeout(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	db.SetMaxOpenConns(1)
+
+	m := New(db, WithBeginTimeout(20*time.Millisecond))
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = m.Wrap(context.Background(), nil, noop)
+
+	require.ErrorIs(t, err, ErrBeginTimeout)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, m.Wrap(context.Background(), nil, noop))
+}
+
+func TestWithBeginTimeout_work(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	m := New(testdb.Open(t, inMemory), WithBeginTimeout(50*time.Millisecond), WithClock(c))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		c.Advance(60 * time.Millisecond)
+
+		require.NoError(t, ctx.Err(), "work should not be bounded by the begin timeout")
+
+		_, err := Get(ctx).ExecContext(ctx, "SELECT 1")
+
+		return err
+	}))
+}
+
+func TestWrapWithTimeout(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	insert := func(expire bool) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := Get(ctx).ExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+			require.NoError(t, err)
+
+			if expire {
+				<-ctx.Done()
+			}
+
+			return nil
+		}
+	}
+
+	err := WrapWithTimeout(context.Background(), db, nil, 20*time.Millisecond, insert(true))
+
+	require.ErrorIs(t, err, ErrTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), NewDB(db)), "should be rolled back")
+
+	require.NoError(t, New(db).WrapWithTimeout(context.Background(), nil, time.Second, insert(false)))
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, context.Background(), NewDB(db)))
+}
+
+func TestWrapWithTimeout_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WrapWithTimeout(ctx, testdb.Open(t, inMemory), nil, time.Second, noop)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrTimeout, "cancellation of the parent context is not a timeout")
+}
+
+func TestWrapWithTimeout_error(t *testing.T) {
+	errTest := errors.New("test") //nolint:goerr113
+	m := New(testdb.Open(t, inMemory))
+
+	err := m.WrapWithTimeout(context.Background(), nil, 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+
+		assert.ErrorIs(t, context.Cause(ctx), ErrTimeout)
+
+		return errTest
+	})
+
+	require.ErrorIs(t, err, ErrTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, errTest)
+}
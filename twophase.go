@@ -0,0 +1,236 @@
+package txx
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrTwoPhaseCommit is matched by every TwoPhaseError, see WrapTwoPhase.
+	ErrTwoPhaseCommit = errors.New("txx: two-phase commit")
+	// ErrPrepareFailed wraps the error of preparing a transaction, see TwoPhase.
+	ErrPrepareFailed = errors.New("txx: prepare failed")
+)
+
+// TwoPhase issues the two-phase commit statements of a database, like PostgresTwoPhase.
+type TwoPhase interface {
+	// Prepare the transaction under given global ID, so that it survives its session.
+	//
+	// The transaction is then released with Rollback, which must not undo the prepared transaction.
+	Prepare(ctx context.Context, tx *sql.Tx, gid string) error
+	// CommitPrepared commits the transaction prepared under given global ID, outside of any transaction.
+	CommitPrepared(ctx context.Context, db *sql.DB, gid string) error
+	// RollbackPrepared rolls back the transaction prepared under given global ID, outside of any transaction.
+	RollbackPrepared(ctx context.Context, db *sql.DB, gid string) error
+}
+
+// PostgresTwoPhase is the TwoPhase of PostgreSQL, requiring a positive max_prepared_transactions.
+type PostgresTwoPhase struct{}
+
+// Prepare issues PREPARE TRANSACTION.
+func (PostgresTwoPhase) Prepare(ctx context.Context, tx *sql.Tx, gid string) error {
+	_, err := tx.ExecContext(ctx, "PREPARE TRANSACTION "+quoteLiteral(gid))
+
+	return err
+}
+
+// CommitPrepared issues COMMIT PREPARED.
+func (PostgresTwoPhase) CommitPrepared(ctx context.Context, db *sql.DB, gid string) error {
+	_, err := db.ExecContext(ctx, "COMMIT PREPARED "+quoteLiteral(gid))
+
+	return err
+}
+
+// RollbackPrepared issues ROLLBACK PREPARED.
+func (PostgresTwoPhase) RollbackPrepared(ctx context.Context, db *sql.DB, gid string) error {
+	_, err := db.ExecContext(ctx, "ROLLBACK PREPARED "+quoteLiteral(gid))
+
+	return err
+}
+
+// quoteLiteral returns given string as an SQL literal, these statements accepting no parameters.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// TwoPhaseDB is a database taking part in WrapTwoPhase.
+type TwoPhaseDB struct {
+	DB       *sql.DB
+	TwoPhase TwoPhase
+}
+
+// GIDFunc returns the global ID of the transaction of the database of given name, see WrapTwoPhase.
+//
+// Global IDs must be unique among the prepared transactions of a database.
+type GIDFunc func(ctx context.Context, name string) string
+
+// TwoPhaseError is returned by WrapTwoPhase when a transaction failed to be prepared or committed,
+// naming the transactions left prepared, to be finished by an operator.
+type TwoPhaseError struct {
+	// Committed are the names of the databases committed, in commit order.
+	Committed []string
+	// Orphaned are the global IDs of the transactions left prepared, by name of database:
+	// neither committed nor rolled back, they hold their locks until finished by hand.
+	Orphaned map[string]string
+	// GIDs are the global IDs of the transactions prepared, by name of database.
+	GIDs map[string]string
+	// Err is the error of the failed steps.
+	Err error
+}
+
+func (e *TwoPhaseError) Error() string {
+	orphaned := make([]string, 0, len(e.Orphaned))
+	for name, gid := range e.Orphaned {
+		orphaned = append(orphaned, name+"="+gid)
+	}
+
+	slices.Sort(orphaned)
+
+	return fmt.Sprintf(
+		"txx: two-phase commit: committed [%s], orphaned [%s]: %v",
+		strings.Join(e.Committed, " "), strings.Join(orphaned, " "), e.Err,
+	)
+}
+
+// Is reports if target is ErrTwoPhaseCommit.
+func (e *TwoPhaseError) Is(target error) bool {
+	return target == ErrTwoPhaseCommit //nolint:errorlint
+}
+
+// Unwrap returns the error of the failed steps.
+func (e *TwoPhaseError) Unwrap() error {
+	return e.Err
+}
+
+// WrapTwoPhase is WrapAll with a two-phase commit, for databases supporting it, see TwoPhase.
+//
+// Once f returns nil, every transaction is prepared under the global ID given by gid, in the order of their names,
+// then committed. If a prepare fails, the transactions already prepared are rolled back, and the others too.
+// A failed commit doesn't stop the others: the transaction then stays prepared, to be committed by hand.
+// Both return a *TwoPhaseError naming the transactions left prepared, whose recovery is up to the operator.
+//
+// If ctx is done before every transaction is prepared, the prepared ones are rolled back;
+// once they all are, the commits are run even if ctx is done, not to leave transactions prepared.
+// Global IDs default to "txx_" followed by a random hex string shared by the transactions, "_" and the name.
+func WrapTwoPhase(
+	ctx context.Context,
+	dbs map[string]TwoPhaseDB,
+	opts *sql.TxOptions,
+	gid GIDFunc,
+	f func(ctx context.Context) error,
+) error {
+	if gid == nil {
+		gid = randomGID()
+	}
+
+	names := sortedNames(dbs)
+	gids := make(map[string]string, len(names))
+
+	for _, name := range names {
+		gids[name] = gid(ctx, name)
+	}
+
+	e := &TwoPhaseError{Orphaned: map[string]string{}, GIDs: map[string]string{}}
+
+	err := runAll(ctx, names, func(name string) beginner {
+		return twoPhaseBeginner{db: dbs[name], gid: gids[name]}
+	}, opts, f, func(i int, err error) error {
+		for _, name := range names[:i] {
+			e.GIDs[name] = gids[name]
+		}
+
+		e.Err = errors.Join(err, e.rollback(context.WithoutCancel(ctx), dbs, names[:i]))
+
+		return e
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	e.GIDs = gids
+
+	var errs []error
+
+	for _, name := range names {
+		if err := dbs[name].TwoPhase.CommitPrepared(ctx, dbs[name].DB, gids[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s %s: %w", ErrCommitFailed, name, gids[name], err))
+			e.Orphaned[name] = gids[name]
+
+			continue
+		}
+
+		e.Committed = append(e.Committed, name)
+	}
+
+	if len(errs) > 0 {
+		e.Err = errors.Join(errs...)
+
+		return e
+	}
+
+	return nil
+}
+
+// rollback rolls back the prepared transactions of given databases, recording those failing as orphaned.
+func (e *TwoPhaseError) rollback(ctx context.Context, dbs map[string]TwoPhaseDB, names []string) error {
+	var errs []error
+
+	for _, name := range names {
+		if err := dbs[name].TwoPhase.RollbackPrepared(ctx, dbs[name].DB, e.GIDs[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s %s: %w", ErrRollbackFailed, name, e.GIDs[name], err))
+			e.Orphaned[name] = e.GIDs[name]
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// randomGID returns a GIDFunc of global IDs sharing a random part.
+func randomGID() GIDFunc {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	prefix := "txx_" + hex.EncodeToString(b) + "_"
+
+	return func(_ context.Context, name string) string {
+		return prefix + name
+	}
+}
+
+// twoPhaseBeginner begins the transactions of a database taking part in WrapTwoPhase.
+type twoPhaseBeginner struct {
+	db  TwoPhaseDB
+	gid string
+}
+
+func (b twoPhaseBeginner) begin(ctx context.Context, opts *sql.TxOptions) (transaction, error) {
+	tx, err := b.db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return preparedTransaction{sqlTransaction: sqlTransaction{tx: tx, strategy: TxStrategy{}}, b: b}, nil
+}
+
+// preparedTransaction is a transaction whose commit prepares it under its global ID, see TwoPhase.
+type preparedTransaction struct {
+	sqlTransaction
+	b twoPhaseBeginner
+}
+
+func (t preparedTransaction) Commit(ctx context.Context) error {
+	err := t.b.db.TwoPhase.Prepare(ctx, t.tx, t.b.gid)
+	_ = t.tx.Rollback()
+
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrPrepareFailed, t.b.gid, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDB returns a database whose statements and transaction calls fail if they start with a prefix of fail.
+func recordingDB(t *testing.T, fail map[string]error) (TwoPhaseDB, *fakedb.Driver) {
+	t.Helper()
+
+	failing := func(name string) error {
+		for prefix, err := range fail {
+			if strings.HasPrefix(name, prefix) {
+				return err
+			}
+		}
+
+		return nil
+	}
+	d := &fakedb.Driver{
+		Begin: func(_ *sql.TxOptions) error {
+			return failing("begin")
+		},
+		Exec: func(_ context.Context, query string, _ []any) (fakedb.Result, error) {
+			return fakedb.Result{}, failing(query)
+		},
+		Commit: func() error {
+			return failing("commit")
+		},
+		Rollback: func() error {
+			return failing("rollback")
+		},
+	}
+
+	return TwoPhaseDB{DB: fakedb.Open(t, d), TwoPhase: PostgresTwoPhase{}}, d
+}
+
+// twoPhaseCalls returns the statements and transaction calls made to d.
+func twoPhaseCalls(d *fakedb.Driver) []string {
+	var result []string
+
+	for _, c := range d.Calls() {
+		switch {
+		case c.Method == "connect":
+		case c.Query != "":
+			result = append(result, c.Query)
+		default:
+			result = append(result, c.Method)
+		}
+	}
+
+	return result
+}
+
+func orderGID(_ context.Context, name string) string {
+	return "order_42_" + name
+}
+
+func TestWrapTwoPhase(t *testing.T) {
+	a, da := recordingDB(t, nil)
+	b, db := recordingDB(t, nil)
+
+	require.NoError(t, WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a, "b": b}, nil, orderGID,
+		func(ctx context.Context) error {
+			_, err := GetNamed(ctx, "b").ExecContext(ctx, "INSERT INTO b")
+
+			return err
+		},
+	))
+
+	assert.Equal(t, []string{
+		"begin", "PREPARE TRANSACTION 'order_42_a'", "rollback", "COMMIT PREPARED 'order_42_a'",
+	}, twoPhaseCalls(da))
+	assert.Equal(t, []string{
+		"begin", "INSERT INTO b", "PREPARE TRANSACTION 'order_42_b'", "rollback", "COMMIT PREPARED 'order_42_b'",
+	}, twoPhaseCalls(db))
+}
+
+func TestWrapTwoPhase_defaultGID(t *testing.T) {
+	a, da := recordingDB(t, nil)
+	b, db := recordingDB(t, nil)
+
+	require.NoError(t, WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a, "b": b}, nil, nil, noop))
+
+	prepareA, prepareB := twoPhaseCalls(da)[1], twoPhaseCalls(db)[1]
+
+	assert.Regexp(t, `^PREPARE TRANSACTION 'txx_[0-9a-f]{16}_a'$`, prepareA)
+	assert.Equal(t, strings.TrimSuffix(prepareA, "a'"), strings.TrimSuffix(prepareB, "b'"), "random part should be shared")
+}
+
+func TestWrapTwoPhase_prepareFailed(t *testing.T) {
+	errPrepare := errors.New("max_prepared_transactions is zero") //nolint:goerr113
+	a, da := recordingDB(t, nil)
+	b, db := recordingDB(t, map[string]error{"PREPARE": errPrepare})
+	c, dc := recordingDB(t, nil)
+
+	err := WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a, "b": b, "c": c}, nil, orderGID, noop)
+
+	var e *TwoPhaseError
+
+	require.ErrorAs(t, err, &e)
+	assert.ErrorIs(t, err, ErrTwoPhaseCommit)
+	assert.ErrorIs(t, err, ErrPrepareFailed)
+	assert.ErrorIs(t, err, errPrepare)
+	assert.Empty(t, e.Committed)
+	assert.Empty(t, e.Orphaned)
+	assert.Equal(t, map[string]string{"a": "order_42_a"}, e.GIDs)
+
+	assert.Equal(t, []string{
+		"begin", "PREPARE TRANSACTION 'order_42_a'", "rollback", "ROLLBACK PREPARED 'order_42_a'",
+	}, twoPhaseCalls(da))
+	assert.Equal(t, []string{"begin", "PREPARE TRANSACTION 'order_42_b'", "rollback"}, twoPhaseCalls(db))
+	assert.Equal(t, []string{"begin", "rollback"}, twoPhaseCalls(dc))
+}
+
+func TestWrapTwoPhase_orphaned(t *testing.T) {
+	errConn := errors.New("connection reset") //nolint:goerr113
+	a, _ := recordingDB(t, map[string]error{"ROLLBACK PREPARED": errConn})
+	b, _ := recordingDB(t, map[string]error{"PREPARE": errConn})
+
+	err := WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a, "b": b}, nil, orderGID, noop)
+
+	var e *TwoPhaseError
+
+	require.ErrorAs(t, err, &e)
+	assert.ErrorIs(t, err, ErrRollbackFailed)
+	assert.Equal(t, map[string]string{"a": "order_42_a"}, e.Orphaned)
+	assert.Contains(t, err.Error(), "orphaned [a=order_42_a]")
+}
+
+func TestWrapTwoPhase_commitFailed(t *testing.T) {
+	errConn := errors.New("connection reset") //nolint:goerr113
+	a, _ := recordingDB(t, map[string]error{"COMMIT PREPARED": errConn})
+	b, db := recordingDB(t, nil)
+
+	err := WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a, "b": b}, nil, orderGID, noop)
+
+	var e *TwoPhaseError
+
+	require.ErrorAs(t, err, &e)
+	assert.ErrorIs(t, err, ErrCommitFailed)
+	assert.ErrorIs(t, err, errConn)
+	assert.Equal(t, []string{"b"}, e.Committed, "other commits should go on")
+	assert.Equal(t, map[string]string{"a": "order_42_a"}, e.Orphaned)
+	assert.Equal(t, map[string]string{"a": "order_42_a", "b": "order_42_b"}, e.GIDs)
+	assert.Contains(t, twoPhaseCalls(db), "COMMIT PREPARED 'order_42_b'")
+}
+
+func TestWrapTwoPhase_rollback(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	a, da := recordingDB(t, nil)
+
+	err := WrapTwoPhase(context.Background(), map[string]TwoPhaseDB{"a": a}, nil, orderGID, func(context.Context) error {
+		return errFailed
+	})
+
+	require.ErrorIs(t, err, errFailed)
+	assert.NotErrorIs(t, err, ErrTwoPhaseCommit)
+	assert.Equal(t, []string{"begin", "rollback"}, twoPhaseCalls(da))
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	assert.Equal(t, "'it''s'", quoteLiteral("it's"))
+}
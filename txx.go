@@ -3,36 +3,173 @@ package txx
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
 )
 
+// Beginner begins transactions, like *sql.DB and *sql.Conn.
+//
+// A transaction begun on a *sql.Conn is pinned to this connection,
+// which stays owned by the caller: txx never closes it.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 // Current transaction stored in context.
 type Current struct {
+	// Tx is the transaction, owned by txx when begun by Wrap: it must not be committed or rolled back directly,
+	// see ErrManagedTransaction, Executor giving access to its statements only.
 	Tx   *sql.Tx
 	Opts *sql.TxOptions
+	// Tenant owning the transaction when begun by a TenantManager.
+	Tenant string
+	// Nesting is the number of savepoints of Nested enclosing the current function, 0 outside of Nested.
+	Nesting int
+	// Depth is the number of frames sharing the transaction down to the current function, 0 without transaction:
+	// 1 in the function of Wrap, or for a transaction stored by Set, plus 1 per Ensure or Nested reusing it.
+	Depth int
+
+	s *scope
+	// ext identifies a transaction stored by Set.
+	ext *external
+}
+
+// external identifies a transaction not begun by txx, see Set.
+type external struct {
+	once      sync.Once
+	id        uint64
+	startedAt time.Time
 }
 
-// IsValid returns if current transaction is valid.
+// IsValid returns if current transaction is valid, not being finished by Commit or Rollback,
+// nor directly by Tx.Commit or Tx.Rollback once a statement of Current reported it,
+// so that Ensure begins a new transaction instead of reusing it.
 func (c Current) IsValid() bool {
-	return c.Tx != nil
+	return c.Tx != nil && !c.s.isFinishing()
+}
+
+// ID identifies the transaction, like Info.ID, stable across reuses by Ensure, 0 without transaction.
+//
+// A transaction stored by Set is given an ID on first call.
+func (c Current) ID() uint64 {
+	switch {
+	case c.s != nil:
+		return c.s.id
+	case c.ext != nil:
+		c.ext.once.Do(func() {
+			c.ext.id = transactions.Add(1)
+		})
+
+		return c.ext.id
+	}
+
+	return 0
+}
+
+// StartedAt returns when the transaction was begun, or stored by Set, the zero time without transaction.
+func (c Current) StartedAt() time.Time {
+	switch {
+	case c.s != nil:
+		return c.s.startedAt
+	case c.ext != nil:
+		return c.ext.startedAt
+	}
+
+	return time.Time{}
+}
+
+// Duration returns for how long the transaction has been open, 0 without transaction.
+func (c Current) Duration() time.Duration {
+	switch {
+	case c.s != nil && c.s.clock != nil:
+		return c.s.clock.Now().Sub(c.s.startedAt)
+	case c.ext != nil:
+		return time.Since(c.ext.startedAt)
+	}
+
+	return 0
+}
+
+// Reused reports if the current function reuses the transaction of an enclosing frame, see Depth,
+// rather than being the outermost one, which completes it.
+func (c Current) Reused() bool {
+	return c.Depth > 1
+}
+
+// deeper returns a copy of ctx for a function reusing its transaction, one frame deeper, see Current.Depth.
+func deeper(ctx context.Context) context.Context {
+	current := Get(ctx)
+	current.Depth++
+
+	return context.WithValue(ctx, ctxKey, current)
+}
+
+// IsInTx returns if given context carries a valid transaction.
+func IsInTx(ctx context.Context) bool {
+	return Get(ctx).IsValid()
 }
 
 // NewTransactionRequired returns if a new transaction is required to match given options.
+//
+// Nil options are equivalent to zero-value options.
+// LevelDefault is the unknown level of the driver or session: any current transaction satisfies it,
+// while a current transaction of that level doesn't satisfy any explicit level,
+// unless begun by a Manager declaring the level, see WithDefaultIsolation.
+// Read-only options are satisfied by a read-write transaction according to the default ReadOnlyReuse policy.
 func (c Current) NewTransactionRequired(opts *sql.TxOptions) bool {
+	return c.newTransactionRequired(opts, sql.LevelDefault, ReadOnlyReuse(defaultReadOnlyReuse.Load()))
+}
+
+// newTransactionRequired is NewTransactionRequired with LevelDefault resolving to given level,
+// or else to the one declared for the current transaction, following given policy.
+func (c Current) newTransactionRequired(opts *sql.TxOptions, level sql.IsolationLevel, reuse ReadOnlyReuse) bool {
 	if !c.IsValid() {
 		return true
 	}
 
-	if c.Opts == nil {
-		return opts != nil
-	} else if opts == nil {
+	if level == sql.LevelDefault && c.s != nil {
+		level = c.s.isolation
+	}
+
+	return requiresNew(c.Opts, opts, level, reuse)
+}
+
+// requiresNew returns if a transaction of given current options doesn't match requested ones,
+// LevelDefault resolving to given level, following given policy.
+func requiresNew(currentOpts, opts *sql.TxOptions, level sql.IsolationLevel, reuse ReadOnlyReuse) bool {
+	current, requested := normalize(currentOpts, level), normalize(opts, level)
+
+	if current.ReadOnly != requested.ReadOnly && (current.ReadOnly || reuse != ReuseReadWrite) {
 		return true
 	}
 
-	if c.Opts.ReadOnly != opts.ReadOnly {
+	switch {
+	case requested.Isolation == sql.LevelDefault:
+		return false
+	case current.Isolation == sql.LevelDefault:
 		return true
+	default:
+		return requested.Isolation > current.Isolation
+	}
+}
+
+// normalize returns given options, nil being equivalent to zero-value options,
+// and LevelDefault resolving to given level.
+func normalize(opts *sql.TxOptions, level sql.IsolationLevel) sql.TxOptions {
+	var result sql.TxOptions
+
+	if opts != nil {
+		result = *opts
+	}
+
+	if result.Isolation == sql.LevelDefault {
+		result.Isolation = level
 	}
 
-	return opts.Isolation > c.Opts.Isolation
+	return result
 }
 
 // ReadOnly returns a read-only transaction option.
@@ -40,44 +177,157 @@ func ReadOnly() *sql.TxOptions {
 	return &sql.TxOptions{ReadOnly: true}
 }
 
+// SerializableReadOnly returns a serializable read-only transaction option.
+func SerializableReadOnly() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+}
+
+// Serializable returns a serializable transaction option.
+func Serializable() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelSerializable}
+}
+
+// RepeatableRead returns a repeatable read transaction option.
+func RepeatableRead() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+}
+
+// ReadCommitted returns a read committed transaction option.
+func ReadCommitted() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+}
+
+// TxOption configures transaction options, see Options.
+type TxOption func(opts *sql.TxOptions)
+
+// Isolation sets the isolation level of transaction options.
+func Isolation(level sql.IsolationLevel) TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.Isolation = level
+	}
+}
+
+// ReadOnlyAccess makes transaction options read-only.
+func ReadOnlyAccess() TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.ReadOnly = true
+	}
+}
+
+// Options returns transaction options configured by given ones, in order,
+// like Options(Isolation(sql.LevelSerializable), ReadOnlyAccess()).
+func Options(opts ...TxOption) *sql.TxOptions {
+	result := &sql.TxOptions{}
+
+	for _, opt := range opts {
+		opt(result)
+	}
+
+	return result
+}
+
 // Ensure function f run in a transaction with given options.
 //
 // If a transaction already exists matching given options, this transaction is reused,
 // otherwise a new transaction is created.
-func Ensure(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
-	current := Get(ctx)
-	if current.NewTransactionRequired(opts) {
-		return Wrap(ctx, db, opts, f)
+func Ensure(ctx context.Context, db Beginner, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	_, err := EnsureValue(ctx, db, opts, noValue(f))
+
+	return err
+}
+
+// EnsureValue is Ensure for a function returning a value, the zero value being returned on error.
+func EnsureValue[T any](
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) (T, error),
+) (T, error) {
+	if transactionRequired(ctx, opts, sql.LevelDefault, nil) {
+		return WrapValue(ctx, db, opts, f)
 	}
 
-	return f(ctx)
+	var result T
+
+	err := runReused(nil, clock.Real{}, opts, Get(ctx).s.getLabels(), func() error {
+		if err := Get(ctx).s.checkAborted(); err != nil {
+			return err
+		}
+
+		var err error
+
+		result, err = f(deeper(ctx))
+
+		return Get(ctx).s.markRollback(err)
+	})
+
+	return valueOrZero(result, err)
 }
 
 // Wrap function f in a new transaction with given options.
 //
 // If function f returns an error or panic, the transaction is aborted,
-// otherwise the transaction is committed.
-func Wrap(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
-	tx, err := db.BeginTx(ctx, opts)
-	if err != nil {
-		return err
-	}
+// otherwise the transaction is committed and the commit error is returned.
+// A rollback error is joined to the error of f, unless sql.ErrTxDone.
+//
+// If ctx is done once f returns without error, the transaction is aborted rather than committed
+// and ctx.Err() is returned: the caller gave up and believes the work failed.
+func Wrap(ctx context.Context, db Beginner, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	_, err := WrapValue(ctx, db, opts, noValue(f))
 
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
+	return err
+}
+
+// WrapConn is Wrap for a transaction pinned to given connection, so that session state like settings,
+// temporary tables and advisory locks of the connection is shared with the transaction.
+//
+// The connection stays owned by the caller: it is not closed. Ensure reuses the transaction like any other.
+func WrapConn(ctx context.Context, conn *sql.Conn, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	return Wrap(ctx, conn, opts, f)
+}
+
+// WrapValue is Wrap for a function returning a value.
+//
+// The zero value is returned if the transaction is not committed, even if f returned a value.
+func WrapValue[T any](
+	ctx context.Context,
+	db Beginner,
+	opts *sql.TxOptions,
+	f func(ctx context.Context) (T, error),
+) (T, error) {
+	var result T
+
+	err := runner{b: sqlBeginner{db: db}}.run(ctx, opts, func(ctx context.Context, _ transaction) error {
+		var err error
 
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		} else {
-			err = tx.Commit()
+		result, err = f(ctx)
+		if errors.Is(err, ErrRollback) {
+			var zero T
+
+			result = zero
 		}
-	}()
 
-	err = f(Set(ctx, tx, opts))
+		return err
+	})
+
+	return valueOrZero(result, err)
+}
 
-	return err
+// noValue adapts a function without value to WrapValue and EnsureValue.
+func noValue(f func(ctx context.Context) error) func(ctx context.Context) (struct{}, error) {
+	return func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	}
+}
+
+func valueOrZero[T any](v T, err error) (T, error) {
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return v, nil
 }
 
 type key int
@@ -93,9 +343,25 @@ func Get(ctx context.Context) Current {
 	return Current{}
 }
 
+// Detach returns a copy of ctx without current transaction, for work outliving it like background goroutines.
+//
+// Other values and the deadline of ctx are kept. Given context is returned if it has no current transaction.
+func Detach(ctx context.Context) context.Context {
+	if !Get(ctx).IsValid() {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxKey, Current{})
+}
+
+// Set returns a copy of ctx carrying given transaction begun outside of txx, reused by Ensure.
+//
+// Its ID is generated on first use, see Current.ID, and it is considered started by Set.
 func Set(ctx context.Context, tx *sql.Tx, opts *sql.TxOptions) context.Context {
 	return context.WithValue(ctx, ctxKey, Current{
-		Tx:   tx,
-		Opts: opts,
+		Tx:    tx,
+		Opts:  opts,
+		Depth: 1,
+		ext:   &external{startedAt: time.Now()},
 	})
 }
@@ -6,24 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/MartyHub/txx/internal/clock"
+	"github.com/MartyHub/txx/internal/testdb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	_ "modernc.org/sqlite"
 )
 
-func testDB(t *testing.T) *sql.DB {
-	t.Helper()
-
-	db, err := sql.Open("sqlite", ":memory:")
-	require.NoError(t, err)
-
-	t.Cleanup(func() {
-		_ = db.Close()
-	})
-
-	return db
-}
+// Options of the databases of the tests, see testdb.Open.
+//
+//nolint:gochecknoglobals
+var (
+	// inMemory databases are shared by their connections, each test having its own.
+	inMemory = testdb.WithSharedCache()
+	// onDisk databases are files allowing concurrent transactions, readers not blocking writers.
+	onDisk = testdb.WithFile()
+)
 
 func checkTxExists(ctx context.Context) error {
 	if !Get(ctx).IsValid() {
@@ -56,6 +56,46 @@ func TestReadOnly(t *testing.T) {
 	assert.Equal(t, sql.LevelDefault, opts.Isolation)
 }
 
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      *sql.TxOptions
+		isolation sql.IsolationLevel
+		readOnly  bool
+	}{
+		{name: "Serializable", opts: Serializable(), isolation: sql.LevelSerializable},
+		{name: "RepeatableRead", opts: RepeatableRead(), isolation: sql.LevelRepeatableRead},
+		{name: "ReadCommitted", opts: ReadCommitted(), isolation: sql.LevelReadCommitted},
+		{name: "SerializableReadOnly", opts: SerializableReadOnly(), isolation: sql.LevelSerializable, readOnly: true},
+		{name: "Options()", opts: Options(), isolation: sql.LevelDefault},
+		{name: "Options(ReadOnlyAccess)", opts: Options(ReadOnlyAccess()), isolation: sql.LevelDefault, readOnly: true},
+		{
+			name:      "Options(Isolation)",
+			opts:      Options(Isolation(sql.LevelRepeatableRead)),
+			isolation: sql.LevelRepeatableRead,
+		},
+		{
+			name:      "Options(Isolation, ReadOnlyAccess)",
+			opts:      Options(Isolation(sql.LevelSerializable), ReadOnlyAccess()),
+			isolation: sql.LevelSerializable,
+			readOnly:  true,
+		},
+		{
+			name:      "Options(Isolation, Isolation)",
+			opts:      Options(Isolation(sql.LevelSerializable), Isolation(sql.LevelReadCommitted)),
+			isolation: sql.LevelReadCommitted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NotNil(t, tt.opts)
+			assert.Equal(t, tt.readOnly, tt.opts.ReadOnly)
+			assert.Equal(t, tt.isolation, tt.opts.Isolation)
+		})
+	}
+}
+
 func TestCurrent_IsValid(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -94,6 +134,18 @@ func TestCurrent_NewTransactionRequired(t *testing.T) { //nolint:funlen
 			opts:    nil,
 			want:    false,
 		},
+		{
+			name:    "nil->zero",
+			current: Current{Tx: &sql.Tx{}},
+			opts:    &sql.TxOptions{},
+			want:    false,
+		},
+		{
+			name:    "zero->nil",
+			current: Current{Tx: &sql.Tx{}, Opts: &sql.TxOptions{}},
+			opts:    nil,
+			want:    false,
+		},
 		{
 			name:    "readonly->readonly",
 			current: Current{Tx: &sql.Tx{}, Opts: ReadOnly()},
@@ -136,6 +188,66 @@ func TestCurrent_NewTransactionRequired(t *testing.T) { //nolint:funlen
 			opts:    &sql.TxOptions{Isolation: sql.LevelWriteCommitted},
 			want:    true,
 		},
+		{
+			name:    "default->read uncommitted",
+			current: Current{Tx: &sql.Tx{}, Opts: &sql.TxOptions{}},
+			opts:    &sql.TxOptions{Isolation: sql.LevelReadUncommitted},
+			want:    true,
+		},
+		{
+			name:    "read uncommitted->default",
+			current: Current{Tx: &sql.Tx{}, Opts: &sql.TxOptions{Isolation: sql.LevelReadUncommitted}},
+			opts:    &sql.TxOptions{},
+			want:    false,
+		},
+		{
+			name:    "default->serializable",
+			current: Current{Tx: &sql.Tx{}, Opts: &sql.TxOptions{}},
+			opts:    Serializable(),
+			want:    true,
+		},
+		{
+			name:    "serializable->default",
+			current: Current{Tx: &sql.Tx{}, Opts: Serializable()},
+			opts:    nil,
+			want:    false,
+		},
+		{
+			name:    "default read-only->read committed read-only",
+			current: Current{Tx: &sql.Tx{}, Opts: ReadOnly()},
+			opts:    Options(Isolation(sql.LevelReadCommitted), ReadOnlyAccess()),
+			want:    true,
+		},
+		{
+			name:    "built serializable read-only->serializable read-only",
+			current: Current{Tx: &sql.Tx{}, Opts: Options(Isolation(sql.LevelSerializable), ReadOnlyAccess())},
+			opts:    SerializableReadOnly(),
+			want:    false,
+		},
+		{
+			name:    "serializable read-only->built read-only",
+			current: Current{Tx: &sql.Tx{}, Opts: SerializableReadOnly()},
+			opts:    Options(ReadOnlyAccess()),
+			want:    false,
+		},
+		{
+			name:    "serializable->serializable read-only",
+			current: Current{Tx: &sql.Tx{}, Opts: Serializable()},
+			opts:    Options(Isolation(sql.LevelSerializable), ReadOnlyAccess()),
+			want:    true,
+		},
+		{
+			name:    "repeatable read->read committed",
+			current: Current{Tx: &sql.Tx{}, Opts: RepeatableRead()},
+			opts:    ReadCommitted(),
+			want:    false,
+		},
+		{
+			name:    "read committed->serializable",
+			current: Current{Tx: &sql.Tx{}, Opts: ReadCommitted()},
+			opts:    Serializable(),
+			want:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,8 +257,41 @@ func TestCurrent_NewTransactionRequired(t *testing.T) { //nolint:funlen
 	}
 }
 
+func TestCurrent_newTransactionRequired_defaultIsolation(t *testing.T) {
+	readUncommitted := &sql.TxOptions{Isolation: sql.LevelReadUncommitted}
+	readCommitted := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	readCommittedReadOnly := &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: true}
+	repeatableRead := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+
+	tests := []struct {
+		name    string
+		current *sql.TxOptions
+		opts    *sql.TxOptions
+		level   sql.IsolationLevel
+		want    bool
+	}{
+		{name: "undeclared default->read committed", opts: readCommitted, want: true},
+		{name: "undeclared read committed->default", current: readCommitted, want: false},
+		{name: "default->read committed", opts: readCommitted, level: sql.LevelReadCommitted, want: false},
+		{name: "default->zero", opts: &sql.TxOptions{}, level: sql.LevelReadCommitted, want: false},
+		{name: "read committed->default", current: readCommitted, level: sql.LevelReadCommitted, want: false},
+		{name: "default->read uncommitted", opts: readUncommitted, level: sql.LevelReadCommitted, want: false},
+		{name: "default->repeatable read", opts: repeatableRead, level: sql.LevelReadCommitted, want: true},
+		{name: "repeatable read->default", current: repeatableRead, level: sql.LevelReadCommitted, want: false},
+		{name: "default->read only", opts: readCommittedReadOnly, level: sql.LevelReadCommitted, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := Current{Tx: &sql.Tx{}, Opts: tt.current}
+
+			assert.Equal(t, tt.want, current.newTransactionRequired(tt.opts, tt.level, ReuseStrict))
+		})
+	}
+}
+
 func TestEnsure(t *testing.T) {
-	db := testDB(t)
+	db := testdb.Open(t, inMemory)
 	tx := &sql.Tx{}
 
 	tests := []struct {
@@ -187,7 +332,7 @@ func TestEnsure(t *testing.T) {
 }
 
 func TestWrap(t *testing.T) {
-	db := testDB(t)
+	db := testdb.Open(t, inMemory)
 	tests := []struct {
 		name    string
 		f       func(ctx context.Context) error
@@ -214,6 +359,156 @@ func TestWrap(t *testing.T) {
 	}
 }
 
+func TestWrapValue(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	id, err := WrapValue(context.Background(), db, nil, func(ctx context.Context) (int64, error) {
+		return ExecReturning[int64](ctx, db, "INSERT INTO person (name) VALUES (?) RETURNING id", "Carol")
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+
+	id, err = WrapValue(context.Background(), db, nil, func(ctx context.Context) (int64, error) {
+		id, err := ExecReturning[int64](ctx, db, "INSERT INTO person (name) VALUES (?) RETURNING id", "Dave")
+		require.NoError(t, err)
+
+		return id, fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Zero(t, id, "partial result should not be returned on rollback")
+	assert.Equal(t, 3, countPeople(t, db))
+
+	require.PanicsWithValue(t, "test", func() {
+		_, _ = WrapValue(context.Background(), db, nil, func(_ context.Context) (int, error) {
+			panic("test")
+		})
+	})
+}
+
+func TestEnsureValue(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	name, err := EnsureValue(context.Background(), db, ReadOnly(), func(ctx context.Context) (string, error) {
+		require.NoError(t, checkTxExists(ctx))
+
+		return QueryOne[string](ctx, db, "SELECT name FROM person WHERE id = ?", 1)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", name)
+
+	tx := &sql.Tx{}
+
+	got, err := EnsureValue(Set(context.Background(), tx, nil), db, nil, func(ctx context.Context) (*sql.Tx, error) {
+		return Get(ctx).Tx, nil
+	})
+
+	require.NoError(t, err)
+	assert.Same(t, tx, got, "existing transaction should be reused")
+
+	got, err = EnsureValue(Set(context.Background(), tx, nil), db, nil, func(ctx context.Context) (*sql.Tx, error) {
+		return Get(ctx).Tx, fail(ctx)
+	})
+
+	require.Error(t, err)
+	assert.Nil(t, got)
+}
+
+func TestCurrent_ID(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	m := New(testdb.Open(t, inMemory), WithClock(c))
+	ids := make(map[uint64]struct{})
+
+	for i := 0; i < 3; i++ {
+		start := c.Now()
+
+		require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+			current := Get(ctx)
+			ids[current.ID()] = struct{}{}
+
+			assert.NotZero(t, current.ID())
+			assert.Equal(t, start, current.StartedAt())
+
+			c.Advance(time.Second)
+
+			return m.Ensure(ctx, nil, func(ctx context.Context) error {
+				return Nested(ctx, nil, nil, func(ctx context.Context) error {
+					assert.Equal(t, current.ID(), Get(ctx).ID(), "ID should be stable across reuses")
+					assert.Equal(t, current.StartedAt(), Get(ctx).StartedAt())
+					assert.Equal(t, time.Second, Get(ctx).Duration())
+
+					return nil
+				})
+			})
+		}))
+	}
+
+	assert.Len(t, ids, 3, "IDs should differ across Wrap calls")
+}
+
+func TestCurrent_ID_set(t *testing.T) {
+	before := time.Now()
+	ctx := Set(context.Background(), &sql.Tx{}, nil)
+	current := Get(ctx)
+
+	id := current.ID()
+
+	assert.NotZero(t, id)
+	assert.Equal(t, id, Get(ctx).ID(), "ID should be generated once")
+	assert.NotEqual(t, id, Get(Set(context.Background(), &sql.Tx{}, nil)).ID())
+	assert.False(t, current.StartedAt().Before(before))
+	assert.LessOrEqual(t, current.Duration(), time.Since(before))
+
+	assert.Zero(t, Get(context.Background()).ID())
+	assert.True(t, Get(context.Background()).StartedAt().IsZero())
+	assert.Zero(t, Get(context.Background()).Duration())
+}
+
+func TestCurrent_Depth(t *testing.T) {
+	db := testdb.Open(t, inMemory)
+	m := New(db)
+
+	var depths []int
+
+	record := func(ctx context.Context) {
+		depths = append(depths, Get(ctx).Depth)
+	}
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		record(ctx)
+		assert.False(t, Get(ctx).Reused())
+
+		err := m.Ensure(ctx, nil, func(ctx context.Context) error {
+			record(ctx)
+			assert.True(t, Get(ctx).Reused())
+
+			err := Ensure(ctx, db, nil, func(ctx context.Context) error {
+				record(ctx)
+
+				return Nested(ctx, db, nil, func(ctx context.Context) error {
+					record(ctx)
+
+					return nil
+				})
+			})
+
+			record(ctx)
+
+			return err
+		})
+
+		record(ctx)
+
+		return err
+	}))
+
+	assert.Equal(t, []int{1, 2, 3, 4, 2, 1}, depths)
+	assert.Zero(t, Get(context.Background()).Depth)
+	assert.Equal(t, 1, Get(Set(context.Background(), &sql.Tx{}, nil)).Depth)
+}
+
 func TestGet(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -259,3 +554,103 @@ func TestSet(t *testing.T) {
 	assert.Equal(t, tx, current.Tx)
 	assert.Equal(t, opts, current.Opts)
 }
+
+func TestWrap_conn(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	require.NoError(t, Wrap(context.Background(), conn, nil, func(ctx context.Context) error {
+		return insertPerson(ctx, db, "Carol")
+	}))
+	require.NoError(t, conn.PingContext(context.Background()), "connection should not be closed")
+	assert.Equal(t, 3, countPeople(t, db))
+}
+
+func TestWrapConn(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "CREATE TEMPORARY TABLE session_item (name TEXT)")
+	require.NoError(t, err)
+
+	require.NoError(t, WrapConn(ctx, conn, nil, func(ctx context.Context) error {
+		outer := Get(ctx).Tx
+
+		if _, err := Get(ctx).ExecContext(ctx, "INSERT INTO session_item VALUES ('pinned')"); err != nil {
+			return err
+		}
+
+		if _, err := Get(ctx).ExecContext(ctx, "PRAGMA case_sensitive_like = 1"); err != nil {
+			return err
+		}
+
+		return Ensure(ctx, db, nil, func(ctx context.Context) error {
+			assert.Same(t, outer, Get(ctx).Tx, "transaction should be reused")
+
+			n, err := QueryOne[int](ctx, db, "SELECT COUNT(*) FROM session_item WHERE name LIKE 'PINNED'")
+			require.NoError(t, err)
+			assert.Zero(t, n, "session setting should be visible in the transaction")
+
+			return nil
+		})
+	}))
+
+	var name string
+
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT name FROM session_item").Scan(&name),
+		"connection should not be closed")
+	assert.Equal(t, "pinned", name)
+
+	require.Error(t, WrapConn(ctx, conn, nil, func(ctx context.Context) error {
+		_, err := Get(ctx).ExecContext(ctx, "INSERT INTO session_item VALUES ('rolled back')")
+		require.NoError(t, err)
+
+		return fail(ctx)
+	}))
+
+	var n int
+
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM session_item").Scan(&n))
+	assert.Equal(t, 1, n)
+}
+
+func TestDetach(t *testing.T) {
+	type valueKey struct{}
+
+	db := testdb.Open(t, onDisk, testdb.WithSchema(peopleSchema))
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), valueKey{}, "value"), time.Minute)
+
+	defer cancel()
+
+	assert.Equal(t, ctx, Detach(ctx), "detaching without transaction should be a no-op")
+
+	require.NoError(t, Wrap(ctx, db, nil, func(ctx context.Context) error {
+		outer := Get(ctx)
+		detached := Detach(ctx)
+
+		assert.False(t, IsInTx(detached))
+		assert.Equal(t, "value", detached.Value(valueKey{}))
+
+		deadline, _ := ctx.Deadline()
+		got, found := detached.Deadline()
+		assert.True(t, found)
+		assert.Equal(t, deadline, got)
+
+		return Ensure(detached, db, nil, func(ctx context.Context) error {
+			assert.True(t, IsInTx(ctx))
+			assert.NotSame(t, outer.Tx, Get(ctx).Tx, "a fresh transaction should be begun")
+			assert.NotEqual(t, outer.ID(), Get(ctx).ID())
+
+			return nil
+		})
+	}))
+}
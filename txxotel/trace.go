@@ -0,0 +1,142 @@
+// Package txxotel integrates txx with OpenTelemetry tracing.
+package txxotel
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+
+	"github.com/MartyHub/txx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys of transaction spans.
+const (
+	KeyTxID      = attribute.Key("txx.tx_id")
+	KeyReadOnly  = attribute.Key("txx.read_only")
+	KeyIsolation = attribute.Key("txx.isolation")
+	KeyReused    = attribute.Key("txx.reused")
+	KeyCause     = attribute.Key("txx.cause")
+	// KeyLabelPrefix prefixes the keys of the labels of transactions, see txx.WithLabelsFromContext.
+	KeyLabelPrefix = "txx.label."
+)
+
+// Span names.
+const (
+	SpanWrap   = "txx.Wrap"
+	SpanEnsure = "txx.Ensure"
+)
+
+// Use traces the transactions of m with given tracer, see Interceptor and ReuseInterceptor.
+func Use(m *txx.Manager, tracer trace.Tracer) {
+	m.Use(Interceptor(tracer))
+	m.UseOnReuse(ReuseInterceptor(tracer))
+}
+
+// Interceptor returns a txx.Interceptor starting a span for every transaction begun by Wrap,
+// child of the span of its context if any, and carried by the context of the transaction function.
+//
+// The commit or rollback of the transaction is recorded as a span event, and a failure as the span status.
+// Labels of the transaction, see txx.WithLabelsFromContext, are attributes prefixed by KeyLabelPrefix.
+func Interceptor(tracer trace.Tracer) txx.Interceptor {
+	return func(next txx.RunFunc) txx.RunFunc {
+		return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+			ctx, span := tracer.Start(ctx, SpanWrap, trace.WithAttributes(attributes(opts, false)...))
+			defer end(span)
+
+			err := next(ctx, db, opts, func(ctx context.Context) error {
+				span.SetAttributes(current(ctx)...)
+
+				// registered before the transaction ends, it can't fail
+				_ = txx.OnCommit(ctx, func(_ context.Context) {
+					span.AddEvent("commit")
+				})
+				txx.OnRollback(ctx, func(_ context.Context, cause error) {
+					if cause == nil {
+						span.AddEvent("rollback")
+					} else {
+						span.AddEvent("rollback", trace.WithAttributes(KeyCause.String(cause.Error())))
+					}
+				})
+
+				return f(ctx)
+			})
+
+			fail(span, err)
+
+			return err
+		}
+	}
+}
+
+// ReuseInterceptor returns a txx.Interceptor starting a span for every function run by Ensure in a reused
+// transaction, child of the span of the transaction, see txx.Manager.UseOnReuse.
+func ReuseInterceptor(tracer trace.Tracer) txx.Interceptor {
+	return func(next txx.RunFunc) txx.RunFunc {
+		return func(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+			ctx, span := tracer.Start(ctx, SpanEnsure, trace.WithAttributes(attributes(opts, true)...))
+			defer end(span)
+
+			span.SetAttributes(current(ctx)...)
+
+			err := next(ctx, db, opts, f)
+
+			fail(span, err)
+
+			return err
+		}
+	}
+}
+
+// attributes of a span for given options.
+func attributes(opts *sql.TxOptions, reused bool) []attribute.KeyValue {
+	var o sql.TxOptions
+
+	if opts != nil {
+		o = *opts
+	}
+
+	return []attribute.KeyValue{
+		KeyReadOnly.Bool(o.ReadOnly),
+		KeyIsolation.String(o.Isolation.String()),
+		KeyReused.Bool(reused),
+	}
+}
+
+// current returns the attributes of the transaction of given context: its ID and labels.
+func current(ctx context.Context) []attribute.KeyValue {
+	c := txx.Get(ctx)
+	labels := c.Labels()
+	result := make([]attribute.KeyValue, 0, 1+len(labels))
+
+	result = append(result, KeyTxID.Int64(int64(c.ID()))) //nolint:gosec
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		result = append(result, attribute.String(KeyLabelPrefix+key, labels[key]))
+	}
+
+	return result
+}
+
+// end given span, recording a panic, if any, before propagating it.
+func end(span trace.Span) {
+	if p := recover(); p != nil {
+		fail(span, &txx.PanicError{Value: p})
+		span.End()
+
+		panic(p)
+	}
+
+	span.End()
+}
+
+// fail records given error, if any, as the status of given span.
+func fail(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
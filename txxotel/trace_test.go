@@ -0,0 +1,155 @@
+package txxotel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+func manager(t *testing.T, opts ...txx.Option) (*txx.Manager, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	rec := tracetest.NewSpanRecorder()
+	m := txx.New(db, opts...)
+
+	Use(m, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test"))
+
+	return m, rec
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	result := make(map[attribute.Key]attribute.Value)
+
+	for _, kv := range span.Attributes() {
+		result[kv.Key] = kv.Value
+	}
+
+	return result
+}
+
+func events(span sdktrace.ReadOnlySpan) []string {
+	var result []string
+
+	for _, event := range span.Events() {
+		result = append(result, event.Name)
+	}
+
+	return result
+}
+
+func TestUse(t *testing.T) {
+	m, rec := manager(t)
+
+	require.NoError(t, m.Wrap(context.Background(), txx.Serializable(), func(ctx context.Context) error {
+		return m.Ensure(ctx, nil, func(ctx context.Context) error {
+			_, err := txx.Get(ctx).ExecContext(ctx, "SELECT 1")
+
+			return err
+		})
+	}))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+
+	ensure, wrap := spans[0], spans[1]
+
+	assert.Equal(t, SpanWrap, wrap.Name())
+	assert.False(t, wrap.Parent().IsValid(), "Wrap should start a root span")
+	assert.Equal(t, []string{"commit"}, events(wrap))
+	assert.Equal(t, codes.Unset, wrap.Status().Code)
+	assert.False(t, attrs(wrap)[KeyReadOnly].AsBool())
+	assert.Equal(t, "Serializable", attrs(wrap)[KeyIsolation].AsString())
+	assert.False(t, attrs(wrap)[KeyReused].AsBool())
+
+	assert.Equal(t, SpanEnsure, ensure.Name())
+	assert.Equal(t, wrap.SpanContext().SpanID(), ensure.Parent().SpanID(), "reuse should start a child span")
+	assert.True(t, attrs(ensure)[KeyReused].AsBool())
+	assert.Equal(t, attrs(wrap)[KeyTxID], attrs(ensure)[KeyTxID])
+}
+
+func TestUse_rollback(t *testing.T) {
+	m, rec := manager(t)
+	errTest := errors.New("test") //nolint:goerr113
+
+	require.ErrorIs(t, m.Wrap(context.Background(), txx.ReadOnly(), func(_ context.Context) error {
+		return errTest
+	}), errTest)
+
+	require.Panics(t, func() {
+		_ = m.Wrap(context.Background(), nil, func(_ context.Context) error {
+			panic("boom")
+		})
+	})
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+
+	assert.True(t, attrs(spans[0])[KeyReadOnly].AsBool())
+	assert.Equal(t, []string{"rollback", "exception"}, events(spans[0]))
+	assert.Equal(t, "test", spans[0].Events()[0].Attributes[0].Value.AsString())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+
+	assert.Equal(t, []string{"rollback", "exception"}, events(spans[1]))
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "panic: boom", spans[1].Status().Description)
+}
+
+func TestUse_parent(t *testing.T) {
+	m, rec := manager(t)
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+
+	require.NoError(t, m.Wrap(ctx, nil, func(ctx context.Context) error {
+		_, inner := tracer.Start(ctx, "query")
+		inner.End()
+
+		return nil
+	}))
+
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+
+	query, wrap := spans[0], spans[1]
+
+	assert.Equal(t, parent.SpanContext().SpanID(), wrap.Parent().SpanID())
+	assert.Equal(t, wrap.SpanContext().SpanID(), query.Parent().SpanID(), "f should be given the span")
+}
+
+func TestUse_labels(t *testing.T) {
+	m, rec := manager(t, txx.WithLabelsFromContext(func(_ context.Context) map[string]string {
+		return map[string]string{"endpoint": "/orders"}
+	}))
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		return m.Ensure(ctx, nil, func(_ context.Context) error {
+			return nil
+		})
+	}))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+
+	for _, span := range spans {
+		assert.Equal(t, "/orders", attrs(span)[KeyLabelPrefix+"endpoint"].AsString(), span.Name())
+	}
+}
@@ -0,0 +1,54 @@
+//go:build integration
+
+package txxpgx
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cockroachDB returns the CockroachDB database of the TXX_COCKROACH_URL environment variable,
+// skipping the test if not set.
+func cockroachDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("TXX_COCKROACH_URL")
+	if url == "" {
+		t.Skip("TXX_COCKROACH_URL not set")
+	}
+
+	db, err := sql.Open("pgx", url)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db
+}
+
+func TestWithAsOfSystemTime_cockroach(t *testing.T) {
+	db := cockroachDB(t)
+
+	var infos []txx.Info
+
+	m := txx.New(db, txx.WithDialect(txx.DialectCockroach), txx.WithAsOfSystemTime("-1s"),
+		txx.WithFinally(func(info txx.Info) { infos = append(infos, info) }))
+
+	require.NoError(t, m.Wrap(context.Background(), txx.ReadOnly(), func(ctx context.Context) error {
+		var n int
+
+		return txx.Get(ctx).QueryRowContext(ctx, "SELECT 1").Scan(&n)
+	}))
+
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Committed)
+	assert.Equal(t, "'-1s'", infos[0].AsOfSystemTime)
+}
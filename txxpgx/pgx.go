@@ -0,0 +1,206 @@
+// Package txxpgx is txx for native pgx transactions, like the ones of pgxpool, see https://github.com/jackc/pgx.
+//
+// Transactions follow the rules of txx: reuse by Ensure, savepoints of Nested, commit, rollback and panics,
+// with the ReadOnlyReuse policy of txx. They are stored under their own context key: txx.Get doesn't see them.
+package txxpgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/MartyHub/txx"
+	"github.com/jackc/pgx/v5"
+)
+
+// Beginner begins pgx transactions, like pgxpool.Pool and pgx.Conn.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Current pgx transaction stored in context.
+type Current struct {
+	Tx   pgx.Tx
+	Opts pgx.TxOptions
+	// Nesting is the number of savepoints of Nested enclosing the current function, 0 outside of Nested.
+	Nesting int
+
+	// ended is set once the transaction of Wrap is committed or rolled back.
+	ended *atomic.Bool
+}
+
+type ctxKey struct{}
+
+// IsValid returns if current transaction is valid, not being completed by its Wrap.
+func (c Current) IsValid() bool {
+	return c.Tx != nil && (c.ended == nil || !c.ended.Load())
+}
+
+// NewTransactionRequired returns if a new transaction is required to match given options,
+// like txx.Current.NewTransactionRequired, following the default txx.ReadOnlyReuse policy.
+func (c Current) NewTransactionRequired(opts pgx.TxOptions) bool {
+	return c.newTransactionRequired(context.Background(), opts)
+}
+
+// newTransactionRequired is NewTransactionRequired following the txx.ReadOnlyReuse policy of ctx.
+func (c Current) newTransactionRequired(ctx context.Context, opts pgx.TxOptions) bool {
+	if !c.IsValid() {
+		return true
+	}
+
+	if opts.DeferrableMode == pgx.Deferrable && c.Opts.DeferrableMode != pgx.Deferrable {
+		return true
+	}
+
+	return txx.NewTransactionRequired(ctx, options(c.Opts), options(opts))
+}
+
+// options returns the database/sql options of given pgx options, except their deferrable mode.
+func options(opts pgx.TxOptions) *sql.TxOptions {
+	result := &sql.TxOptions{ReadOnly: opts.AccessMode == pgx.ReadOnly}
+
+	switch opts.IsoLevel {
+	case pgx.ReadUncommitted:
+		result.Isolation = sql.LevelReadUncommitted
+	case pgx.ReadCommitted:
+		result.Isolation = sql.LevelReadCommitted
+	case pgx.RepeatableRead:
+		result.Isolation = sql.LevelRepeatableRead
+	case pgx.Serializable:
+		result.Isolation = sql.LevelSerializable
+	}
+
+	return result
+}
+
+// Get returns the current transaction if any.
+func Get(ctx context.Context) Current {
+	current, _ := ctx.Value(ctxKey{}).(Current)
+
+	return current
+}
+
+// Set returns a copy of ctx carrying given transaction begun outside of txxpgx, reused by Ensure.
+func Set(ctx context.Context, tx pgx.Tx, opts pgx.TxOptions) context.Context {
+	return context.WithValue(ctx, ctxKey{}, Current{Tx: tx, Opts: opts})
+}
+
+// Detach returns a copy of ctx without current transaction, see txx.Detach.
+func Detach(ctx context.Context) context.Context {
+	if !Get(ctx).IsValid() {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxKey{}, Current{})
+}
+
+// Ensure function f run in a transaction with given options, reusing the current one if it matches them,
+// like txx.Ensure.
+func Ensure(ctx context.Context, db Beginner, opts pgx.TxOptions, f func(ctx context.Context) error) error {
+	if Get(ctx).newTransactionRequired(ctx, opts) {
+		return Wrap(ctx, db, opts, f)
+	}
+
+	return f(ctx)
+}
+
+// Wrap function f in a new transaction with given options, like txx.Wrap, see txx.WrapBackend.
+//
+// If function f returns an error or panics, or if ctx is done once it returns, the transaction is rolled back,
+// otherwise it is committed. Errors of begin, commit and rollback wrap the corresponding errors of txx,
+// like txx.ErrCommitFailed, a rollback error being joined to the error of f.
+func Wrap(ctx context.Context, db Beginner, opts pgx.TxOptions, f func(ctx context.Context) error) error {
+	b := backend{db: db, opts: opts}
+
+	return txx.WrapBackend(ctx, b, options(opts), func(ctx context.Context, tx txx.BackendTx) error {
+		t := tx.(transaction) //nolint:forcetypeassert
+
+		return f(context.WithValue(ctx, ctxKey{}, Current{Tx: t.tx, Opts: opts, ended: t.ended}))
+	})
+}
+
+// Nested runs function f like Ensure, except that a reused transaction is protected by a savepoint,
+// like txx.Nested: if f returns an error, only its work is rolled back and the transaction remains usable.
+//
+// Savepoints are the pseudo nested transactions of pgx.Tx.Begin. A panic of f is not recovered,
+// aborting the whole transaction.
+func Nested(ctx context.Context, db Beginner, opts pgx.TxOptions, f func(ctx context.Context) error) error {
+	current := Get(ctx)
+	if current.newTransactionRequired(ctx, opts) {
+		return Wrap(ctx, db, opts, f)
+	}
+
+	tx, err := current.Tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("txxpgx: creating savepoint: %w", err)
+	}
+
+	ended := &atomic.Bool{}
+	defer ended.Store(true)
+
+	if err = f(context.WithValue(ctx, ctxKey{}, Current{
+		Tx:      tx,
+		Opts:    current.Opts,
+		Nesting: current.Nesting + 1,
+		ended:   ended,
+	})); err != nil {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: to savepoint: %w", txx.ErrRollbackFailed, rollbackErr))
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("txxpgx: releasing savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// backend begins the pgx transactions of Wrap.
+type backend struct {
+	db   Beginner
+	opts pgx.TxOptions
+}
+
+func (b backend) Begin(ctx context.Context, _ *sql.TxOptions) (txx.BackendTx, error) {
+	tx, err := b.db.BeginTx(ctx, b.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction{tx: tx, ended: &atomic.Bool{}}, nil
+}
+
+// transaction is a pgx transaction driven by txx, ended once committed or rolled back.
+type transaction struct {
+	tx    pgx.Tx
+	ended *atomic.Bool
+}
+
+func (t transaction) Commit(ctx context.Context) error {
+	t.ended.Store(true)
+
+	return t.tx.Commit(ctx)
+}
+
+func (t transaction) Rollback(ctx context.Context) error {
+	t.ended.Store(true)
+
+	// rolled back even once ctx is done
+	err := t.tx.Rollback(context.WithoutCancel(ctx))
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("%w: %w", sql.ErrTxDone, err)
+	}
+
+	return err
+}
+
+func (t transaction) Exec(ctx context.Context, query string) error {
+	_, err := t.tx.Exec(ctx, query)
+
+	return err
+}
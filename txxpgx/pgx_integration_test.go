@@ -0,0 +1,75 @@
+//go:build integration
+
+package txxpgx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresPool returns a pool of the PostgreSQL database of the TXX_POSTGRES_URL environment variable,
+// with an empty person table, skipping the test if not set.
+func postgresPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TXX_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TXX_POSTGRES_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	require.NoError(t, err)
+
+	t.Cleanup(pool.Close)
+
+	_, err = pool.Exec(context.Background(), `
+		DROP TABLE IF EXISTS txxpgx_person;
+		CREATE TABLE txxpgx_person (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+	`)
+	require.NoError(t, err)
+
+	return pool
+}
+
+func TestWrap_postgres(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	pool := postgresPool(t)
+	insert := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := Get(ctx).Tx.Exec(ctx, "INSERT INTO txxpgx_person (name) VALUES ($1)", name)
+
+			return err
+		}
+	}
+
+	require.NoError(t, Wrap(context.Background(), pool, pgx.TxOptions{}, func(ctx context.Context) error {
+		require.NoError(t, insert("Alice")(ctx))
+		require.ErrorIs(t, Nested(ctx, pool, pgx.TxOptions{}, func(ctx context.Context) error {
+			require.NoError(t, insert("Bob")(ctx))
+
+			return errFailed
+		}), errFailed)
+
+		return Ensure(ctx, pool, pgx.TxOptions{IsoLevel: pgx.ReadCommitted}, insert("Carol"))
+	}))
+
+	require.ErrorIs(t, Wrap(context.Background(), pool, pgx.TxOptions{}, func(ctx context.Context) error {
+		require.NoError(t, insert("Dave")(ctx))
+
+		return errFailed
+	}), errFailed)
+
+	rows, err := pool.Query(context.Background(), "SELECT name FROM txxpgx_person ORDER BY id")
+	require.NoError(t, err)
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Carol"}, names)
+}
@@ -0,0 +1,263 @@
+package txxpgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBeginner begins fakeTx transactions, recording their calls.
+type fakeBeginner struct {
+	beginErr  error
+	commitErr error
+	calls     []string
+}
+
+func (b *fakeBeginner) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	b.calls = append(b.calls, fmt.Sprintf("begin %s %s", opts.IsoLevel, opts.AccessMode))
+
+	if b.beginErr != nil {
+		return nil, b.beginErr
+	}
+
+	return &fakeTx{b: b}, nil
+}
+
+// fakeTx is a pgx.Tx whose savepoints are named after their depth, other methods than completion panicking.
+type fakeTx struct {
+	pgx.Tx
+
+	b     *fakeBeginner
+	depth int
+}
+
+func (tx *fakeTx) Begin(_ context.Context) (pgx.Tx, error) {
+	tx.b.calls = append(tx.b.calls, fmt.Sprintf("savepoint sp_%d", tx.depth+1))
+
+	return &fakeTx{b: tx.b, depth: tx.depth + 1}, nil
+}
+
+func (tx *fakeTx) Commit(_ context.Context) error {
+	if tx.depth > 0 {
+		tx.b.calls = append(tx.b.calls, fmt.Sprintf("release sp_%d", tx.depth))
+
+		return nil
+	}
+
+	tx.b.calls = append(tx.b.calls, "commit")
+
+	return tx.b.commitErr
+}
+
+func (tx *fakeTx) Rollback(_ context.Context) error {
+	if tx.depth > 0 {
+		tx.b.calls = append(tx.b.calls, fmt.Sprintf("rollback to sp_%d", tx.depth))
+	} else {
+		tx.b.calls = append(tx.b.calls, "rollback")
+	}
+
+	return nil
+}
+
+func TestWrap(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	errDriver := errors.New("driver") //nolint:goerr113
+
+	tests := []struct {
+		name      string
+		b         *fakeBeginner
+		f         func(ctx context.Context) error
+		wantErr   []error
+		wantCalls []string
+	}{
+		{
+			name:      "commit",
+			b:         &fakeBeginner{},
+			f:         func(context.Context) error { return nil },
+			wantCalls: []string{"begin  ", "commit"},
+		},
+		{
+			name:      "rollback",
+			b:         &fakeBeginner{},
+			f:         func(context.Context) error { return errFailed },
+			wantErr:   []error{errFailed},
+			wantCalls: []string{"begin  ", "rollback"},
+		},
+		{
+			name:      "begin error",
+			b:         &fakeBeginner{beginErr: errDriver},
+			f:         func(context.Context) error { return nil },
+			wantErr:   []error{txx.ErrBeginFailed, errDriver},
+			wantCalls: []string{"begin  "},
+		},
+		{
+			name:      "commit error",
+			b:         &fakeBeginner{commitErr: errDriver},
+			f:         func(context.Context) error { return nil },
+			wantErr:   []error{txx.ErrCommitFailed, errDriver},
+			wantCalls: []string{"begin  ", "commit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Wrap(context.Background(), tt.b, pgx.TxOptions{}, tt.f)
+
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			}
+
+			for _, want := range tt.wantErr {
+				require.ErrorIs(t, err, want)
+			}
+
+			assert.Equal(t, tt.wantCalls, tt.b.calls)
+		})
+	}
+}
+
+func TestWrap_panic(t *testing.T) {
+	b := &fakeBeginner{}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = Wrap(context.Background(), b, pgx.TxOptions{}, func(context.Context) error {
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, []string{"begin  ", "rollback"}, b.calls)
+}
+
+func TestWrap_canceled(t *testing.T) {
+	b := &fakeBeginner{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := Wrap(ctx, b, pgx.TxOptions{}, func(context.Context) error {
+		cancel()
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"begin  ", "rollback"}, b.calls)
+}
+
+func TestEnsure(t *testing.T) {
+	b := &fakeBeginner{}
+
+	require.NoError(t, Ensure(context.Background(), b, pgx.TxOptions{}, func(ctx context.Context) error {
+		outer := Get(ctx)
+
+		assert.True(t, outer.IsValid())
+
+		require.NoError(t, Ensure(ctx, b, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(ctx context.Context) error {
+			assert.NotSame(t, outer.Tx, Get(ctx).Tx, "stronger isolation should begin a new transaction")
+
+			return Ensure(ctx, b, pgx.TxOptions{IsoLevel: pgx.ReadCommitted}, func(ctx context.Context) error {
+				assert.Equal(t, pgx.Serializable, Get(ctx).Opts.IsoLevel, "weaker isolation should reuse")
+
+				return nil
+			})
+		}))
+
+		return Ensure(ctx, b, pgx.TxOptions{}, func(ctx context.Context) error {
+			assert.Same(t, outer.Tx, Get(ctx).Tx)
+
+			return nil
+		})
+	}))
+
+	assert.Equal(t, []string{"begin  ", "begin serializable ", "commit", "commit"}, b.calls)
+}
+
+func TestCurrent_NewTransactionRequired(t *testing.T) {
+	tx := &fakeTx{}
+	readWrite := Current{Tx: tx}
+	readOnly := Current{Tx: tx, Opts: pgx.TxOptions{AccessMode: pgx.ReadOnly}}
+	repeatable := Current{Tx: tx, Opts: pgx.TxOptions{IsoLevel: pgx.RepeatableRead}}
+
+	tests := []struct {
+		name    string
+		current Current
+		opts    pgx.TxOptions
+		reuse   txx.ReadOnlyReuse
+		want    bool
+	}{
+		{name: "no transaction", current: Current{}, want: true},
+		{name: "default", current: readWrite},
+		{name: "read-only in read-write", current: readWrite, opts: readOnly.Opts, want: true},
+		{name: "read-only reusing read-write", current: readWrite, opts: readOnly.Opts, reuse: txx.ReuseReadWrite},
+		{name: "read-write in read-only", current: readOnly, opts: readWrite.Opts, reuse: txx.ReuseReadWrite, want: true},
+		{name: "explicit level in default", current: readWrite, opts: repeatable.Opts, want: true},
+		{name: "weaker level", current: repeatable, opts: pgx.TxOptions{IsoLevel: pgx.ReadCommitted}},
+		{name: "stronger level", current: repeatable, opts: pgx.TxOptions{IsoLevel: pgx.Serializable}, want: true},
+		{name: "deferrable", current: readOnly, opts: pgx.TxOptions{
+			AccessMode:     pgx.ReadOnly,
+			DeferrableMode: pgx.Deferrable,
+		}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := txx.SetReadOnlyReuse(context.Background(), tt.reuse)
+
+			assert.Equal(t, tt.want, tt.current.newTransactionRequired(ctx, tt.opts))
+		})
+	}
+}
+
+func TestNested(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	b := &fakeBeginner{}
+
+	require.NoError(t, Wrap(context.Background(), b, pgx.TxOptions{}, func(ctx context.Context) error {
+		require.ErrorIs(t, Nested(ctx, b, pgx.TxOptions{}, func(ctx context.Context) error {
+			assert.Equal(t, 1, Get(ctx).Nesting)
+
+			return Nested(ctx, b, pgx.TxOptions{}, func(ctx context.Context) error {
+				assert.Equal(t, 2, Get(ctx).Nesting)
+
+				return errFailed
+			})
+		}), errFailed)
+
+		assert.True(t, Get(ctx).IsValid(), "transaction should remain usable")
+
+		return Nested(ctx, b, pgx.TxOptions{}, func(context.Context) error { return nil })
+	}))
+
+	assert.Equal(t, []string{
+		"begin  ",
+		"savepoint sp_1", "savepoint sp_2", "rollback to sp_2", "rollback to sp_1",
+		"savepoint sp_1", "release sp_1",
+		"commit",
+	}, b.calls)
+}
+
+func TestNested_noTransaction(t *testing.T) {
+	b := &fakeBeginner{}
+
+	require.NoError(t, Nested(context.Background(), b, pgx.TxOptions{}, func(ctx context.Context) error {
+		assert.Zero(t, Get(ctx).Nesting)
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"begin  ", "commit"}, b.calls)
+}
+
+func TestSet(t *testing.T) {
+	tx := &fakeTx{}
+	ctx := Set(context.Background(), tx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+
+	assert.Same(t, tx, Get(ctx).Tx)
+	assert.True(t, Get(ctx).IsValid())
+	assert.False(t, Get(Detach(ctx)).IsValid())
+	assert.False(t, txx.IsInTx(ctx), "core package should not see the transaction")
+}
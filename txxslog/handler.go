@@ -0,0 +1,96 @@
+// Package txxslog integrates txx with log/slog.
+package txxslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/MartyHub/txx"
+)
+
+// KeyTxID is the key of the transaction ID attribute.
+const KeyTxID = "tx_id"
+
+// group opened by WithGroup, with the attributes added to it.
+type group struct {
+	name  string
+	attrs []slog.Attr
+}
+
+type handler struct {
+	inner slog.Handler
+	// root is the inner handler before any group, groups being replayed on top of it
+	// so that the transaction attributes stay top-level.
+	root   slog.Handler
+	groups []group
+}
+
+// NewHandler returns a slog.Handler adding the ID of the transaction of the record context,
+// if valid, as a top-level tx_id attribute before passing records to inner.
+//
+// Records without transaction are passed as is, without allocating.
+func NewHandler(inner slog.Handler) slog.Handler {
+	return handler{inner: inner, root: inner}
+}
+
+func (h handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h handler) Handle(ctx context.Context, record slog.Record) error {
+	current := txx.Get(ctx)
+	if !current.IsValid() || current.ID() == 0 {
+		return h.inner.Handle(ctx, record)
+	}
+
+	attr := slog.Uint64(KeyTxID, current.ID())
+
+	if len(h.groups) == 0 {
+		record = record.Clone()
+		record.AddAttrs(attr)
+
+		return h.inner.Handle(ctx, record)
+	}
+
+	inner := h.root.WithAttrs([]slog.Attr{attr})
+
+	for _, g := range h.groups {
+		inner = inner.WithGroup(g.name)
+
+		if len(g.attrs) > 0 {
+			inner = inner.WithAttrs(g.attrs)
+		}
+	}
+
+	return inner.Handle(ctx, record)
+}
+
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	result := handler{inner: h.inner.WithAttrs(attrs), root: h.root}
+
+	if len(h.groups) == 0 {
+		result.root = result.inner
+	} else {
+		result.groups = append([]group(nil), h.groups...)
+		last := &result.groups[len(result.groups)-1]
+		last.attrs = append(append([]slog.Attr(nil), last.attrs...), attrs...)
+	}
+
+	return result
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return handler{
+		inner:  h.inner.WithGroup(name),
+		root:   h.root,
+		groups: append(append([]group(nil), h.groups...), group{name: name}),
+	}
+}
@@ -0,0 +1,114 @@
+package txxslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/MartyHub/txx/txxtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// lines decodes the JSON records written to buf.
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var result []map[string]any
+
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+
+		require.NoError(t, dec.Decode(&line))
+
+		result = append(result, line)
+	}
+
+	return result
+}
+
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil)))
+	db := txxtest.NewDB(t, txxtest.WithSharedCache())
+
+	logger.InfoContext(context.Background(), "outside")
+
+	var id uint64
+
+	require.NoError(t, txx.Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		id = txx.Get(ctx).ID()
+		logger.InfoContext(ctx, "inside")
+
+		return txx.Ensure(ctx, db, nil, func(ctx context.Context) error {
+			logger.InfoContext(ctx, "nested")
+
+			return nil
+		})
+	}))
+
+	got := lines(t, &buf)
+	require.Len(t, got, 3)
+
+	assert.NotContains(t, got[0], KeyTxID)
+	assert.NotZero(t, id)
+	assert.InDelta(t, float64(id), got[1][KeyTxID], 0)
+	assert.InDelta(t, float64(id), got[2][KeyTxID], 0)
+}
+
+func TestNewHandler_groups(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).
+		With("app", "test").
+		WithGroup("req").
+		With("path", "/items")
+
+	db := txxtest.NewDB(t, txxtest.WithSharedCache())
+
+	require.NoError(t, txx.Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		logger.InfoContext(ctx, "inside", "status", 200)
+
+		return nil
+	}))
+
+	logger.InfoContext(context.Background(), "outside", "status", 200)
+
+	got := lines(t, &buf)
+	require.Len(t, got, 2)
+
+	assert.Contains(t, got[0], KeyTxID, "transaction ID should be top-level")
+	assert.Equal(t, "test", got[0]["app"])
+	assert.Equal(t, map[string]any{"path": "/items", "status": float64(200)}, got[0]["req"])
+
+	assert.NotContains(t, got[1], KeyTxID)
+	assert.Equal(t, map[string]any{"path": "/items", "status": float64(200)}, got[1]["req"])
+}
+
+func TestNewHandler_noTransactionAllocs(t *testing.T) {
+	h := NewHandler(discard{})
+	record := slog.Record{Message: "outside"}
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = h.Handle(ctx, record)
+	})
+
+	assert.Zero(t, allocs)
+}
+
+type discard struct{}
+
+func (discard) Enabled(_ context.Context, _ slog.Level) bool { return true }
+
+func (discard) Handle(_ context.Context, _ slog.Record) error { return nil }
+
+func (d discard) WithAttrs(_ []slog.Attr) slog.Handler { return d }
+
+func (d discard) WithGroup(_ string) slog.Handler { return d }
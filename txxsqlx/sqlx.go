@@ -0,0 +1,103 @@
+// Package txxsqlx integrates txx with sqlx, see https://github.com/jmoiron/sqlx.
+//
+// Transactions are the ones of txx, stored under the same context key: txx.Get sees the underlying sql.Tx,
+// and Ensure reuses transactions begun by either package, following the same compatibility rules.
+package txxsqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/MartyHub/txx"
+	"github.com/jmoiron/sqlx"
+)
+
+// Current is the current transaction of txx as a sqlx transaction.
+type Current struct {
+	txx.Current
+	// Tx is the sqlx transaction of txx.Current.
+	Tx *sqlx.Tx
+}
+
+type txKey struct{}
+
+// Get returns the current transaction if any.
+//
+// A transaction begun by txx outside of this package has no driver name: sqlx doesn't rebind its queries,
+// which must then use ? placeholders, but it uses the mapper of the database of the closest Wrap or Ensure.
+func Get(ctx context.Context) Current {
+	current := txx.Get(ctx)
+	if current.Tx == nil {
+		return Current{}
+	}
+
+	tx, _ := ctx.Value(txKey{}).(*sqlx.Tx)
+	if tx != nil && tx.Tx == current.Tx {
+		return Current{Current: current, Tx: tx}
+	}
+
+	result := &sqlx.Tx{Tx: current.Tx}
+	if tx != nil {
+		result.Mapper = tx.Mapper
+	}
+
+	return Current{Current: current, Tx: result}
+}
+
+// Querier returns the current transaction if valid, db otherwise, for the sqlx functions
+// like sqlx.GetContext, sqlx.SelectContext and sqlx.NamedExecContext.
+func Querier(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	if current := Get(ctx); current.IsValid() {
+		return current.Tx
+	}
+
+	return db
+}
+
+// Wrap is txx.Wrap for a sqlx database.
+func Wrap(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	b := &beginner{db: db}
+
+	return txx.Wrap(ctx, b, opts, b.bind(f))
+}
+
+// Ensure is txx.Ensure for a sqlx database.
+func Ensure(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, f func(ctx context.Context) error) error {
+	b := &beginner{db: db}
+
+	return txx.Ensure(ctx, b, opts, b.bind(f))
+}
+
+// beginner begins sqlx transactions, keeping the last one begun.
+type beginner struct {
+	db *sqlx.DB
+	tx *sqlx.Tx
+}
+
+func (b *beginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := b.db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b.tx = tx
+
+	return tx.Tx, nil
+}
+
+// bind returns f run with the sqlx transaction begun by b, if current.
+func (b *beginner) bind(f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		tx := b.tx
+		if tx == nil || tx.Tx != txx.Get(ctx).Tx {
+			// reused
+			tx = &sqlx.Tx{Tx: txx.Get(ctx).Tx, Mapper: b.db.Mapper}
+
+			if outer, _ := ctx.Value(txKey{}).(*sqlx.Tx); outer != nil && outer.Tx == tx.Tx {
+				tx = outer
+			}
+		}
+
+		return f(context.WithValue(ctx, txKey{}, tx))
+	}
+}
@@ -0,0 +1,121 @@
+package txxsqlx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/MartyHub/txx/txxtest"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+type person struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// peopleSchema creates the person table, of Alice and Bob.
+const peopleSchema = `
+	CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	INSERT INTO person (name) VALUES ('Alice'), ('Bob');
+`
+
+func names(t *testing.T, ctx context.Context, db *sqlx.DB) []string {
+	t.Helper()
+
+	var result []string
+
+	require.NoError(t, sqlx.SelectContext(ctx, Querier(ctx, db), &result, "SELECT name FROM person ORDER BY id"))
+
+	return result
+}
+
+func TestWrap(t *testing.T) {
+	db := sqlx.NewDb(txxtest.NewDB(t, txxtest.WithFile(), txxtest.WithSchema(peopleSchema)), "sqlite")
+
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		current := Get(ctx)
+
+		require.True(t, current.IsValid())
+		assert.Same(t, txx.Get(ctx).Tx, current.Tx.Tx, "core package should see the transaction")
+		assert.Equal(t, "sqlite", current.Tx.DriverName())
+
+		_, err := sqlx.NamedExecContext(ctx, Querier(ctx, db),
+			"INSERT INTO person (name) VALUES (:name)", person{Name: "Carol"})
+		require.NoError(t, err)
+
+		var got person
+
+		require.NoError(t, sqlx.GetContext(ctx, Querier(ctx, db), &got, "SELECT * FROM person WHERE name = ?", "Carol"))
+		assert.Equal(t, person{ID: 3, Name: "Carol"}, got)
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(t, context.Background(), db))
+}
+
+func TestWrap_rollback(t *testing.T) {
+	errFailed := errors.New("failed") //nolint:goerr113
+	db := sqlx.NewDb(txxtest.NewDB(t, txxtest.WithFile(), txxtest.WithSchema(peopleSchema)), "sqlite")
+
+	require.ErrorIs(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		Get(ctx).Tx.MustExecContext(ctx, "INSERT INTO person (name) VALUES ('Carol')")
+
+		return errFailed
+	}), errFailed)
+
+	assert.Equal(t, []string{"Alice", "Bob"}, names(t, context.Background(), db))
+}
+
+func TestEnsure(t *testing.T) {
+	db := sqlx.NewDb(txxtest.NewDB(t, txxtest.WithFile(), txxtest.WithSchema(peopleSchema)), "sqlite")
+
+	require.NoError(t, Ensure(context.Background(), db, nil, func(ctx context.Context) error {
+		outer := Get(ctx)
+
+		return Ensure(ctx, db, nil, func(ctx context.Context) error {
+			assert.Same(t, outer.Tx, Get(ctx).Tx, "transaction should be reused")
+			assert.Equal(t, outer.ID(), Get(ctx).ID())
+
+			return nil
+		})
+	}))
+
+	require.NoError(t, Ensure(context.Background(), db, nil, func(ctx context.Context) error {
+		return Ensure(ctx, db, txx.ReadOnly(), func(ctx context.Context) error {
+			assert.True(t, Get(ctx).Opts.ReadOnly, "incompatible transaction should not be reused")
+			assert.Equal(t, "sqlite", Get(ctx).Tx.DriverName())
+
+			return nil
+		})
+	}))
+}
+
+func TestEnsure_core(t *testing.T) {
+	db := sqlx.NewDb(txxtest.NewDB(t, txxtest.WithFile(), txxtest.WithSchema(peopleSchema)), "sqlite")
+
+	require.NoError(t, txx.Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		return Ensure(ctx, db, nil, func(ctx context.Context) error {
+			current := Get(ctx)
+
+			assert.Same(t, txx.Get(ctx).Tx, current.Tx.Tx, "core transaction should be reused")
+			assert.Same(t, db.Mapper, current.Tx.Mapper)
+			assert.Empty(t, current.Tx.DriverName())
+
+			return sqlx.GetContext(ctx, Querier(ctx, db), new(int), "SELECT COUNT(*) FROM person")
+		})
+	}))
+}
+
+func TestGet_noTransaction(t *testing.T) {
+	db := sqlx.NewDb(txxtest.NewDB(t, txxtest.WithFile(), txxtest.WithSchema(peopleSchema)), "sqlite")
+
+	assert.False(t, Get(context.Background()).IsValid())
+	assert.Nil(t, Get(context.Background()).Tx)
+	assert.Same(t, db, Querier(context.Background(), db))
+}
@@ -0,0 +1,79 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/fakedb"
+)
+
+// Beginner is a txx.Beginner whose transactions don't need a database, to test failure paths.
+//
+// Begin, Commit and Rollback script the outcome of the corresponding calls,
+// and must be set before beginning transactions. Statements succeed without effect, returning no rows.
+// Every call is recorded, see Calls.
+type Beginner struct {
+	// Begin, if not nil, returns the error of beginning a transaction with given options.
+	Begin func(opts *sql.TxOptions) error
+	// Commit, if not nil, returns the error of committing a transaction.
+	Commit func() error
+	// Rollback, if not nil, returns the error of rolling back a transaction.
+	Rollback func() error
+
+	d  *fakedb.Driver
+	db *sql.DB
+}
+
+// NewBeginner returns a Beginner whose transactions succeed, closed by the test cleanup.
+func NewBeginner(tb testing.TB) *Beginner {
+	tb.Helper()
+
+	b := &Beginner{}
+	b.d = &fakedb.Driver{
+		Begin: func(opts *sql.TxOptions) error {
+			if b.Begin == nil {
+				return nil
+			}
+
+			return b.Begin(opts)
+		},
+		Commit: func() error {
+			return call(b.Commit)
+		},
+		Rollback: func() error {
+			return call(b.Rollback)
+		},
+	}
+	b.db = fakedb.Open(tb, b.d)
+
+	return b
+}
+
+// BeginTx begins a transaction, failing with the error of Begin if any.
+func (b *Beginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return b.db.BeginTx(ctx, opts)
+}
+
+// Calls returns the calls made so far, like "begin", "exec INSERT ...", "query SELECT ...",
+// "commit" and "rollback".
+func (b *Beginner) Calls() []string {
+	var result []string
+
+	for _, c := range b.d.Calls() {
+		if c.Method != "connect" {
+			result = append(result, c.String())
+		}
+	}
+
+	return result
+}
+
+// call returns the error of given script, if any.
+func call(script func() error) error {
+	if script == nil {
+		return nil
+	}
+
+	return script()
+}
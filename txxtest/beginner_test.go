@@ -0,0 +1,94 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeginner(t *testing.T) {
+	b := NewBeginner(t)
+	serializable := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	var got *sql.TxOptions
+
+	b.Begin = func(opts *sql.TxOptions) error {
+		got = opts
+
+		return nil
+	}
+
+	require.NoError(t, txx.Wrap(context.Background(), b, serializable, func(ctx context.Context) error {
+		_, err := txx.Get(ctx).ExecContext(ctx, "UPDATE item SET name = ?", "name")
+		if err != nil {
+			return err
+		}
+
+		rows, err := txx.Get(ctx).QueryContext(ctx, "SELECT name FROM item")
+		if err != nil {
+			return err
+		}
+
+		assert.False(t, rows.Next())
+
+		return rows.Close()
+	}))
+
+	assert.Equal(t, serializable, got)
+	assert.Equal(t, []string{"begin", "exec UPDATE item SET name = ?", "query SELECT name FROM item", "commit"}, b.Calls())
+}
+
+func TestBeginner_failures(t *testing.T) {
+	errDriver := errors.New("driver") //nolint:goerr113
+	errFailed := errors.New("failed") //nolint:goerr113
+
+	tests := []struct {
+		name      string
+		script    func(b *Beginner)
+		f         func(ctx context.Context) error
+		wantErr   []error
+		wantCalls []string
+	}{
+		{
+			name:      "begin",
+			script:    func(b *Beginner) { b.Begin = func(_ *sql.TxOptions) error { return errDriver } },
+			f:         func(_ context.Context) error { return nil },
+			wantErr:   []error{txx.ErrBeginFailed, errDriver},
+			wantCalls: []string{"begin"},
+		},
+		{
+			name:      "commit",
+			script:    func(b *Beginner) { b.Commit = func() error { return errDriver } },
+			f:         func(_ context.Context) error { return nil },
+			wantErr:   []error{txx.ErrCommitFailed, errDriver},
+			wantCalls: []string{"begin", "commit"},
+		},
+		{
+			name:      "rollback",
+			script:    func(b *Beginner) { b.Rollback = func() error { return errDriver } },
+			f:         func(_ context.Context) error { return errFailed },
+			wantErr:   []error{errFailed, txx.ErrRollbackFailed, errDriver},
+			wantCalls: []string{"begin", "rollback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBeginner(t)
+			tt.script(b)
+
+			err := txx.Wrap(context.Background(), b, nil, tt.f)
+
+			for _, want := range tt.wantErr {
+				require.ErrorIs(t, err, want)
+			}
+
+			assert.Equal(t, tt.wantCalls, b.Calls())
+		})
+	}
+}
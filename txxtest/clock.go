@@ -0,0 +1,16 @@
+// Package txxtest provides helpers to test code using txx.
+package txxtest
+
+import (
+	"time"
+
+	"github.com/MartyHub/txx/internal/clock"
+)
+
+// Clock is a txx.Clock whose time only moves when told to, see Advance.
+type Clock = clock.Fake
+
+// NewClock returns a Clock set to given time.
+func NewClock(now time.Time) *Clock {
+	return clock.NewFake(now)
+}
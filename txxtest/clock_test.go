@@ -0,0 +1,54 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestNewClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewClock(start)
+
+	var _ txx.Clock = c
+
+	c.Advance(time.Minute)
+
+	assert.Equal(t, start.Add(time.Minute), c.Now())
+}
+
+func TestClock_circuitBreaker(t *testing.T) {
+	c := NewClock(time.Unix(0, 0))
+	db := openClosed(t)
+	m := txx.New(db, txx.WithClock(c), txx.WithCircuitBreaker(txx.CircuitBreaker{Threshold: 1, CoolDown: time.Second}))
+	wrap := func() error {
+		return m.Wrap(context.Background(), nil, func(_ context.Context) error { return nil })
+	}
+
+	require.Error(t, wrap())
+	require.ErrorIs(t, wrap(), txx.ErrCircuitOpen)
+
+	c.Advance(time.Second)
+
+	err := wrap()
+
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, txx.ErrCircuitOpen), "half-open probe should reach the database")
+}
+
+func openClosed(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	return db
+}
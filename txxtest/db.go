@@ -0,0 +1,48 @@
+package txxtest
+
+import (
+	"database/sql"
+	"io/fs"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+)
+
+// Option configures a database returned by NewDB.
+type Option = testdb.Option
+
+// WithFile makes NewDB open a database file in a temporary directory, in WAL mode so that readers
+// don't block writers, instead of an in-memory database.
+func WithFile() Option {
+	return testdb.WithFile()
+}
+
+// WithSharedCache makes NewDB open an in-memory database shared by several connections,
+// instead of a single connection. Shared-cache connections lock whole tables.
+func WithSharedCache() Option {
+	return testdb.WithSharedCache()
+}
+
+// WithSchema makes NewDB apply given SQL script, a list of statements separated by semicolons.
+func WithSchema(script string) Option {
+	return testdb.WithSchema(script)
+}
+
+// WithSchemaFS makes NewDB apply given SQL files of fsys, in order, see WithSchema.
+func WithSchemaFS(fsys fs.FS, names ...string) Option {
+	return testdb.WithSchemaFS(fsys, names...)
+}
+
+// NewDB opens a SQLite database for test tb, closed by its cleanup, with foreign keys enabled
+// and a busy timeout of 5 seconds.
+//
+// By default, the database is in-memory and uniquely named, so that parallel tests don't collide,
+// on a single connection as every in-memory connection has its own database otherwise.
+// See WithFile and WithSharedCache for databases allowing concurrent connections.
+//
+// It requires the modernc.org/sqlite driver to be registered.
+func NewDB(tb testing.TB, opts ...Option) *sql.DB {
+	tb.Helper()
+
+	return testdb.Open(tb, opts...)
+}
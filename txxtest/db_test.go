@@ -0,0 +1,98 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const parentChild = `
+	CREATE TABLE parent (id INTEGER PRIMARY KEY);
+	CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL REFERENCES parent (id));
+`
+
+func pragma(t *testing.T, db *sql.DB, name string) string {
+	t.Helper()
+
+	var result string
+
+	require.NoError(t, db.QueryRow("PRAGMA "+name).Scan(&result))
+
+	return result
+}
+
+func TestNewDB(t *testing.T) {
+	db := NewDB(t, WithSchema(parentChild))
+
+	assert.Equal(t, "1", pragma(t, db, "foreign_keys"))
+	assert.Equal(t, "5000", pragma(t, db, "busy_timeout"))
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+
+	_, err := db.Exec("INSERT INTO child VALUES (1, 42)")
+	require.Error(t, err, "foreign keys should be enforced")
+}
+
+func TestNewDB_schemaFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_schema.sql": {Data: []byte(parentChild)},
+		"2_data.sql":   {Data: []byte("INSERT INTO parent VALUES (1);\nINSERT INTO child VALUES (1, 1);")},
+	}
+
+	db := NewDB(t, WithSchemaFS(fsys, "1_schema.sql", "2_data.sql"))
+
+	var n int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM child").Scan(&n))
+	assert.Equal(t, 1, n)
+}
+
+func TestNewDB_file(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(parentChild))
+
+	assert.Equal(t, "wal", pragma(t, db, "journal_mode"))
+	assert.Equal(t, "1", pragma(t, db, "foreign_keys"))
+	assert.Zero(t, db.Stats().MaxOpenConnections)
+}
+
+func TestNewDB_sharedCache(t *testing.T) {
+	db := NewDB(t, WithSharedCache(), WithSchema(parentChild))
+	ctx := context.Background()
+
+	c1, err := db.Conn(ctx)
+	require.NoError(t, err)
+
+	defer c1.Close()
+
+	c2, err := db.Conn(ctx)
+	require.NoError(t, err)
+
+	defer c2.Close()
+
+	_, err = c1.ExecContext(ctx, "INSERT INTO parent VALUES (1)")
+	require.NoError(t, err)
+
+	var n int
+
+	require.NoError(t, c2.QueryRowContext(ctx, "SELECT COUNT(*) FROM parent").Scan(&n))
+	assert.Equal(t, 1, n, "connections should share the database")
+}
+
+func TestNewDB_parallel(t *testing.T) {
+	for _, opt := range []Option{WithSharedCache(), WithFile()} {
+		for i := 0; i < 3; i++ {
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				t.Parallel()
+
+				db := NewDB(t, opt, WithSchema(parentChild))
+
+				_, err := db.Exec("INSERT INTO parent VALUES (1)")
+				require.NoError(t, err, "databases should not collide")
+			})
+		}
+	}
+}
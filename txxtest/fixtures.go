@@ -0,0 +1,142 @@
+package txxtest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/MartyHub/txx"
+	"github.com/MartyHub/txx/internal/testdb"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoTransaction is txx.ErrNoTransaction, wrapped by the error returned when fixtures are loaded
+// without a transaction in the context.
+//
+// Deprecated: use txx.ErrNoTransaction.
+var ErrNoTransaction = txx.ErrNoTransaction
+
+// LoadFixtures executes given fixture files of fsys in the transaction of the context,
+// so that fixtures vanish with its rollback.
+//
+// A .sql fixture is a list of statements separated by semicolons.
+// A .yaml or .yml fixture maps table names to lists of rows, each row mapping columns to values:
+//
+//	person:
+//	  - id: 1
+//	    name: Alice
+//
+// Errors report the file and line of the failing statement or row.
+func LoadFixtures(ctx context.Context, fsys fs.FS, names ...string) error {
+	current := txx.Get(ctx)
+	if !current.IsValid() {
+		return fmt.Errorf("txxtest: loading fixtures: %w", txx.ErrNoTransaction)
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("txxtest: loading fixture: %w", err)
+		}
+
+		var stmts []statement
+
+		switch path.Ext(name) {
+		case ".sql":
+			stmts = testdb.SplitSQL(string(data))
+		case ".yaml", ".yml":
+			if stmts, err = parseYAML(data); err != nil {
+				return fmt.Errorf("txxtest: %s: %w", name, err)
+			}
+		default:
+			return fmt.Errorf("txxtest: %s: unsupported fixture format", name) //nolint:goerr113
+		}
+
+		for _, stmt := range stmts {
+			if _, err = current.ExecContext(ctx, stmt.Query, stmt.Args...); err != nil {
+				return fmt.Errorf("txxtest: %s:%d: %w", name, stmt.Line, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// statement is a statement of a fixture.
+type statement = testdb.Statement
+
+// parseYAML parses a YAML fixture into INSERT statements.
+func parseYAML(data []byte) ([]statement, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	tables := doc.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: tables mapping expected", tables.Line) //nolint:goerr113
+	}
+
+	var result []statement
+
+	for i := 0; i+1 < len(tables.Content); i += 2 {
+		table, rows := tables.Content[i], tables.Content[i+1]
+		if rows.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("line %d: rows sequence expected for table %s", rows.Line, table.Value) //nolint:goerr113
+		}
+
+		for _, row := range rows.Content {
+			stmt, err := insert(table.Value, row)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, stmt)
+		}
+	}
+
+	return result, nil
+}
+
+func insert(table string, row *yaml.Node) (statement, error) {
+	if row.Kind != yaml.MappingNode {
+		return statement{}, fmt.Errorf("line %d: row mapping expected for table %s", row.Line, table) //nolint:goerr113
+	}
+
+	values := make(map[string]any, len(row.Content)/2) //nolint:mnd
+	columns := make([]string, 0, len(row.Content)/2)   //nolint:mnd
+
+	for i := 0; i+1 < len(row.Content); i += 2 {
+		var value any
+
+		if err := row.Content[i+1].Decode(&value); err != nil {
+			return statement{}, fmt.Errorf("line %d: %w", row.Content[i+1].Line, err)
+		}
+
+		columns = append(columns, row.Content[i].Value)
+		values[row.Content[i].Value] = value
+	}
+
+	sort.Strings(columns)
+
+	args := make([]any, 0, len(columns))
+	for _, column := range columns {
+		args = append(args, values[column])
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+
+	return statement{
+		Line:  row.Line,
+		Query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders),
+		Args:  args,
+	}, nil
+}
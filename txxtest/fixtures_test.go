@@ -0,0 +1,96 @@
+package txxtest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixtures() fstest.MapFS {
+	return fstest.MapFS{
+		"items.sql": &fstest.MapFile{Data: []byte(`-- items; with a comment
+INSERT INTO item (owner) VALUES ('alice');
+
+INSERT INTO item (owner)
+VALUES ('bob;');
+`)},
+		"items.yaml": &fstest.MapFile{Data: []byte(`item:
+  - owner: carol
+  - owner: dave
+`)},
+		"broken.sql": &fstest.MapFile{Data: []byte(`INSERT INTO item (owner) VALUES ('eve');
+
+INSERT INTO unknown (owner) VALUES ('eve');
+`)},
+		"broken.yaml": &fstest.MapFile{Data: []byte(`item:
+  - owner: frank
+  - unknown: frank
+`)},
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(itemSchema))
+
+	t.Cleanup(func() {
+		var n int
+
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM item").Scan(&n))
+		assert.Equal(t, 0, n, "fixtures should be rolled back")
+	})
+
+	Parallel(t, db, 1, func(t *testing.T, ctx context.Context) {
+		require.NoError(t, LoadFixtures(ctx, fixtures(), "items.sql", "items.yaml"))
+
+		rows, err := txx.Get(ctx).QueryContext(ctx, "SELECT owner FROM item ORDER BY owner")
+		require.NoError(t, err)
+
+		defer rows.Close()
+
+		var owners []string
+
+		for rows.Next() {
+			var owner string
+
+			require.NoError(t, rows.Scan(&owner))
+
+			owners = append(owners, owner)
+		}
+
+		require.NoError(t, rows.Err())
+		assert.Equal(t, []string{"alice", "bob;", "carol", "dave"}, owners)
+	})
+}
+
+func TestLoadFixtures_errors(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(itemSchema))
+
+	require.ErrorIs(t, LoadFixtures(context.Background(), fixtures(), "items.sql"), txx.ErrNoTransaction)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "missing.sql", want: "missing.sql"},
+		{name: "items.txt", want: "items.txt: unsupported fixture format"},
+		{name: "broken.sql", want: "broken.sql:3:"},
+		{name: "broken.yaml", want: "broken.yaml:3:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fixtures()
+			fsys["items.txt"] = &fstest.MapFile{}
+
+			ctx := beginConn(t, db)
+			err := LoadFixtures(ctx, fsys, tt.name)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
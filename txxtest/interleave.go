@@ -0,0 +1,169 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MartyHub/txx"
+)
+
+const defaultStepTimeout = time.Second
+
+var (
+	// ErrStepTimeout is returned for a script whose step didn't complete in time, see Interleaving.
+	ErrStepTimeout = errors.New("txxtest: step timeout")
+	// ErrNotCompleted is returned for a script not completed by the order of an Interleaving.
+	ErrNotCompleted = errors.New("txxtest: script not completed")
+)
+
+// Script is a transaction run as ordered steps, see Interleaving.
+type Script struct {
+	Opts *sql.TxOptions
+	// Steps run in the transaction, in order. An error rolls the transaction back.
+	Steps []func(ctx context.Context) error
+}
+
+// Interleaving runs scripted transactions concurrently, each in its own Wrap on its own goroutine,
+// advancing them step by step in a deterministic global order, to reproduce concurrency anomalies.
+type Interleaving struct {
+	Scripts []Script
+	// Order of the steps as script indexes, like {0, 1, 0, 1, 0, 1} for
+	// "T0 step 1, T1 step 1, T0 step 2, T1 step 2, T0 commits, T1 commits".
+	//
+	// Every script needs an entry per step plus a final one returning from Wrap, committing it.
+	// Entries of a script already completed, like after a failed step, are skipped.
+	Order []int
+	// StepTimeout bounds every step, 1 second if not positive.
+	//
+	// A step still blocked then has its context canceled, failing its script with ErrStepTimeout,
+	// and the order goes on, as drivers may not interrupt a blocked statement before it gets unblocked.
+	StepTimeout time.Duration
+}
+
+// script is the state of a running Script.
+type script struct {
+	turn    chan struct{}
+	stepped chan struct{}
+	result  chan error
+	cancel  context.CancelCauseFunc
+	steps   int
+	done    bool
+	// timedOut is the timeout of a step which timed out, if any, the result being received by Run.
+	timedOut time.Duration
+	err      error
+}
+
+// Run the scripts on db and returns the result of the Wrap of each of them, in order.
+func (il Interleaving) Run(ctx context.Context, db *sql.DB) []error {
+	scripts := make([]*script, len(il.Scripts))
+
+	for i, s := range il.Scripts {
+		scripts[i] = start(ctx, db, s)
+	}
+
+	timeout := il.StepTimeout
+	if timeout <= 0 {
+		timeout = defaultStepTimeout
+	}
+
+	for _, i := range il.Order {
+		if s := scripts[i]; !s.done {
+			s.advance(timeout)
+		}
+	}
+
+	result := make([]error, len(scripts))
+
+	for i, s := range scripts {
+		if s.timedOut > 0 {
+			s.finish(<-s.result)
+			s.err = fmt.Errorf("%w: step %d after %v: %w", ErrStepTimeout, s.steps+1, s.timedOut, s.err)
+		} else if !s.done {
+			s.cancel(ErrNotCompleted)
+			s.finish(<-s.result)
+
+			if errors.Is(s.err, ErrNotCompleted) {
+				s.err = fmt.Errorf("%w: %d steps of %d", ErrNotCompleted, s.steps, len(il.Scripts[i].Steps))
+			}
+		}
+
+		result[i] = s.err
+	}
+
+	return result
+}
+
+func start(ctx context.Context, db *sql.DB, s Script) *script {
+	result := &script{
+		turn:    make(chan struct{}),
+		stepped: make(chan struct{}),
+		result:  make(chan error, 1),
+	}
+
+	ctx, result.cancel = context.WithCancelCause(ctx)
+
+	go func() {
+		result.result <- txx.Wrap(ctx, db, s.Opts, func(ctx context.Context) error {
+			for _, step := range s.Steps {
+				if err := wait(ctx, result.turn); err != nil {
+					return err
+				}
+
+				if err := step(ctx); err != nil {
+					return err
+				}
+
+				select {
+				case result.stepped <- struct{}{}:
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				}
+			}
+
+			return wait(ctx, result.turn)
+		})
+	}()
+
+	return result
+}
+
+// advance s by one step, waiting for it at most given timeout.
+func (s *script) advance(timeout time.Duration) {
+	select {
+	case s.turn <- struct{}{}:
+	case err := <-s.result:
+		s.finish(err)
+
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.stepped:
+		s.steps++
+	case err := <-s.result:
+		s.finish(err)
+	case <-timer.C:
+		s.cancel(ErrStepTimeout)
+		s.done, s.timedOut = true, timeout
+	}
+}
+
+func (s *script) finish(err error) {
+	s.done, s.err = true, err
+	s.cancel(nil)
+}
+
+func wait(ctx context.Context, turn <-chan struct{}) error {
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
@@ -0,0 +1,114 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doctorsSchema creates the doctor table, of two doctors on call.
+const doctorsSchema = `
+	CREATE TABLE doctor (name TEXT PRIMARY KEY, on_call INTEGER NOT NULL);
+	INSERT INTO doctor VALUES ('alice', 1), ('bob', 1);
+`
+
+func onCall(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var n int
+
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM doctor WHERE on_call = 1").Scan(&n))
+
+	return n
+}
+
+// leave returns the script of a doctor leaving if another one is still on call.
+func leave(name string) Script {
+	var others int
+
+	return Script{Steps: []func(ctx context.Context) error{
+		func(ctx context.Context) error {
+			return txx.Get(ctx).QueryRowContext(ctx,
+				"SELECT COUNT(*) FROM doctor WHERE on_call = 1 AND name <> ?", name).Scan(&others)
+		},
+		func(ctx context.Context) error {
+			if others == 0 {
+				return nil
+			}
+
+			_, err := txx.Get(ctx).ExecContext(ctx, "UPDATE doctor SET on_call = 0 WHERE name = ?", name)
+
+			return err
+		},
+	}}
+}
+
+// TestInterleaving_writeSkew reproduces the classic write skew interleaving: both doctors check that
+// the other one is on call, then leave. SQLite serializes writers, so the anomaly can't happen:
+// the closest reproducible case is the second writer failing, its snapshot being stale.
+func TestInterleaving_writeSkew(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(doctorsSchema))
+
+	errs := Interleaving{
+		Scripts: []Script{leave("alice"), leave("bob")},
+		Order:   []int{0, 1, 0, 0, 1, 1},
+	}.Run(context.Background(), db)
+
+	require.Len(t, errs, 2)
+	require.NoError(t, errs[0])
+	require.Error(t, errs[1])
+	assert.Equal(t, 1, onCall(t, db))
+}
+
+func TestInterleaving_serial(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(doctorsSchema))
+
+	errs := Interleaving{
+		Scripts: []Script{leave("alice"), leave("bob")},
+		Order:   []int{0, 0, 0, 1, 1, 1},
+	}.Run(context.Background(), db)
+
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, 1, onCall(t, db), "bob should see alice left")
+}
+
+func TestInterleaving_stepTimeout(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(doctorsSchema))
+	write := func(ctx context.Context) error {
+		_, err := txx.Get(ctx).ExecContext(ctx, "UPDATE doctor SET on_call = 0")
+
+		return err
+	}
+
+	start := time.Now()
+	errs := Interleaving{
+		Scripts: []Script{
+			{Steps: []func(ctx context.Context) error{write}},
+			{Steps: []func(ctx context.Context) error{write}},
+		},
+		Order:       []int{0, 1, 0, 1},
+		StepTimeout: 50 * time.Millisecond,
+	}.Run(context.Background(), db)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], ErrStepTimeout)
+	assert.Equal(t, 0, onCall(t, db))
+}
+
+func TestInterleaving_notCompleted(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(doctorsSchema))
+
+	errs := Interleaving{
+		Scripts: []Script{leave("alice")},
+		Order:   []int{0, 0},
+	}.Run(context.Background(), db)
+
+	require.ErrorIs(t, errs[0], ErrNotCompleted)
+	assert.Equal(t, 2, onCall(t, db), "transaction should be rolled back")
+}
@@ -0,0 +1,95 @@
+package txxtest
+
+import (
+	"database/sql"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/MartyHub/txx"
+)
+
+// Started describes a call of txx.MetricsRecorder.TxStarted.
+type Started struct {
+	Reused bool
+	Opts   *sql.TxOptions
+	Labels map[string]string
+}
+
+// Finished describes a call of txx.MetricsRecorder.TxFinished.
+type Finished struct {
+	Outcome  txx.TxOutcome
+	Cause    txx.RollbackCause
+	Duration time.Duration
+	Err      error
+	Labels   map[string]string
+}
+
+// Metrics is an in-memory txx.MetricsRecorder, for assertions.
+//
+// Its zero value is ready to use, and it is safe for concurrent use.
+type Metrics struct {
+	mu       sync.Mutex
+	started  []Started
+	finished []Finished
+}
+
+// TxStarted implements txx.MetricsRecorder.
+func (m *Metrics) TxStarted(reused bool, opts *sql.TxOptions, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.started = append(m.started, Started{Reused: reused, Opts: opts, Labels: maps.Clone(labels)})
+}
+
+// TxFinished implements txx.MetricsRecorder.
+func (m *Metrics) TxFinished(
+	outcome txx.TxOutcome,
+	cause txx.RollbackCause,
+	d time.Duration,
+	err error,
+	labels map[string]string,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.finished = append(m.finished, Finished{
+		Outcome:  outcome,
+		Cause:    cause,
+		Duration: d,
+		Err:      err,
+		Labels:   maps.Clone(labels),
+	})
+}
+
+// Started returns the recorded starts, in order.
+func (m *Metrics) Started() []Started {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Started(nil), m.started...)
+}
+
+// Finished returns the recorded completions, in order.
+func (m *Metrics) Finished() []Finished {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Finished(nil), m.finished...)
+}
+
+// Count returns the number of recorded completions with given outcome.
+func (m *Metrics) Count(outcome txx.TxOutcome) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := 0
+
+	for _, f := range m.finished {
+		if f.Outcome == outcome {
+			result++
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,57 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/MartyHub/txx"
+)
+
+// Parallel runs n parallel subtests calling f, each with a context carrying its own transaction.
+//
+// Each transaction is begun on a distinct pinned connection and rolled back when the subtest completes,
+// so that parallel subtests are isolated from each other.
+// The test fails if n exceeds the MaxOpenConns of db.
+func Parallel(t *testing.T, db *sql.DB, n int, f func(t *testing.T, ctx context.Context)) { //nolint:thelper
+	t.Helper()
+
+	if limit := db.Stats().MaxOpenConnections; limit > 0 && n > limit {
+		t.Fatalf("txxtest: %d parallel subtests need more connections than MaxOpenConns %d", n, limit)
+	}
+
+	for i := 0; i < n; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+
+			f(t, beginConn(t, db))
+		})
+	}
+}
+
+// beginConn begins a transaction on a pinned connection, rolled back by the test cleanup.
+func beginConn(t *testing.T, db *sql.DB) context.Context {
+	t.Helper()
+
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("txxtest: pinning connection: %v", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+
+		t.Fatalf("txxtest: beginning transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = tx.Rollback()
+		_ = conn.Close()
+	})
+
+	return txx.Set(ctx, tx, nil)
+}
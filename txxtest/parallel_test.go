@@ -0,0 +1,77 @@
+package txxtest
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemSchema creates the item table, of items owned by a transaction.
+const itemSchema = "CREATE TABLE item (owner TEXT NOT NULL)"
+
+func TestParallel(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(itemSchema))
+
+	var ran atomic.Int32
+
+	t.Cleanup(func() {
+		assert.Equal(t, int32(3), ran.Load())
+
+		var n int
+
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM item").Scan(&n))
+		assert.Equal(t, 0, n, "every transaction should be rolled back")
+	})
+
+	Parallel(t, db, 3, func(t *testing.T, ctx context.Context) {
+		ran.Add(1)
+
+		current := txx.Get(ctx)
+		require.True(t, current.IsValid())
+
+		for i := 0; i < 2; i++ {
+			_, err := current.ExecContext(ctx, "INSERT INTO item (owner) VALUES (?)", t.Name())
+			require.NoError(t, err)
+		}
+
+		rows, err := current.QueryContext(ctx, "SELECT DISTINCT owner FROM item")
+		require.NoError(t, err)
+
+		defer rows.Close()
+
+		var owners []string
+
+		for rows.Next() {
+			var owner string
+
+			require.NoError(t, rows.Scan(&owner))
+
+			owners = append(owners, owner)
+		}
+
+		require.NoError(t, rows.Err())
+		assert.Equal(t, []string{t.Name()}, owners, "only own rows should be visible")
+	})
+}
+
+func TestParallel_maxOpenConns(t *testing.T) {
+	db := NewDB(t, WithFile(), WithSchema(itemSchema))
+	db.SetMaxOpenConns(2)
+
+	ft := &testing.T{}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		Parallel(ft, db, 3, func(_ *testing.T, _ context.Context) {})
+	}()
+
+	<-done
+
+	assert.True(t, ft.Failed(), "subtests should exceed the pool")
+}
@@ -0,0 +1,54 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx"
+)
+
+// RunInRollback runs f with a context carrying a transaction of db, always rolled back once f returns,
+// so that tests leave no trace in the database, see InRollback.
+func RunInRollback(t *testing.T, db *sql.DB, f func(ctx context.Context)) { //nolint:thelper
+	t.Helper()
+
+	ctx, rollback := beginRollback(t, db)
+	defer rollback()
+
+	f(ctx)
+}
+
+// InRollback returns a context carrying a transaction of db like txx.Set, rolled back by the test cleanup.
+//
+// Ensure calls in that context, and the like, reuse the transaction whatever their options,
+// so that code under test can't commit its own: savepoints of Nested are rolled back with it.
+// Wrap still begins an independent transaction.
+func InRollback(t *testing.T, db *sql.DB) context.Context {
+	t.Helper()
+
+	ctx, rollback := beginRollback(t, db)
+	t.Cleanup(rollback)
+
+	return ctx
+}
+
+// beginRollback begins a transaction reused by every Ensure call, returning the function rolling it back.
+func beginRollback(t *testing.T, db *sql.DB) (context.Context, func()) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("txxtest: beginning transaction: %v", err)
+	}
+
+	ctx = txx.SetCompatibility(txx.Set(ctx, tx, nil), func(_ txx.Current, _ *sql.TxOptions) bool {
+		return true
+	})
+
+	return ctx, func() {
+		_ = tx.Rollback()
+	}
+}
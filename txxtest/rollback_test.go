@@ -0,0 +1,66 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func count(t *testing.T, ctx context.Context, db *sql.DB) int {
+	t.Helper()
+
+	var n int
+
+	require.NoError(t, txx.QueryRowContext(ctx, db, "SELECT COUNT(*) FROM parent").Scan(&n))
+
+	return n
+}
+
+func TestRunInRollback(t *testing.T) {
+	db := NewDB(t, WithSchema(parentChild))
+
+	RunInRollback(t, db, func(ctx context.Context) {
+		_, err := txx.ExecContext(ctx, db, "INSERT INTO parent VALUES (1)")
+		require.NoError(t, err)
+
+		require.NoError(t, txx.Ensure(ctx, db, txx.SerializableReadOnly(), func(ctx context.Context) error {
+			assert.Equal(t, 1, count(t, ctx, db), "Ensure should reuse the test transaction")
+
+			return nil
+		}))
+
+		require.Error(t, txx.Nested(ctx, db, nil, func(ctx context.Context) error {
+			_, err := txx.ExecContext(ctx, db, "INSERT INTO parent VALUES (2)")
+			require.NoError(t, err)
+
+			return errors.New("test") //nolint:goerr113
+		}))
+
+		assert.Equal(t, 1, count(t, ctx, db), "savepoint should be rolled back")
+	})
+
+	assert.Equal(t, 0, count(t, context.Background(), db), "inserted rows should be gone")
+}
+
+func TestInRollback(t *testing.T) {
+	db := NewDB(t, WithSchema(parentChild))
+
+	t.Run("sandbox", func(t *testing.T) {
+		ctx := InRollback(t, db)
+
+		require.NoError(t, txx.Ensure(ctx, db, nil, func(ctx context.Context) error {
+			_, err := txx.ExecContext(ctx, db, "INSERT INTO parent VALUES (1)")
+
+			return err
+		}))
+
+		assert.Equal(t, 1, count(t, ctx, db))
+	})
+
+	assert.Equal(t, 0, count(t, context.Background(), db), "inserted rows should be gone")
+}
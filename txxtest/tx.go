@@ -0,0 +1,95 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/MartyHub/txx/internal/fakedb"
+)
+
+// Tx is a fake transaction, to unit test code running statements through txx without a database.
+//
+// Its transaction is a real sql.Tx backed by a fake driver, so that code using txx.Get, txx.QuerierFor,
+// the Current methods or Current.Tx directly is run as is. Exec and Query script the results of statements,
+// and must be set before running them. Every statement is recorded with its arguments, see Calls.
+type Tx struct {
+	// Exec, if not nil, returns the result of executing given query with given arguments, a zero Result otherwise.
+	Exec func(query string, args []any) (Result, error)
+	// Query, if not nil, returns the rows of given query with given arguments, no rows otherwise.
+	// QueryRow is a Query whose first row is scanned.
+	Query func(query string, args []any) (*Rows, error)
+
+	d  *fakedb.Driver
+	tx *sql.Tx
+}
+
+// Result is the result of a statement executed by a Tx.
+type Result = fakedb.Result
+
+// Rows are the rows returned by a query of a Tx.
+type Rows = fakedb.Rows
+
+// Call is a statement run by a Tx, or its completion: its Method is "exec", "query", "commit" or "rollback".
+type Call = fakedb.Call
+
+// NewTx returns a fake transaction, rolled back by the test cleanup.
+func NewTx(tb testing.TB) *Tx {
+	tb.Helper()
+
+	t := &Tx{}
+	t.d = &fakedb.Driver{
+		Exec: func(_ context.Context, query string, args []any) (Result, error) {
+			if t.Exec == nil {
+				return Result{}, nil
+			}
+
+			return t.Exec(query, args)
+		},
+		Query: func(_ context.Context, query string, args []any) (*Rows, error) {
+			if t.Query == nil {
+				return nil, nil
+			}
+
+			return t.Query(query, args)
+		},
+	}
+	db := fakedb.Open(tb, t.d)
+
+	tx, err := db.Begin()
+	if err != nil {
+		tb.Fatalf("txxtest: begin fake transaction: %v", err)
+	}
+
+	t.tx = tx
+
+	tb.Cleanup(func() {
+		_ = tx.Rollback()
+	})
+
+	return t
+}
+
+// SetFake returns a copy of ctx whose current transaction is given fake, reused by Ensure, see txx.Set.
+func SetFake(ctx context.Context, fake *Tx) context.Context {
+	return txx.Set(ctx, fake.tx, nil)
+}
+
+// SQLTx returns the sql.Tx of the fake.
+func (t *Tx) SQLTx() *sql.Tx {
+	return t.tx
+}
+
+// Calls returns the calls made so far.
+func (t *Tx) Calls() []Call {
+	var result []Call
+
+	for _, c := range t.d.Calls() {
+		if c.Method != "connect" && c.Method != "begin" {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
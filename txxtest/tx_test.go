@@ -0,0 +1,108 @@
+package txxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MartyHub/txx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("not found") //nolint:gochecknoglobals
+
+// people is a repository of the kind unit tested with a fake transaction.
+type people struct {
+	db *sql.DB
+}
+
+func (p people) name(ctx context.Context, id int64) (string, error) {
+	var result string
+
+	err := txx.QuerierFor(ctx, p.db).QueryRowContext(ctx, "SELECT name FROM person WHERE id = ?", id).Scan(&result)
+
+	return result, err
+}
+
+func (p people) rename(ctx context.Context, id int64, name string) error {
+	return txx.Ensure(ctx, nil, nil, func(ctx context.Context) error {
+		result, err := txx.Get(ctx).ExecContext(ctx, "UPDATE person SET name = ? WHERE id = ?", name, id)
+		if err != nil {
+			return err
+		}
+
+		if n, _ := result.RowsAffected(); n == 0 {
+			return errNotFound
+		}
+
+		return nil
+	})
+}
+
+func TestSetFake(t *testing.T) {
+	fake := NewTx(t)
+	fake.Query = func(string, []any) (*Rows, error) {
+		return &Rows{Columns: []string{"name"}, Values: [][]any{{"Alice"}}}, nil
+	}
+	fake.Exec = func(_ string, args []any) (Result, error) {
+		if args[1] == int64(1) {
+			return Result{RowsAffected: 1}, nil
+		}
+
+		return Result{}, nil
+	}
+
+	ctx := SetFake(context.Background(), fake)
+	repo := people{}
+
+	name, err := repo.name(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", name)
+
+	require.NoError(t, repo.rename(ctx, 1, "Carol"))
+	require.ErrorIs(t, repo.rename(ctx, 2, "Dave"), errNotFound)
+
+	assert.Equal(t, []Call{
+		{Method: "query", Query: "SELECT name FROM person WHERE id = ?", Args: []any{int64(1)}},
+		{Method: "exec", Query: "UPDATE person SET name = ? WHERE id = ?", Args: []any{"Carol", int64(1)}},
+		{Method: "exec", Query: "UPDATE person SET name = ? WHERE id = ?", Args: []any{"Dave", int64(2)}},
+	}, fake.Calls())
+}
+
+func TestTx_noScript(t *testing.T) {
+	fake := NewTx(t)
+	ctx := SetFake(context.Background(), fake)
+
+	rows, err := txx.Get(ctx).QueryContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	stmt, err := fake.SQLTx().PrepareContext(ctx, "DELETE FROM person")
+	require.NoError(t, err)
+
+	_, err = stmt.ExecContext(ctx)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, txx.Get(ctx).QueryRowContext(ctx, "SELECT 2").Scan(new(int)), sql.ErrNoRows)
+
+	assert.Equal(t, []Call{
+		{Method: "query", Query: "SELECT 1"},
+		{Method: "exec", Query: "DELETE FROM person"},
+		{Method: "query", Query: "SELECT 2"},
+	}, fake.Calls())
+}
+
+func TestTx_error(t *testing.T) {
+	errDriver := errors.New("driver") //nolint:goerr113
+	fake := NewTx(t)
+	fake.Exec = func(string, []any) (Result, error) {
+		return Result{}, errDriver
+	}
+
+	_, err := txx.Get(SetFake(context.Background(), fake)).ExecContext(context.Background(), "DELETE FROM person")
+
+	require.ErrorIs(t, err, errDriver)
+}
@@ -0,0 +1,54 @@
+package txx
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoTransaction is returned when a transaction begun by txx is required but none is current.
+var ErrNoTransaction = errors.New("txx: no transaction")
+
+// SetValue stores a value under given key in the transaction of the context,
+// shared by every function running in that transaction, like nested Ensure calls.
+//
+// Values are cleared when the transaction completes. SetValue returns ErrNoTransaction if there is
+// no transaction begun by txx in the context, a transaction set with Set having no values,
+// and ErrTransactionFinished if the transaction is completed.
+func SetValue(ctx context.Context, key, value any) error {
+	s := Get(ctx).s
+	if s == nil {
+		return ErrNoTransaction
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrTransactionFinished
+	}
+
+	if s.values == nil {
+		s.values = make(map[any]any)
+	}
+
+	s.values[key] = value
+
+	return nil
+}
+
+// Value returns the value stored under given key in the transaction of the context, see SetValue.
+//
+// It reports false if there is no such value or no transaction.
+func Value(ctx context.Context, key any) (any, bool) {
+	s := Get(ctx).s
+	if s == nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, found := s.values[key]
+
+	return result, found
+}
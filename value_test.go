@@ -0,0 +1,93 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type valueKey struct{}
+
+func TestSetValue(t *testing.T) {
+	m := New(testdb.Open(t, onDisk))
+
+	var captured context.Context
+
+	require.NoError(t, m.Wrap(context.Background(), nil, func(ctx context.Context) error {
+		require.NoError(t, SetValue(ctx, valueKey{}, "outer"))
+
+		require.NoError(t, m.Ensure(ctx, nil, func(ctx context.Context) error {
+			value, found := Value(ctx, valueKey{})
+			require.True(t, found)
+			assert.Equal(t, "outer", value)
+
+			return SetValue(ctx, valueKey{}, "inner")
+		}))
+
+		value, _ := Value(ctx, valueKey{})
+		assert.Equal(t, "inner", value, "nested Ensure should share values")
+
+		require.NoError(t, m.Wrap(ctx, nil, func(ctx context.Context) error {
+			_, found := Value(ctx, valueKey{})
+			assert.False(t, found, "new transaction should not share values")
+
+			return nil
+		}))
+
+		captured = ctx
+
+		return nil
+	}))
+
+	_, found := Value(captured, valueKey{})
+	assert.False(t, found, "values should be cleared on completion")
+	require.ErrorIs(t, SetValue(captured, valueKey{}, "late"), ErrTransactionFinished)
+}
+
+func TestSetValue_concurrent(t *testing.T) {
+	require.NoError(t, New(testdb.Open(t, inMemory)).Wrap(context.Background(), nil, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				assert.NoError(t, SetValue(ctx, i, i))
+
+				_, found := Value(ctx, i)
+				assert.True(t, found)
+			}()
+		}
+
+		wg.Wait()
+
+		for i := 0; i < 10; i++ {
+			value, found := Value(ctx, i)
+			require.True(t, found)
+			assert.Equal(t, i, value)
+		}
+
+		return nil
+	}))
+}
+
+func TestSetValue_noTransaction(t *testing.T) {
+	for name, ctx := range map[string]context.Context{
+		"none": context.Background(),
+		"set":  Set(context.Background(), &sql.Tx{}, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, SetValue(ctx, valueKey{}, "value"), ErrNoTransaction)
+
+			_, found := Value(ctx, valueKey{})
+			assert.False(t, found)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrStaleVersion is returned by UpdateVersioned when no row matched the version predicate,
+// the row having been updated or deleted concurrently.
+//
+// Retrying the whole transaction, so that it reads the new version, usually resolves it:
+// a retry classifier can match it with errors.Is.
+var ErrStaleVersion = errors.New("txx: stale version")
+
+// UpdateVersioned executes an optimistic concurrency UPDATE in the current transaction if any,
+// or on db otherwise, like:
+//
+//	UPDATE item SET name = ?, version = version + 1 WHERE id = ? AND version = ?
+//
+// The query must include the version predicate. ErrStaleVersion is returned if no row was affected.
+func UpdateVersioned(ctx context.Context, db *sql.DB, query string, args ...any) error {
+	Get(ctx).s.invalidate()
+
+	result, err := QuerierFor(ctx, db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("txx: counting updated rows: %w", err)
+	}
+
+	if n == 0 {
+		return ErrStaleVersion
+	}
+
+	return nil
+}
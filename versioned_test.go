@@ -0,0 +1,92 @@
+package txx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MartyHub/txx/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// docSchema creates the doc table, of a versioned document.
+const docSchema = `
+	CREATE TABLE doc (id INTEGER PRIMARY KEY, title TEXT NOT NULL, version INTEGER NOT NULL);
+	INSERT INTO doc VALUES (1, 'draft', 0);
+`
+
+func retitle(ctx context.Context, db *sql.DB, title string, version int) error {
+	return UpdateVersioned(ctx, db, "UPDATE doc SET title = ?, version = version + 1 WHERE id = 1 AND version = ?",
+		title, version)
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(docSchema))
+
+	require.NoError(t, retitle(context.Background(), db, "first", 0))
+	require.ErrorIs(t, retitle(context.Background(), db, "stale", 0), ErrStaleVersion)
+
+	got, err := QueryOne[string](context.Background(), db, "SELECT title FROM doc WHERE id = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "first", got)
+}
+
+func TestUpdateVersioned_race(t *testing.T) {
+	db := testdb.Open(t, onDisk, testdb.WithSchema(docSchema))
+
+	// both transactions start from the version read before
+	version, err := QueryOne[int](context.Background(), db, "SELECT version FROM doc WHERE id = 1")
+	require.NoError(t, err)
+
+	updated := make(chan struct{})
+	proceed := make(chan struct{})
+	first := make(chan error, 1)
+
+	go func() {
+		first <- Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+			if err := retitle(ctx, db, "first", version); err != nil {
+				return err
+			}
+
+			close(updated)
+			<-proceed
+
+			return nil
+		})
+	}()
+
+	<-updated
+
+	second := make(chan error, 1)
+	attempts := 0
+
+	go func() {
+		second <- Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+			attempts++
+
+			return retitle(ctx, db, "second", version)
+		})
+	}()
+
+	close(proceed)
+	require.NoError(t, <-first)
+	require.ErrorIs(t, <-second, ErrStaleVersion)
+
+	// retry re-reading the version
+	require.NoError(t, Wrap(context.Background(), db, nil, func(ctx context.Context) error {
+		attempts++
+
+		current, err := QueryOne[int](ctx, db, "SELECT version FROM doc WHERE id = 1")
+		if err != nil {
+			return err
+		}
+
+		return retitle(ctx, db, "second", current)
+	}))
+
+	got, err := QueryOne[string](context.Background(), db, "SELECT title || ' v' || version FROM doc WHERE id = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "second v2", got)
+	assert.Equal(t, 2, attempts)
+}